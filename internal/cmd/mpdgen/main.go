@@ -0,0 +1,379 @@
+// Command mpdgen generates reflection-free decoders for the structs of a package
+// tagged with `mpd_prefix`, and registers them with parser.RegisterDecoder.
+//
+// It is meant to be run with go:generate from the package directory:
+//
+//	//go:generate go run ../../internal/cmd/mpdgen -output decoders_gen.go
+//
+// Supported field types are strings, integers, floats, booleans, time.Time, time.Duration,
+// pointers to and slices of them, and catch-all maps. Structs having fields of other types
+// (named types, nested structs) are skipped and keep being parsed with reflection.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	parserImportPath = "github.com/anpotashev/mpdgo/internal/parser"
+	generatedHeader  = "// Code generated by mpdgen. DO NOT EDIT."
+)
+
+func main() {
+	output := flag.String("output", "decoders_gen.go", "output file name")
+	typeNames := flag.String("type", "", "comma-separated list of type names; all tagged structs if empty")
+	flag.Parse()
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+	src, skipped, err := generate(".", types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mpdgen:", err)
+		os.Exit(1)
+	}
+	for _, msg := range skipped {
+		fmt.Fprintln(os.Stderr, "mpdgen:", msg)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "mpdgen:", err)
+		os.Exit(1)
+	}
+}
+
+// taggedStruct is a struct type declaration having fields tagged with `mpd_prefix`.
+type taggedStruct struct {
+	name string
+	typ  *ast.StructType
+}
+
+// generate returns the source of the decoders of the tagged structs declared in the
+// non-test, non-generated files of dir, and the reasons the unsupported structs were skipped.
+func generate(dir string, types []string) ([]byte, []string, error) {
+	pkgName, structs, err := loadStructs(dir, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := &generator{}
+	var skipped []string
+	var generated []string
+	for _, s := range structs {
+		if err := g.generateDecoder(s); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s is parsed with reflection: %v", s.name, err))
+			continue
+		}
+		generated = append(generated, s.name)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n\npackage %s\n\nimport (\n", generatedHeader, pkgName)
+	if g.usesStrconv {
+		buf.WriteString("\t\"strconv\"\n\n")
+	}
+	fmt.Fprintf(&buf, "\t%q\n)\n\n", parserImportPath)
+	buf.WriteString("func init() {\n")
+	for _, name := range generated {
+		fmt.Fprintf(&buf, "\tparser.RegisterDecoder(%s", decodeFuncName(name))
+		for _, key := range g.newElementKeys[name] {
+			fmt.Fprintf(&buf, ", %q", key)
+		}
+		buf.WriteString(")\n")
+	}
+	buf.WriteString("}\n")
+	buf.Write(g.body.Bytes())
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, skipped, nil
+}
+
+// loadStructs parses the package in dir and returns its name and its tagged structs sorted by name.
+func loadStructs(dir string, types []string) (string, []taggedStruct, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+	fset := token.NewFileSet()
+	var pkgName string
+	var result []taggedStruct
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return "", nil, err
+		}
+		if isGenerated(file) {
+			continue
+		}
+		pkgName = file.Name.Name
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok || typeSpec.TypeParams != nil || !hasPrefixTags(structType) {
+					continue
+				}
+				if len(types) > 0 && !slices.Contains(types, typeSpec.Name.Name) {
+					continue
+				}
+				result = append(result, taggedStruct{name: typeSpec.Name.Name, typ: structType})
+			}
+		}
+	}
+	if pkgName == "" {
+		return "", nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	slices.SortFunc(result, func(a, b taggedStruct) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return pkgName, result, nil
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if c.Text == generatedHeader {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasPrefixTags(s *ast.StructType) bool {
+	for _, field := range s.Fields.List {
+		if fieldTag(field).Get("mpd_prefix") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+func decodeFuncName(typeName string) string {
+	return "decode" + strings.ToUpper(typeName[:1]) + typeName[1:]
+}
+
+type generator struct {
+	body           bytes.Buffer
+	usesStrconv    bool
+	newElementKeys map[string][]string
+	vars           int
+}
+
+// generateDecoder writes the decode function of s to g.body.
+// Nothing is written if s has a field of an unsupported type.
+func (g *generator) generateDecoder(s taggedStruct) error {
+	var cases bytes.Buffer
+	var defaultCase string
+	var newElementKeys []string
+	prefixes := make(map[string]bool)
+	usesStrconv := g.usesStrconv
+	for _, field := range s.typ.Fields.List {
+		tag := fieldTag(field)
+		prefix := tag.Get("mpd_prefix")
+		if len(field.Names) == 0 {
+			if prefix != "" || mayBeNestedStruct(field.Type) {
+				return fmt.Errorf("embedded field %s is not supported", exprString(field.Type))
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			switch {
+			case tag.Get("is_catch_all") == "true":
+				code, err := catchAllCode("target."+name.Name, field.Type)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", name.Name, err)
+				}
+				defaultCase = code
+			case prefix != "":
+				if prefixes[prefix] {
+					return fmt.Errorf("prefix %q is used by several fields", prefix)
+				}
+				prefixes[prefix] = true
+				g.vars = 0
+				code, err := g.assignCode("target."+name.Name, name.Name, field.Type, &usesStrconv)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", name.Name, err)
+				}
+				fmt.Fprintf(&cases, "case %q:\n%s", prefix, code)
+				if tag.Get("is_new_element_prefix") == "true" {
+					newElementKeys = append(newElementKeys, prefix)
+				}
+			case name.IsExported() && mayBeNestedStruct(field.Type):
+				return fmt.Errorf("untagged field %s may be a nested struct", name.Name)
+			}
+		}
+	}
+	g.usesStrconv = usesStrconv
+	if g.newElementKeys == nil {
+		g.newElementKeys = make(map[string][]string)
+	}
+	g.newElementKeys[s.name] = newElementKeys
+	fmt.Fprintf(&g.body, "\nfunc %s(target *%s, key, value string) (bool, error) {\n", decodeFuncName(s.name), s.name)
+	fmt.Fprintf(&g.body, "switch key {\n%s", cases.String())
+	if defaultCase != "" {
+		fmt.Fprintf(&g.body, "default:\n%s", defaultCase)
+	} else {
+		g.body.WriteString("default:\nreturn false, nil\n")
+	}
+	g.body.WriteString("}\nreturn true, nil\n}\n")
+	return nil
+}
+
+// assignCode returns the statements storing value in target, an expression of type typ.
+// Slice fields collect the values of all the lines with their prefix.
+func (g *generator) assignCode(target, fieldName string, typ ast.Expr, usesStrconv *bool) (string, error) {
+	if t, ok := typ.(*ast.ArrayType); ok {
+		if t.Len != nil {
+			return "", fmt.Errorf("arrays are not supported")
+		}
+		code, value, err := g.valueCode(fieldName, t.Elt, usesStrconv)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s%s = append(%s, %s)\n", code, target, target, value), nil
+	}
+	code, value, err := g.valueCode(fieldName, typ, usesStrconv)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s = %s\n", code, target, value), nil
+}
+
+// valueCode returns the statements converting value to typ and the expression holding the result.
+func (g *generator) valueCode(fieldName string, typ ast.Expr, usesStrconv *bool) (string, string, error) {
+	if t, ok := typ.(*ast.StarExpr); ok {
+		code, value, err := g.valueCode(fieldName, t.X, usesStrconv)
+		if err != nil {
+			return "", "", err
+		}
+		if token.IsIdentifier(value) && value != "value" {
+			return code, "&" + value, nil
+		}
+		v := g.newVar()
+		return fmt.Sprintf("%s%s := %s\n", code, v, value), "&" + v, nil
+	}
+	typeName := exprString(typ)
+	var parse string
+	switch typeName {
+	case "string":
+		return "", "value", nil
+	case "time.Time":
+		parse = "parser.ParseTime(value)"
+	case "time.Duration":
+		parse = "parser.ParseDuration(value)"
+	case "bool":
+		parse = "strconv.ParseBool(value)"
+	case "int", "int8", "int16", "int32", "int64":
+		parse = fmt.Sprintf("strconv.ParseInt(value, 10, %d)", bitSize(typeName))
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		parse = fmt.Sprintf("strconv.ParseUint(value, 10, %d)", bitSize(typeName))
+	case "float32", "float64":
+		parse = fmt.Sprintf("strconv.ParseFloat(value, %d)", bitSize(typeName))
+	default:
+		return "", "", fmt.Errorf("type %s is not supported", typeName)
+	}
+	if strings.HasPrefix(parse, "strconv.") {
+		*usesStrconv = true
+	}
+	v := g.newVar()
+	value := v
+	switch typeName {
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32", "float32":
+		value = fmt.Sprintf("%s(%s)", typeName, v)
+	}
+	code := fmt.Sprintf("%s, err := %s\nif err != nil {\nreturn true, parser.NewFieldTypeParsingError(%q, value, %q, err)\n}\n",
+		v, parse, fieldName, typeName)
+	return code, value, nil
+}
+
+func (g *generator) newVar() string {
+	g.vars++
+	return "v" + strconv.Itoa(g.vars)
+}
+
+// catchAllCode returns the statements storing key and value in target, a catch-all map of type typ.
+func catchAllCode(target string, typ ast.Expr) (string, error) {
+	switch exprString(typ) {
+	case "map[string]string":
+		return fmt.Sprintf("if %s == nil {\n%s = make(map[string]string)\n}\n%s[key] = value\n", target, target, target), nil
+	case "map[string][]string":
+		return fmt.Sprintf("if %s == nil {\n%s = make(map[string][]string)\n}\n%s[key] = append(%s[key], value)\n", target, target, target, target), nil
+	}
+	return "", fmt.Errorf("catch-all type %s is not supported", exprString(typ))
+}
+
+func bitSize(typeName string) int {
+	switch {
+	case strings.HasSuffix(typeName, "8"):
+		return 8
+	case strings.HasSuffix(typeName, "16"):
+		return 16
+	case strings.HasSuffix(typeName, "32"):
+		return 32
+	case strings.HasSuffix(typeName, "64"):
+		return 64
+	}
+	return 0
+}
+
+// basicTypes are the predeclared types that cannot be nested structs.
+var basicTypes = []string{
+	"bool", "string", "byte", "rune", "error", "any", "uintptr", "complex64", "complex128",
+	"int", "int8", "int16", "int32", "int64",
+	"uint", "uint8", "uint16", "uint32", "uint64",
+	"float32", "float64",
+}
+
+// mayBeNestedStruct reports whether an untagged field of type typ may be a struct
+// whose tagged fields the reflective parser handles as if they belonged to the outer struct.
+func mayBeNestedStruct(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return !slices.Contains(basicTypes, t.Name)
+	case *ast.SelectorExpr:
+		return exprString(t) != "time.Time" && exprString(t) != "time.Duration"
+	case *ast.StructType:
+		return true
+	}
+	return false
+}
+
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("generated decoders of mpdapi are up to date", func(t *testing.T) {
+		dir := filepath.Join("..", "..", "..", "pkg", "mpdapi")
+		expected, err := os.ReadFile(filepath.Join(dir, "decoders_gen.go"))
+		assert.NoError(t, err)
+		actual, skipped, err := generate(dir, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, skipped)
+		assert.Equal(t, string(expected), string(actual), "run go generate ./pkg/mpdapi")
+	})
+	t.Run("unsupported structs are skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		src := "package sample\n\n" +
+			"type State string\n\n" +
+			"type named struct {\n\tState State `mpd_prefix:\"state\"`\n}\n\n" +
+			"type duplicated struct {\n\tA string `mpd_prefix:\"a\"`\n\tB string `mpd_prefix:\"a\"`\n}\n\n" +
+			"type Nested struct {\n\tA string `mpd_prefix:\"a\"`\n}\n\n" +
+			"type outer struct {\n\tNested\n\tB string `mpd_prefix:\"b\"`\n}\n\n" +
+			"type supported struct {\n\tA []*uint16 `mpd_prefix:\"a\" is_new_element_prefix:\"true\"`\n}\n"
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "sample.go"), []byte(src), 0o644))
+		actual, skipped, err := generate(dir, nil)
+		assert.NoError(t, err)
+		assert.Len(t, skipped, 3)
+		assert.Contains(t, string(actual), `parser.RegisterDecoder(decodeNested)`)
+		assert.Contains(t, string(actual), `parser.RegisterDecoder(decodeSupported, "a")`)
+		assert.NotContains(t, string(actual), "decodeNamed")
+		assert.NotContains(t, string(actual), "decodeDuplicated")
+		assert.NotContains(t, string(actual), "decodeOuter")
+	})
+	t.Run("selected types only", func(t *testing.T) {
+		dir := filepath.Join("..", "..", "..", "pkg", "mpdapi")
+		actual, _, err := generate(dir, []string{"Output"})
+		assert.NoError(t, err)
+		assert.Contains(t, string(actual), "decodeOutput")
+		assert.NotContains(t, string(actual), "decodePlaylistItem")
+	})
+}
@@ -20,6 +20,21 @@ func NewBatchCommands(cmds []SingleCommand, maxCommandsCount int) []BatchCommand
 	return result
 }
 
+// Name returns "command_list".
+func (m BatchCommand) Name() string {
+	return "command_list"
+}
+
+// Idempotent reports whether all the commands of the list are idempotent.
+func (m BatchCommand) Idempotent() bool {
+	for _, command := range m.commands {
+		if !command.Idempotent() {
+			return false
+		}
+	}
+	return true
+}
+
 func (m BatchCommand) String() string {
 	stringSlice := make([]string, len(m.commands))
 	for i, command := range m.commands {
@@ -16,6 +16,7 @@ const (
 	STATUS
 	LSINFO
 	IDLE
+	NOIDLE
 	PING
 	ENABLE_OUTPUT
 	DISABLE_OUTPUT
@@ -42,6 +43,7 @@ const (
 	SAVE
 	RENAME
 	PASSWORD
+	RESCAN
 )
 
 func (c CommandType) String() string {
@@ -64,6 +66,8 @@ func (c CommandType) String() string {
 		return "lsinfo"
 	case IDLE:
 		return "idle"
+	case NOIDLE:
+		return "noidle"
 	case PING:
 		return "ping"
 	case ENABLE_OUTPUT:
@@ -116,11 +120,46 @@ func (c CommandType) String() string {
 		return "rename"
 	case PASSWORD:
 		return "password"
+	case RESCAN:
+		return "rescan"
 	default:
 		return "unknown"
 	}
 }
 
+// idempotentCommands are the commands that only read the state of the MPD server,
+// so sending them again has the same effect as sending them once.
+var idempotentCommands = map[string]bool{
+	"ping":               true,
+	"status":             true,
+	"stats":              true,
+	"currentsong":        true,
+	"playlistinfo":       true,
+	"playlistid":         true,
+	"plchanges":          true,
+	"lsinfo":             true,
+	"listall":            true,
+	"listallinfo":        true,
+	"listplaylists":      true,
+	"listplaylist":       true,
+	"listplaylistinfo":   true,
+	"outputs":            true,
+	"find":               true,
+	"search":             true,
+	"list":               true,
+	"count":              true,
+	"commands":           true,
+	"notcommands":        true,
+	"tagtypes":           true,
+	"urlhandlers":        true,
+	"decoders":           true,
+	"replay_gain_status": true,
+}
+
 type MpdCommand interface {
 	fmt.Stringer
+	Name() string
+	// Idempotent reports whether the command may be sent again after a failure
+	// without changing the state of the MPD server.
+	Idempotent() bool
 }
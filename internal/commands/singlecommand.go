@@ -21,12 +21,51 @@ func (c SingleCommand) String() string {
 	return fmt.Sprintf("%s %s\n", c.command, strings.Join(stringSlice, " "))
 }
 
+// Name returns the command without the parameters.
+func (c SingleCommand) Name() string {
+	return c.command
+}
+
+// Idempotent reports whether the command only reads the state of the MPD server.
+func (c SingleCommand) Idempotent() bool {
+	return idempotentCommands[c.command]
+}
+
+// Args returns the parameters of the command, unquoted.
+func (c SingleCommand) Args() []string {
+	args := make([]string, len(c.params))
+	for i, param := range c.params {
+		switch p := param.(type) {
+		case StringParam:
+			args[i] = string(p)
+		case BoolParam:
+			args[i] = "0"
+			if p {
+				args[i] = "1"
+			}
+		default:
+			args[i] = p.String()
+		}
+	}
+	return args
+}
+
 func NewSingleCommand(command CommandType) SingleCommand {
 	return SingleCommand{
 		command: command.String(),
 	}
 }
 
+// NewCustomCommand returns the command name with the string parameters args,
+// for the commands not listed in CommandType.
+func NewCustomCommand(name string, args ...string) SingleCommand {
+	c := SingleCommand{command: name}
+	for _, arg := range args {
+		c.params = append(c.params, StringParam(arg))
+	}
+	return c
+}
+
 func (c SingleCommand) AddParams(params ...any) SingleCommand {
 	for _, param := range params {
 		var p Param
@@ -9,4 +9,7 @@ var (
 	ErrAlreadyConnected = fmt.Errorf("already connected")
 	ErrOnConnection     = fmt.Errorf("connection error")
 	ErrSendCommand      = fmt.Errorf("command send error")
+	// ErrSeveralCommands is returned when an interceptor passes several commands to next
+	// for a single command, whose answer they would not have.
+	ErrSeveralCommands = fmt.Errorf("several commands sent instead of a single one")
 )
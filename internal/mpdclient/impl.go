@@ -3,18 +3,29 @@ package mpdclient
 import (
 	"context"
 	"errors"
+	"iter"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/anpotashev/go-observer/pkg/observer"
 	"github.com/anpotashev/mpdgo/internal/commands"
 	log "github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/anpotashev/mpdgo/internal/mpdrw"
 	"github.com/anpotashev/mpdgo/internal/mpdrwpool"
+	"github.com/anpotashev/mpdgo/pkg/metrics"
 )
 
 type config struct {
-	host                  string
-	port                  uint16
+	dialer       mpdrw.Dialer
+	recorder     *mpdrw.Recorder
+	metrics      metrics.Metrics
+	interceptors []Interceptor
+	retryPolicy  mpdrwpool.RetryPolicy
+	// sizing replaces the fixed poolSize if not nil.
+	sizing *mpdrwpool.Sizing
+	// idleSubsystems are the subsystems the idle connection waits for, all if empty. Guarded by Impl.mu.
+	idleSubsystems        []string
 	password              string
 	readTimeout           time.Duration
 	pingPeriod            time.Duration
@@ -29,6 +40,8 @@ type Impl struct {
 	config     config
 	cancelFunc context.CancelFunc
 	observer.Observer[string]
+	// wasConnected reports whether the client has ever connected, to count the reconnections.
+	wasConnected bool
 }
 
 func NewMpdClientImpl(ctx context.Context,
@@ -37,36 +50,55 @@ func NewMpdClientImpl(ctx context.Context,
 	password string,
 	maxBatchCommandLength uint16,
 	poolSize uint8,
-	readTimeout, pingPeriod time.Duration) *Impl {
-	return &Impl{
+	readTimeout, pingPeriod time.Duration,
+	opts ...Option) *Impl {
+	result := &Impl{
 		ctx: ctx,
 		config: config{
-			host:                  host,
-			port:                  port,
+			dialer:                mpdrw.NewDialer(host, port),
 			password:              password,
 			maxBatchCommandLength: maxBatchCommandLength,
 			poolSize:              poolSize,
 			readTimeout:           readTimeout,
 			pingPeriod:            pingPeriod,
+			metrics:               metrics.Nop{},
+			retryPolicy:           mpdrwpool.DefaultRetryPolicy,
 		},
 		cancelFunc: nil,
 		Observer:   observer.New[string](),
 	}
+	for _, opt := range opts {
+		opt(result)
+	}
+	if result.config.recorder != nil {
+		result.config.dialer = result.config.recorder.Wrap(result.config.dialer)
+	}
+	return result
 }
 
 type newMpdRWPoolFactory func(requestContext, ctx context.Context, onDisconnect func()) (mpdrwpool.MpdRWPool, error)
 
 func (m *Impl) newMpdRWPoolFactory(requestContext, ctx context.Context, onDisconnect func()) (mpdrwpool.MpdRWPool, error) {
+	opts := []mpdrwpool.Option{
+		mpdrwpool.WithMetrics(m.config.metrics),
+		mpdrwpool.WithRetryPolicy(m.config.retryPolicy),
+	}
+	if m.config.sizing != nil {
+		opts = append(opts, mpdrwpool.WithSizing(*m.config.sizing))
+	}
+	if len(m.config.idleSubsystems) > 0 {
+		opts = append(opts, mpdrwpool.WithIdleSubsystems(m.config.idleSubsystems...))
+	}
 	return mpdrwpool.NewMpdRWPool(
 		requestContext,
 		ctx,
 		m.config.poolSize,
-		m.config.host,
-		m.config.port,
+		m.config.dialer,
 		m.config.password,
 		m.config.readTimeout,
 		m.config.pingPeriod,
-		onDisconnect)
+		onDisconnect,
+		opts...)
 }
 
 func (m *Impl) Connect(requestContext context.Context) error {
@@ -89,7 +121,12 @@ func (m *Impl) connect(requestContext context.Context, newMpdRWPoolFactoryFunc n
 	ctx, cancel := context.WithCancel(m.ctx)
 	log.DebugContext(requestContext, "Creating an onDisconnect function")
 	//lint:ignore SA1012 ignore
-	onDisconnect := func() { m.Disconnect(nil) }
+	onDisconnect := func() {
+		// A pool closed by Disconnect must not disconnect the pool of a later Connect.
+		if ctx.Err() == nil || m.ctx.Err() != nil {
+			m.Disconnect(nil)
+		}
+	}
 	log.DebugContext(requestContext, "Creating an mpdRWPool")
 	pool, err := newMpdRWPoolFactoryFunc(requestContext, ctx, onDisconnect)
 	if err != nil {
@@ -116,6 +153,10 @@ func (m *Impl) connect(requestContext context.Context, newMpdRWPoolFactoryFunc n
 	}()
 	m.pool = pool
 	m.cancelFunc = cancel
+	if m.wasConnected {
+		m.config.metrics.Reconnected()
+	}
+	m.wasConnected = true
 	log.DebugContext(requestContext, "Sending an onConnect event")
 	m.Notify(OnConnect)
 	return nil
@@ -142,29 +183,110 @@ func (m *Impl) IsConnected(requestContext context.Context) bool {
 	return m.pool != nil
 }
 
-func (m *Impl) SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error) {
-	log.DebugContext(requestContext, "Sending single command", "command", log.Truncate(command.String(), 100))
+func (m *Impl) Stats(requestContext context.Context) (mpdrwpool.Stats, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.pool == nil {
-		return nil, ErrNotConnected
+		return mpdrwpool.Stats{}, ErrNotConnected
+	}
+	return m.pool.Stats(), nil
+}
+
+func (m *Impl) SetIdleSubsystems(requestContext context.Context, subsystems ...string) error {
+	log.DebugContext(requestContext, "Setting idle subsystems", "subsystems", subsystems)
+	if err := mpdrwpool.CheckSubsystems(subsystems); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.idleSubsystems = slices.Clone(subsystems)
+	if m.pool == nil {
+		return nil
 	}
-	response, err := m.pool.SendSingleCommand(requestContext, command)
+	if err := m.pool.SetIdleSubsystems(subsystems...); err != nil {
+		return errors.Join(ErrSendCommand, err)
+	}
+	return nil
+}
+
+func (m *Impl) SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error) {
+	log.DebugContext(requestContext, "Sending single command", "command", log.Truncate(command.String(), 100))
+	var response []string
+	err := m.intercept(requestContext, []commands.SingleCommand{command}, modeSingle, func(line string) bool {
+		response = append(response, line)
+		return true
+	})
 	if err != nil {
-		return nil, errors.Join(ErrSendCommand, err)
+		return nil, err
 	}
 	return response, nil
 }
 
+func (m *Impl) SendSingleCommandSeq(requestContext context.Context, command commands.SingleCommand) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		log.DebugContext(requestContext, "Sending single command (stream)", "command", log.Truncate(command.String(), 100))
+		stopped := false
+		err := m.intercept(requestContext, []commands.SingleCommand{command}, modeStream, func(line string) bool {
+			// An interceptor may go on yielding after the consumer stopped.
+			stopped = stopped || !yield(line, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield("", err)
+		}
+	}
+}
+
 func (m *Impl) SendBatchCommand(requestContext context.Context, cmds []commands.SingleCommand) error {
 	log.DebugContext(requestContext, "Sending batch commands", "commands", log.JoinAndTruncateSingleCommands(cmds, "\n", 100))
+	return m.intercept(requestContext, cmds, modeBatch, func(string) bool { return true })
+}
+
+// currentPool returns the pool of the connection, nil if not connected. The lock is not held
+// while a command is sent, so that the commands run concurrently on the connections of the pool.
+func (m *Impl) currentPool() mpdrwpool.MpdRWPool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.pool == nil {
+	return m.pool
+}
+
+func (m *Impl) sendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error) {
+	pool := m.currentPool()
+	if pool == nil {
+		return nil, ErrNotConnected
+	}
+	response, err := pool.SendSingleCommand(requestContext, command)
+	if err != nil {
+		return nil, errors.Join(ErrSendCommand, err)
+	}
+	return response, nil
+}
+
+func (m *Impl) sendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error {
+	// The consumer may send other commands from the loop body, they are sent on another
+	// connection of the pool.
+	pool := m.currentPool()
+	if pool == nil {
+		return ErrNotConnected
+	}
+	stopped := false
+	err := pool.SendSingleCommandStream(requestContext, command, func(line string) bool {
+		stopped = !yield(line)
+		return !stopped
+	})
+	if err != nil && !stopped {
+		return errors.Join(ErrSendCommand, err)
+	}
+	return nil
+}
+
+func (m *Impl) sendBatchCommand(requestContext context.Context, cmds []commands.SingleCommand) error {
+	pool := m.currentPool()
+	if pool == nil {
 		return ErrNotConnected
 	}
 	for _, batchCommand := range commands.NewBatchCommands(cmds, int(m.config.maxBatchCommandLength)) {
-		err := m.pool.SendBatchCommand(requestContext, batchCommand)
+		err := pool.SendBatchCommand(requestContext, batchCommand)
 		if err != nil {
 			return errors.Join(err, ErrSendCommand)
 		}
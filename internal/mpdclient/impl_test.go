@@ -10,6 +10,7 @@ import (
 	"github.com/anpotashev/go-observer/pkg/observer"
 	"github.com/anpotashev/mpdgo/internal/commands"
 	"github.com/anpotashev/mpdgo/internal/mpdrwpool"
+	"github.com/anpotashev/mpdgo/pkg/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -86,6 +87,29 @@ func TestImpl_Disconnect(t *testing.T) {
 	})
 }
 
+// reconnectCounter counts the reconnections and ignores the other metrics.
+type reconnectCounter struct {
+	metrics.Nop
+	count int
+}
+
+func (c *reconnectCounter) Reconnected() {
+	c.count++
+}
+
+func TestImpl_Reconnected(t *testing.T) {
+	collector := &reconnectCounter{}
+	client := NewMpdClientImpl(defaultClientParams.ctx, defaultClientParams.host, defaultClientParams.port, defaultClientParams.password,
+		defaultClientParams.maxBatchCommandLength, defaultClientParams.poolSize, defaultClientParams.readTimeout, defaultClientParams.pingTimeout,
+		WithMetrics(collector))
+	connectTestClient(t, client)
+	assert.Equal(t, 0, collector.count)
+	assert.NoError(t, client.Disconnect(context.Background()))
+	connectTestClient(t, client)
+	assert.Equal(t, 1, collector.count)
+	assert.NoError(t, client.Disconnect(context.Background()))
+}
+
 func TestImpl_IsConnected(t *testing.T) {
 	t.Run("check connection state", func(t *testing.T) {
 		client := createClientWithDefaultValues()
@@ -99,6 +123,21 @@ func TestImpl_IsConnected(t *testing.T) {
 		state = client.IsConnected(context.Background())
 		assert.False(t, state)
 	})
+	t.Run("closed pool does not disconnect the next one", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		var onDisconnects []func()
+		factory := func(requestContext, ctx context.Context, onDisconnect func()) (mpdrwpool.MpdRWPool, error) {
+			onDisconnects = append(onDisconnects, onDisconnect)
+			return newMockMpdRWPoolFactory(requestContext, ctx, onDisconnect)
+		}
+		assert.NoError(t, client.connect(context.Background(), factory))
+		assert.NoError(t, client.Disconnect(context.Background()))
+		assert.NoError(t, client.connect(context.Background(), factory))
+		onDisconnects[0]()
+		assert.True(t, client.IsConnected(context.Background()))
+		onDisconnects[1]()
+		assert.False(t, client.IsConnected(context.Background()))
+	})
 }
 
 func TestImpl_Events(t *testing.T) {
@@ -172,6 +211,54 @@ func TestImpl_SendSingleCommand(t *testing.T) {
 	})
 }
 
+func TestImpl_SendSingleCommandSeq(t *testing.T) {
+	t.Run("stream single command. No error", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		response := []string{"aaa", "bbb", "ccc"}
+		pool.On("SendSingleCommandStream").Return(response, nil)
+		var actual []string
+		for line, err := range client.SendSingleCommandSeq(context.Background(), cmd) {
+			assert.NoError(t, err)
+			actual = append(actual, line)
+			if len(actual) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, response[:2], actual)
+		client.cancelFunc()
+	})
+	t.Run("stream single command. Error", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		pool.On("SendSingleCommandStream").Return([]string{"aaa"}, fmt.Errorf("error"))
+		var lines []string
+		var errs []error
+		for line, err := range client.SendSingleCommandSeq(context.Background(), cmd) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			lines = append(lines, line)
+		}
+		assert.Equal(t, []string{"aaa"}, lines)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], ErrSendCommand)
+		client.cancelFunc()
+	})
+	t.Run("stream single command when not connected", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		for _, err := range client.SendSingleCommandSeq(context.Background(), cmd) {
+			assert.ErrorIs(t, err, ErrNotConnected)
+		}
+	})
+}
+
 func TestImpl_SendBatchCommand(t *testing.T) {
 	t.Run("send single command. No error", func(t *testing.T) {
 		client := createClientWithDefaultValues()
@@ -209,6 +296,39 @@ func TestImpl_SendBatchCommand(t *testing.T) {
 	})
 }
 
+func TestImpl_SetIdleSubsystems(t *testing.T) {
+	t.Run("when connected", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		pool.On("SetIdleSubsystems", []string{"player", "mixer"}).Return(nil)
+		assert.NoError(t, client.SetIdleSubsystems(context.Background(), "player", "mixer"))
+		assert.Equal(t, []string{"player", "mixer"}, client.config.idleSubsystems)
+		pool.AssertExpectations(t)
+		client.cancelFunc()
+	})
+	t.Run("error", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		pool.On("SetIdleSubsystems", []string{"player"}).Return(fmt.Errorf("error"))
+		err := client.SetIdleSubsystems(context.Background(), "player")
+		assert.ErrorIs(t, err, ErrSendCommand)
+		client.cancelFunc()
+	})
+	t.Run("when not connected", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		assert.NoError(t, client.SetIdleSubsystems(context.Background(), "player"))
+		assert.Equal(t, []string{"player"}, client.config.idleSubsystems)
+	})
+	t.Run("unknown subsystem", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		err := client.SetIdleSubsystems(context.Background(), "unknown")
+		assert.ErrorIs(t, err, mpdrwpool.ErrUnknownSubsystem)
+		assert.Empty(t, client.config.idleSubsystems)
+	})
+}
+
 func connectTestClient(t *testing.T, client *Impl) {
 	requestContext := context.Background()
 	// checking connect call
@@ -0,0 +1,62 @@
+package mpdclient
+
+import (
+	"context"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+)
+
+// Invoker sends the commands and passes the lines of the answer to yield.
+// The commands of SendBatchCommand are sent as a command list, which has no answer lines.
+type Invoker func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool) error
+
+// Interceptor is called instead of sending the commands. It proceeds by calling next,
+// possibly with other commands, several times or not at all. For a single command next
+// takes a single command too, several ones fail with ErrSeveralCommands.
+type Interceptor func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error
+
+// mode is the kind of call the commands are sent by.
+type mode int
+
+const (
+	modeSingle mode = iota
+	modeStream
+	modeBatch
+)
+
+// intercept sends the commands through the interceptors, the first one being the outermost.
+func (m *Impl) intercept(requestContext context.Context, cmds []commands.SingleCommand, mode mode, yield func(line string) bool) error {
+	invoker := func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool) error {
+		return m.send(requestContext, cmds, mode, yield)
+	}
+	for i := len(m.config.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := m.config.interceptors[i], invoker
+		invoker = func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool) error {
+			return interceptor(requestContext, cmds, yield, next)
+		}
+	}
+	return invoker(requestContext, cmds, yield)
+}
+
+func (m *Impl) send(requestContext context.Context, cmds []commands.SingleCommand, mode mode, yield func(line string) bool) error {
+	switch {
+	case len(cmds) == 0:
+		return nil
+	case mode == modeBatch:
+		return m.sendBatchCommand(requestContext, cmds)
+	case len(cmds) > 1:
+		return ErrSeveralCommands
+	case mode == modeStream:
+		return m.sendSingleCommandStream(requestContext, cmds[0], yield)
+	}
+	response, err := m.sendSingleCommand(requestContext, cmds[0])
+	if err != nil {
+		return err
+	}
+	for _, line := range response {
+		if !yield(line) {
+			break
+		}
+	}
+	return nil
+}
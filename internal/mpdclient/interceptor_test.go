@@ -0,0 +1,106 @@
+package mpdclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpl_Interceptors(t *testing.T) {
+	t.Run("interceptors are called in order", func(t *testing.T) {
+		var calls []string
+		logging := func(name string) Interceptor {
+			return func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error {
+				calls = append(calls, name+" "+cmds[0].Name())
+				err := next(requestContext, cmds, yield)
+				calls = append(calls, name+" done")
+				return err
+			}
+		}
+		client := createClientWithDefaultValues()
+		WithInterceptors(logging("first"), logging("second"))(client)
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		pool.On("SendSingleCommand").Return([]string{"aaa"}, nil)
+		actual, err := client.SendSingleCommand(context.Background(), commands.NewSingleCommand(commands.PLAY))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"aaa"}, actual)
+		assert.Equal(t, []string{"first play", "second play", "second done", "first done"}, calls)
+		client.cancelFunc()
+	})
+	t.Run("interceptor rewrites commands and answer", func(t *testing.T) {
+		var sent []commands.SingleCommand
+		rewriting := func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error {
+			cmds = append(cmds, commands.NewCustomCommand("stop"))
+			return next(requestContext, cmds, func(line string) bool {
+				return yield("rewritten " + line)
+			})
+		}
+		recording := func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error {
+			sent = cmds
+			return next(requestContext, cmds, yield)
+		}
+		client := createClientWithDefaultValues()
+		WithInterceptors(rewriting, recording)(client)
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		pool.On("SendBatchCommand").Return(nil)
+		err := client.SendBatchCommand(context.Background(), []commands.SingleCommand{commands.NewSingleCommand(commands.PLAY)})
+		assert.NoError(t, err)
+		assert.Len(t, sent, 2)
+		assert.Equal(t, "stop", sent[1].Name())
+		pool.AssertCalled(t, "SendBatchCommand")
+		client.cancelFunc()
+
+		client = createClientWithDefaultValues()
+		WithInterceptors(func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error {
+			return next(requestContext, cmds, func(line string) bool {
+				return yield("rewritten " + line)
+			})
+		})(client)
+		connectTestClient(t, client)
+		pool = client.pool.(*mockMpdRWPool)
+		pool.On("SendSingleCommandStream").Return([]string{"aaa", "bbb"}, nil)
+		var lines []string
+		for line, err := range client.SendSingleCommandSeq(context.Background(), commands.NewSingleCommand(commands.LISTALLINFO)) {
+			assert.NoError(t, err)
+			lines = append(lines, line)
+		}
+		assert.Equal(t, []string{"rewritten aaa", "rewritten bbb"}, lines)
+		client.cancelFunc()
+	})
+	t.Run("interceptor rewrites a single command into several", func(t *testing.T) {
+		client := createClientWithDefaultValues()
+		WithInterceptors(func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error {
+			return next(requestContext, append(cmds, commands.NewCustomCommand("stop")), yield)
+		})(client)
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		_, err := client.SendSingleCommand(context.Background(), commands.NewSingleCommand(commands.STATUS))
+		assert.ErrorIs(t, err, ErrSeveralCommands)
+		for _, err := range client.SendSingleCommandSeq(context.Background(), commands.NewSingleCommand(commands.LISTALLINFO)) {
+			assert.ErrorIs(t, err, ErrSeveralCommands)
+		}
+		pool.AssertNotCalled(t, "SendBatchCommand")
+		pool.AssertNotCalled(t, "SendSingleCommand")
+		client.cancelFunc()
+	})
+	t.Run("interceptor short-circuits the call", func(t *testing.T) {
+		errRejected := errors.New("rejected")
+		client := createClientWithDefaultValues()
+		WithInterceptors(func(requestContext context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next Invoker) error {
+			return errRejected
+		})(client)
+		connectTestClient(t, client)
+		pool := client.pool.(*mockMpdRWPool)
+		err := client.SendBatchCommand(context.Background(), []commands.SingleCommand{
+			commands.NewSingleCommand(commands.PLAY),
+			commands.NewSingleCommand(commands.STOP)})
+		assert.ErrorIs(t, err, errRejected)
+		pool.AssertNotCalled(t, "SendBatchCommand")
+		client.cancelFunc()
+	})
+}
@@ -2,8 +2,11 @@ package mpdclient
 
 import (
 	"context"
+	"iter"
+
 	"github.com/anpotashev/go-observer/pkg/observer"
 	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/anpotashev/mpdgo/internal/mpdrwpool"
 )
 
 type MpdClient interface {
@@ -34,7 +37,23 @@ type MpdClient interface {
 	// Can return the following errors:
 	// - ErrNotConnected
 	// - ErrSendCommand
+	// - ErrSeveralCommands
 	SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error)
+	// SendSingleCommandSeq returns an iterator sending a command to the MPD server
+	// and yielding the lines of the raw response as they arrive.
+	//
+	// The command is sent every time the iterator is used. Breaking out of the loop
+	// discards the rest of the response. An error is yielded as the last element.
+	//
+	// A connection of the pool is held until the loop ends. A command sent from the loop body
+	// needs another one: with a pool of a single connection it waits until its request context
+	// is done.
+	//
+	// Can yield the following errors:
+	// - ErrNotConnected
+	// - ErrSendCommand
+	// - ErrSeveralCommands
+	SendSingleCommandSeq(requestContext context.Context, command commands.SingleCommand) iter.Seq2[string, error]
 	// SendBatchCommand sends a batch command to the MPD server
 	//
 	// The requestContext is used for logging.
@@ -43,6 +62,20 @@ type MpdClient interface {
 	// - ErrNotConnected
 	// - ErrSendCommand
 	SendBatchCommand(requestContext context.Context, cmd []commands.SingleCommand) error
+	// Stats returns a snapshot of the connections of the pool.
+	//
+	// Can return the following errors:
+	// - ErrNotConnected
+	Stats(requestContext context.Context) (mpdrwpool.Stats, error)
+	// SetIdleSubsystems replaces the subsystems the events are received for, all of them if empty.
+	// Unless connected, they are used on Connect.
+	//
+	// The requestContext is used for logging.
+	//
+	// Can return the following errors:
+	// - mpdrwpool.ErrUnknownSubsystem
+	// - ErrSendCommand
+	SetIdleSubsystems(requestContext context.Context, subsystems ...string) error
 	observer.Observer[string]
 }
 
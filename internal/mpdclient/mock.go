@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/anpotashev/go-observer/pkg/observer"
 	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/anpotashev/mpdgo/internal/mpdrwpool"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -20,6 +21,26 @@ func (m *mockMpdRWPool) SendSingleCommand(requestContext context.Context, comman
 	return nil, args.Error(1)
 }
 
+func (m *mockMpdRWPool) SendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error {
+	args := m.Called()
+	if args.Get(0) != nil {
+		for _, line := range args.Get(0).([]string) {
+			if !yield(line) {
+				break
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *mockMpdRWPool) Stats() mpdrwpool.Stats {
+	return mpdrwpool.Stats{MaxSize: 1}
+}
+
+func (m *mockMpdRWPool) SetIdleSubsystems(subsystems ...string) error {
+	return m.Called(subsystems).Error(0)
+}
+
 func (m *mockMpdRWPool) SendBatchCommand(requestContext context.Context, command commands.BatchCommand) error {
 	return m.Called().Error(0)
 }
@@ -0,0 +1,60 @@
+package mpdclient
+
+import (
+	"github.com/anpotashev/mpdgo/internal/mpdrw"
+	"github.com/anpotashev/mpdgo/internal/mpdrwpool"
+	"github.com/anpotashev/mpdgo/pkg/metrics"
+)
+
+// Option configures an Impl created with NewMpdClientImpl.
+type Option func(*Impl)
+
+// WithDialer replaces the TCP dialer connecting to host and port.
+func WithDialer(dialer mpdrw.Dialer) Option {
+	return func(m *Impl) {
+		m.config.dialer = dialer
+	}
+}
+
+// WithRecorder records the traffic of all connections, whichever dialer is used.
+func WithRecorder(recorder *mpdrw.Recorder) Option {
+	return func(m *Impl) {
+		m.config.recorder = recorder
+	}
+}
+
+// WithMetrics reports the commands, the pool usage, the reconnections and the idle events to collector.
+func WithMetrics(collector metrics.Metrics) Option {
+	return func(m *Impl) {
+		m.config.metrics = collector
+	}
+}
+
+// WithInterceptors adds interceptors around sending the commands. The first one is the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(m *Impl) {
+		m.config.interceptors = append(m.config.interceptors, interceptors...)
+	}
+}
+
+// WithRetryPolicy sets how idempotent commands are retried after an IO error, see mpdrwpool.RetryPolicy.
+func WithRetryPolicy(policy mpdrwpool.RetryPolicy) Option {
+	return func(m *Impl) {
+		m.config.retryPolicy = policy
+	}
+}
+
+// WithSizing makes the pool open and close its connections as needed instead of keeping poolSize open,
+// see mpdrwpool.Sizing.
+func WithSizing(sizing mpdrwpool.Sizing) Option {
+	return func(m *Impl) {
+		m.config.sizing = &sizing
+	}
+}
+
+// WithIdleSubsystems makes the client receive the events of the subsystems only, see mpdrwpool.Subsystems.
+func WithIdleSubsystems(subsystems ...string) Option {
+	return func(m *Impl) {
+		m.config.idleSubsystems = subsystems
+	}
+}
@@ -4,20 +4,41 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 )
 
 var (
 	ErrACK = fmt.Errorf("ACK error")
 	ErrIO  = errors.New("IO error on sending command")
+
+	ErrRecordingFormat = errors.New("invalid recording format")
+	ErrReplayMismatch  = errors.New("command does not match the recording")
+	ErrReplayExhausted = errors.New("no more recorded sessions")
 )
 
+// AckError is an ACK answer of the MPD server: ACK [Code@Index] {Command} Message.
+// It is joined with ErrACK, use errors.As to get it.
+type AckError struct {
+	// Code is the error code, e.g. 50 when the requested object does not exist.
+	Code int
+	// Index is the index of the failed command in a command list.
+	Index   int
+	Command string
+	Message string
+}
+
+func (e *AckError) Error() string {
+	return fmt.Sprintf("ACK error on sending command %s: %s", e.Command, e.Message)
+}
+
+var ackRegexp = regexp.MustCompile(`ACK (?:\[(\d+)@(\d+)\] )?.*\{(.*)\} (.+)`)
+
 func parseACKAnswer(answer string) error {
-	re := regexp.MustCompile(`ACK .*\{(.*)\} (.+)`)
-	matches := re.FindStringSubmatch(answer)
-	if len(matches) == 3 {
-		command := matches[1]
-		errorMessage := matches[2]
-		return errors.Join(fmt.Errorf("ACK error on sending command %s: %s", command, errorMessage), ErrACK)
+	matches := ackRegexp.FindStringSubmatch(answer)
+	if len(matches) == 5 {
+		code, _ := strconv.Atoi(matches[1])
+		index, _ := strconv.Atoi(matches[2])
+		return errors.Join(&AckError{Code: code, Index: index, Command: matches[3], Message: matches[4]}, ErrACK)
 	}
 	return errors.Join(fmt.Errorf("unexpected answer format: %s", answer), ErrACK)
 }
@@ -6,24 +6,34 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/anpotashev/mpdgo/internal/commands"
 	log "github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/anpotashev/mpdgo/pkg/tracing"
 	"github.com/google/uuid"
 )
 
 type Impl struct {
 	rw          *bufio.ReadWriter
 	readTimeout time.Duration
+	close       context.CancelFunc
+	// mu guards writing idle and noidle, as noidle is written while the answer to idle is read.
+	mu sync.Mutex
+	// idling reports whether an idle command waits for its answer.
+	idling bool
+	// noIdle makes the next idle command return at once, as noidle was asked before it was sent.
+	noIdle bool
 }
 
 type Dialer func() (net.Conn, error)
 
 func NewDialer(host string, port uint16) Dialer {
 	return func() (net.Conn, error) {
-		return net.Dial("tcp", fmt.Sprintf("%s:%d", host, port))
+		return net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
 	}
 }
 
@@ -32,6 +42,9 @@ func (d Dialer) NewMpdRW(requestContext, ctx context.Context, password string, r
 }
 
 func newMpdRW(requestContext, ctx context.Context, dialer Dialer, password string, readTimeout time.Duration) (*Impl, error) {
+	if requestContext == nil {
+		requestContext = context.Background()
+	}
 	log.DebugContext(requestContext, "Connecting to mpd")
 	log.DebugContext(requestContext, "Dialing")
 	conn, err := dialer()
@@ -41,19 +54,25 @@ func newMpdRW(requestContext, ctx context.Context, dialer Dialer, password strin
 	log.DebugContext(requestContext, "Creating reader and writer")
 	r := bufio.NewReader(conn)
 	w := bufio.NewWriter(conn)
+	ctx, cancel := context.WithCancel(ctx)
 	impl := &Impl{
 		rw:          bufio.NewReadWriter(r, w),
 		readTimeout: readTimeout,
+		close:       cancel,
 	}
 	log.DebugContext(requestContext, "Starting a goroutine that closes the connection on ctx.Done")
 	go func() {
 		defer conn.Close()
 		<-ctx.Done()
+		// Leave an in-progress idle before closing, the write must not delay the closing though.
+		_ = conn.SetWriteDeadline(time.Now().Add(closeWriteTimeout))
+		_ = impl.NoIdle()
 	}()
 	log.DebugContext(requestContext, "Listening version")
-	_, err = impl.readAnswerWithTimeout(requestContext)
+	_, err = impl.readAnswerWithTimeout(requestContext, "")
 	if err != nil {
 		log.DebugContext(requestContext, "Error reading answer: %v", err)
+		cancel()
 		return nil, err
 	}
 	if password != "" {
@@ -61,29 +80,57 @@ func newMpdRW(requestContext, ctx context.Context, dialer Dialer, password strin
 		passwordCommand := commands.NewSingleCommand(commands.PASSWORD).AddParams(password)
 		_, err := impl.SendSingleCommand(requestContext, passwordCommand)
 		if err != nil {
+			cancel()
 			return nil, err
 		}
 	}
 	return impl, nil
 }
 
-func (m *Impl) SendIdleCommand() ([]string, error) {
+func (m *Impl) Close() error {
+	m.close()
+	return nil
+}
+
+// closeWriteTimeout limits writing noidle to a connection being closed.
+const closeWriteTimeout = 100 * time.Millisecond
+
+func (m *Impl) SendIdleCommand(subsystems ...string) ([]string, error) {
 	idleCommandContext := context.Background()
 	commandUUID, _ := uuid.NewUUID()
 	idleCommandContext = context.WithValue(idleCommandContext, "command_id", commandUUID.String())
-	log.DebugContext(idleCommandContext, "Sending idle command")
+	m.mu.Lock()
+	if m.noIdle {
+		m.noIdle = false
+		m.mu.Unlock()
+		log.DebugContext(idleCommandContext, "Noidle was asked already, not sending idle command")
+		return nil, nil
+	}
+	log.DebugContext(idleCommandContext, "Sending idle command", "subsystems", subsystems)
 	command := commands.NewSingleCommand(commands.IDLE)
+	for _, subsystem := range subsystems {
+		command = command.AddParams(subsystem)
+	}
 	log.DebugContext(idleCommandContext, "Writing the command")
 	_, err := m.rw.WriteString(command.String())
 	if err != nil {
+		m.mu.Unlock()
 		return nil, errors.Join(err, errors.Join(ErrIO, err))
 	}
 	log.DebugContext(idleCommandContext, "Flushing the writer")
 	err = m.rw.Flush()
 	if err != nil {
+		m.mu.Unlock()
 		log.ErrorContext(idleCommandContext, "Flushing the writer error", "err", err)
 		return nil, errors.Join(err, errors.Join(ErrIO, err))
 	}
+	m.idling = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.idling = false
+		m.mu.Unlock()
+	}()
 	log.DebugContext(idleCommandContext, "Creating answer and error channels")
 	answerChan := make(chan []string)
 	errorChan := make(chan error)
@@ -91,7 +138,7 @@ func (m *Impl) SendIdleCommand() ([]string, error) {
 	//lint:ignore SA1012 ignore
 	idleCommandContext, cancel := context.WithCancel(idleCommandContext)
 	defer cancel()
-	go m.readAnswer(idleCommandContext, answerChan, errorChan, nil)
+	go m.readAnswer(idleCommandContext, answerChan, errorChan)
 	select {
 	case answer := <-answerChan:
 		log.DebugContext(idleCommandContext, "Got answer in the answer channel", "answer", log.Truncate(strings.Join(answer, "\n"), 100))
@@ -102,59 +149,191 @@ func (m *Impl) SendIdleCommand() ([]string, error) {
 	}
 }
 
+func (m *Impl) NoIdle() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.idling {
+		m.noIdle = true
+		return nil
+	}
+	log.Debug("Sending noidle command")
+	_, err := m.rw.WriteString(commands.NewSingleCommand(commands.NOIDLE).String())
+	if err == nil {
+		err = m.rw.Flush()
+	}
+	if err != nil {
+		return errors.Join(ErrIO, err)
+	}
+	return nil
+}
+
 func (m *Impl) SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error) {
 	return m.sendCommand(requestContext, &command)
 }
 
+func (m *Impl) SendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error {
+	requestContext, err := m.writeCommand(requestContext, &command)
+	if err != nil {
+		return err
+	}
+	log.DebugContext(requestContext, "Streaming the answer")
+	return m.readLinesWithTimeout(requestContext, command.Name(), yield)
+}
+
 func (m *Impl) SendBatchCommand(requestContext context.Context, command commands.BatchCommand) error {
 	_, err := m.sendCommand(requestContext, command)
 	return err
 }
 
 func (m *Impl) sendCommand(requestContext context.Context, command commands.MpdCommand) ([]string, error) {
+	requestContext, err := m.writeCommand(requestContext, command)
+	if err != nil {
+		return nil, err
+	}
+	log.DebugContext(requestContext, "Waiting the answer")
+	return m.readAnswerWithTimeout(requestContext, command.Name())
+}
+
+// writeCommand sends the command and returns the requestContext stamped with a command id.
+func (m *Impl) writeCommand(requestContext context.Context, command commands.MpdCommand) (context.Context, error) {
 	if requestContext == nil {
 		requestContext = context.Background()
 	}
 	commandUUID, _ := uuid.NewUUID()
 	requestContext = context.WithValue(requestContext, "command_id", commandUUID.String())
-	log.DebugContext(requestContext, "Sending command", "command", command.String())
-	_, err := m.rw.WriteString(command.String())
+	_, span := tracing.Start(requestContext, "mpd.write", tracing.String(tracing.AttrCommand, command.Name()))
+	defer span.End()
+	data := command.String()
+	log.DebugContext(requestContext, "Sending command", "command", data)
+	_, err := m.rw.WriteString(data)
 	if err != nil {
-		return nil, errors.Join(errors.Join(ErrIO, err), err)
+		span.RecordError(err)
+		return requestContext, errors.Join(errors.Join(ErrIO, err), err)
 	}
 	log.DebugContext(requestContext, "Flushing the writer")
 	err = m.rw.Flush()
 	if err != nil {
-		return nil, errors.Join(errors.Join(ErrIO, err), err)
+		span.RecordError(err)
+		return requestContext, errors.Join(errors.Join(ErrIO, err), err)
 	}
-	log.DebugContext(requestContext, "Waiting the answer")
-	return m.readAnswerWithTimeout(requestContext)
+	span.SetAttributes(tracing.Int(tracing.AttrBytesWritten, len(data)))
+	return requestContext, nil
 }
 
-func (m *Impl) readAnswerWithTimeout(requestContext context.Context) ([]string, error) {
-	log.DebugContext(requestContext, "Creating answer and error channels")
-	answerChan := make(chan []string)
+func (m *Impl) readAnswerWithTimeout(requestContext context.Context, command string) ([]string, error) {
+	var result []string
+	err := m.readLinesWithTimeout(requestContext, command, func(line string) bool {
+		result = append(result, line)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.DebugContext(requestContext, "Received the answer", "answer", log.Truncate(strings.Join(result, "\n"), 100))
+	return result, nil
+}
+
+// readLinesWithTimeout passes the lines of the answer to yield as they arrive.
+//
+// If yield returns false, the rest of the answer is read and discarded, so the
+// connection stays usable. readTimeout limits the wait for every single line.
+// command is the name of the command answered, "" for the greeting of the server.
+func (m *Impl) readLinesWithTimeout(requestContext context.Context, command string, yield func(line string) bool) (err error) {
+	var attrs []tracing.Attribute
+	if command != "" {
+		attrs = append(attrs, tracing.String(tracing.AttrCommand, command))
+	}
+	_, span := tracing.Start(requestContext, "mpd.read", attrs...)
+	bytesRead, lines := 0, 0
+	defer func() {
+		span.SetAttributes(tracing.Int(tracing.AttrBytesRead, bytesRead), tracing.Int(tracing.AttrLines, lines))
+		if err != nil {
+			var ackErr *AckError
+			if errors.As(err, &ackErr) {
+				span.SetAttributes(tracing.Int(tracing.AttrAckCode, ackErr.Code))
+			}
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+	log.DebugContext(requestContext, "Creating line and error channels")
+	lineChan := make(chan string, 64)
 	errorChan := make(chan error)
 	log.DebugContext(requestContext, "Creation the timer")
 	timer := time.NewTimer(m.readTimeout)
+	defer timer.Stop()
 	log.DebugContext(requestContext, "Starting a goroutine that reads an answer")
 	requestContext, cancel := context.WithCancel(requestContext)
 	defer cancel()
-	go m.readAnswer(requestContext, answerChan, errorChan, timer)
-	select {
-	case answer := <-answerChan:
-		log.DebugContext(requestContext, "Received data from the answer channel", "answer", log.Truncate(strings.Join(answer, "\n"), 100))
-		return answer, nil
-	case err := <-errorChan:
-		log.DebugContext(requestContext, "Received data from the error channel", "err", err)
-		return nil, err
-	case <-timer.C:
-		log.DebugContext(requestContext, "Timeout")
-		return nil, errors.Join(ErrIO, fmt.Errorf("timeout reading the answer"))
+	go m.readLines(requestContext, lineChan, errorChan)
+	stopped := false
+	for {
+		select {
+		case line, ok := <-lineChan:
+			if !ok {
+				log.DebugContext(requestContext, "The answer is completely read")
+				return nil
+			}
+			bytesRead += len(line) + 1
+			lines++
+			if !stopped && !yield(line) {
+				log.DebugContext(requestContext, "Consumer stopped. Discarding the rest of the answer")
+				stopped = true
+			}
+			timer.Reset(m.readTimeout)
+		case err := <-errorChan:
+			log.DebugContext(requestContext, "Received data from the error channel", "err", err)
+			return err
+		case <-timer.C:
+			log.DebugContext(requestContext, "Timeout")
+			return errors.Join(ErrIO, fmt.Errorf("timeout reading the answer"))
+		case <-requestContext.Done():
+			// The rest of the answer is not read, so the connection can't be used anymore.
+			log.DebugContext(requestContext, "Request context is done")
+			return errors.Join(ErrIO, context.Cause(requestContext))
+		}
+	}
+}
+
+// readLines sends the lines of the answer to lineChan and closes it when the answer ends with OK.
+func (m *Impl) readLines(requestContext context.Context, lineChan chan string, errorChan chan error) {
+	log.DebugContext(requestContext, "Starting reading the answer")
+	for {
+		line, err := m.rw.ReadString('\n')
+		if err != nil {
+			select {
+			case errorChan <- errors.Join(ErrIO, err):
+			case <-requestContext.Done():
+			}
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			continue
+		}
+		isEnded, err := isAnswerEnded(line)
+		if isEnded {
+			if err != nil {
+				select {
+				case errorChan <- err:
+				case <-requestContext.Done():
+				}
+				log.DebugContext(requestContext, "stop reading answers (error case)")
+				return
+			}
+			close(lineChan)
+			log.DebugContext(requestContext, "stop reading answers (success case)")
+			return
+		}
+		select {
+		case lineChan <- line:
+		case <-requestContext.Done():
+			return
+		}
 	}
 }
 
-func (m *Impl) readAnswer(requestContext context.Context, readChan chan []string, errorChan chan error, timer *time.Timer) {
+func (m *Impl) readAnswer(requestContext context.Context, readChan chan []string, errorChan chan error) {
 	log.DebugContext(requestContext, "Starting reading the answer")
 	var result []string
 	for {
@@ -188,9 +367,6 @@ func (m *Impl) readAnswer(requestContext context.Context, readChan chan []string
 			return
 		}
 		result = append(result, line)
-		if timer != nil {
-			timer.Reset(m.readTimeout)
-		}
 	}
 }
 
@@ -131,6 +131,86 @@ func TestImpl_SendSingleCommand(t *testing.T) {
 		assert.Nil(t, response)
 		assert.Equal(t, cmd.String(), mockConn.readAllFromOutChan())
 	})
+	t.Run("request context done waiting response", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		mockConn.mockOnRead("first")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		cmd := commands.NewSingleCommand(commands.PING)
+		response, err := rw.SendSingleCommand(ctx, cmd)
+		assert.ErrorIs(t, err, ErrIO)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, response)
+	})
+}
+
+func TestImpl_SendSingleCommandStream(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		mockConn.mockOnRead("first", "", "second", "OK")
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		var lines []string
+		err := rw.SendSingleCommandStream(defaultConnectParams.requestContext, cmd, func(line string) bool {
+			lines = append(lines, line)
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second"}, lines)
+		assert.Equal(t, cmd.String(), mockConn.readAllFromOutChan())
+	})
+	t.Run("stopping early discards the rest of the answer", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		mockConn.mockOnRead("first", "second", "third", "OK", "next", "OK")
+		var lines []string
+		err := rw.SendSingleCommandStream(defaultConnectParams.requestContext, commands.NewSingleCommand(commands.LISTALLINFO), func(line string) bool {
+			lines = append(lines, line)
+			return false
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first"}, lines)
+		response, err := rw.SendSingleCommand(defaultConnectParams.requestContext, commands.NewSingleCommand(commands.PING))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"next"}, response)
+	})
+	t.Run("received ACK error", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		mockConn.mockOnRead("first", "ACK error sending command")
+		var lines []string
+		err := rw.SendSingleCommandStream(defaultConnectParams.requestContext, commands.NewSingleCommand(commands.LISTALLINFO), func(line string) bool {
+			lines = append(lines, line)
+			return true
+		})
+		assert.ErrorIs(t, err, ErrACK)
+		assert.Equal(t, []string{"first"}, lines)
+	})
+	t.Run("timeout waiting response", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		mockConn.mockOnRead("first")
+		err := rw.SendSingleCommandStream(defaultConnectParams.requestContext, commands.NewSingleCommand(commands.LISTALLINFO), func(line string) bool {
+			return true
+		})
+		assert.ErrorIs(t, err, ErrIO)
+	})
 }
 
 func TestImpl_SendMultipleCommands(t *testing.T) {
@@ -304,6 +384,65 @@ func TestImpl_SendIdleCommand(t *testing.T) {
 	})
 }
 
+func TestImpl_NoIdle(t *testing.T) {
+	type idleAnswer struct {
+		events []string
+		err    error
+	}
+	startIdle := func(rw MpdRW, subsystems ...string) chan idleAnswer {
+		answers := make(chan idleAnswer, 1)
+		go func() {
+			events, err := rw.SendIdleCommand(subsystems...)
+			answers <- idleAnswer{events, err}
+		}()
+		return answers
+	}
+	t.Run("interrupts the idle command", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		answers := startIdle(rw, "player", "mixer")
+		assert.Eventually(t, func() bool {
+			return rw.(*Impl).isIdling()
+		}, time.Second, time.Millisecond)
+		assert.NoError(t, rw.NoIdle())
+		assert.Equal(t, "idle \"player\" \"mixer\"\nnoidle\n", mockConn.readAllFromOutChan())
+		mockConn.mockOnRead("OK")
+		select {
+		case answer := <-answers:
+			assert.NoError(t, answer.err)
+			assert.Empty(t, answer.events)
+		case <-time.After(time.Second):
+			t.Fatal("idle command not interrupted")
+		}
+	})
+	t.Run("before the idle command", func(t *testing.T) {
+		mockConn := &MockConn{
+			in:  make(chan byte, 1024),
+			out: make(chan byte, 1024),
+		}
+		rw := happyPathConnect(t, mockConn)
+		assert.NoError(t, rw.NoIdle())
+		select {
+		case answer := <-startIdle(rw):
+			assert.NoError(t, answer.err)
+			assert.Empty(t, answer.events)
+		case <-time.After(time.Second):
+			t.Fatal("idle command not interrupted")
+		}
+		assert.Empty(t, mockConn.readAllFromOutChan())
+	})
+}
+
+// isIdling reports whether an idle command waits for its answer.
+func (m *Impl) isIdling() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.idling
+}
+
 func happyPathConnect(t *testing.T, mockConn *MockConn) MpdRW {
 	responses := []string{
 		fmt.Sprintf("OK MPD %s", version),
@@ -8,13 +8,21 @@ import (
 type MpdRW interface {
 	// SendIdleCommand sends an IDLE command to the MPD server
 	//
+	// subsystems limit the events waited for, all of them are waited for if empty.
 	// Returns a slice of strings containing the raw response from the MPD server
 	// This function should be called in a goroutine. It returns the response
-	// after receiving an IDLE event from MPD server.
+	// after receiving an IDLE event from MPD server, or after NoIdle, then the response
+	// may be empty.
 	// Can return the following errors:
 	// - ErrIO: returned if connection is lost
-	// - ErrACK: theoretically possible if the client receives an ACK response for the IDLE command; practically, this should not happen.
-	SendIdleCommand() ([]string, error)
+	// - ErrACK: returned if a subsystem is unknown to the MPD server.
+	SendIdleCommand(subsystems ...string) ([]string, error)
+
+	// NoIdle sends a NOIDLE command, so the in-progress SendIdleCommand returns.
+	// If no idle command is in progress, the next SendIdleCommand returns at once without sending it.
+	// Can return the following errors:
+	// - ErrIO: returned if connection is lost
+	NoIdle() error
 
 	// SendSingleCommand sends a command to the MPD server
 	//
@@ -25,6 +33,16 @@ type MpdRW interface {
 	// - ErrACK: returned if an ACK response is received from the MPD server.
 	SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error)
 
+	// SendSingleCommandStream sends a command to the MPD server and passes
+	// the lines of the raw response to yield as they arrive.
+	//
+	// The requestContext is used for logging.
+	// If yield returns false, the rest of the response is read and discarded.
+	// Can return the following errors:
+	// - ErrIO: returned if connection is lost.
+	// - ErrACK: returned if an ACK response is received from the MPD server.
+	SendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error
+
 	// SendBatchCommand sends a batch command to the MPD server
 	//
 	// The requestContext
@@ -32,4 +50,7 @@ type MpdRW interface {
 	// - ErrIO: returned if connection is lost.
 	// - ErrACK: returned if an ACK response is received from the MPD server.
 	SendBatchCommand(requestContext context.Context, command commands.BatchCommand) error
+
+	// Close closes the connection. It is also closed when the ctx it was created with is done.
+	Close() error
 }
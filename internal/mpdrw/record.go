@@ -0,0 +1,195 @@
+package mpdrw
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/anpotashev/mpdgo/internal/logger"
+)
+
+// Direction is the kind of a recorded event.
+type Direction string
+
+const (
+	// DirectionDial starts a session. Line holds the dial error, empty if the connection was opened.
+	DirectionDial Direction = "dial"
+	// DirectionSend is a line sent to the MPD server.
+	DirectionSend Direction = "send"
+	// DirectionRecv is a line received from the MPD server.
+	DirectionRecv Direction = "recv"
+	// DirectionEOF means reading from the connection failed before it was closed by the client.
+	// Line holds the read error.
+	DirectionEOF Direction = "eof"
+	// DirectionClose means the connection was closed by the client.
+	DirectionClose Direction = "close"
+)
+
+// Record is a single event of a recording.
+//
+// A recording is a file with one JSON encoded Record per line, e.g.
+//
+//	{"session":1,"t":1021000,"dir":"dial"}
+//	{"session":1,"t":1309000,"dir":"recv","line":"OK MPD 0.23.5"}
+//	{"session":1,"t":1377000,"dir":"send","line":"status"}
+//	{"session":1,"t":1620000,"dir":"recv","line":"volume: 100"}
+//	{"session":1,"t":1625000,"dir":"recv","line":"OK"}
+//
+// Every connection is a session numbered from 1 in dial order. Lines are stored without
+// the trailing line break, the password sent with the password command is replaced by "***".
+type Record struct {
+	Session int `json:"session"`
+	// Time is the time since the recording started, in nanoseconds.
+	Time      time.Duration `json:"t"`
+	Direction Direction     `json:"dir"`
+	Line      string        `json:"line,omitempty"`
+}
+
+// redactedPassword replaces the password in the recorded password command.
+const redactedPassword = `password "***"`
+
+// redact hides the argument of the password command.
+func redact(line string) string {
+	if strings.HasPrefix(line, "password ") {
+		return redactedPassword
+	}
+	return line
+}
+
+// Recorder writes the traffic of the connections opened by its dialers to a recording.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	start    time.Time
+	sessions int
+	err      error
+}
+
+// NewRecorder returns a Recorder writing the recording to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w), start: time.Now()}
+}
+
+// Wrap returns a dialer recording the connections opened by dialer as new sessions.
+func (r *Recorder) Wrap(dialer Dialer) Dialer {
+	return func() (net.Conn, error) {
+		r.mu.Lock()
+		r.sessions++
+		session := r.sessions
+		r.mu.Unlock()
+		conn, err := dialer()
+		if err != nil {
+			r.record(session, DirectionDial, err.Error())
+			return nil, err
+		}
+		r.record(session, DirectionDial, "")
+		return &recordingConn{Conn: conn, recorder: r, session: session}, nil
+	}
+}
+
+// Err returns the first error writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(session int, direction Direction, line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	record := Record{Session: session, Time: time.Since(r.start), Direction: direction, Line: line}
+	if err := r.enc.Encode(record); err != nil {
+		log.Warn("Error writing the recording. Recording stopped", "err", err)
+		r.err = err
+	}
+}
+
+// ReadRecords reads a recording written by a Recorder.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Join(ErrRecordingFormat, fmt.Errorf("line %d: %w", lineNumber, err))
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Join(ErrRecordingFormat, err)
+	}
+	return records, nil
+}
+
+// lineBuffer splits a stream of bytes into lines.
+type lineBuffer struct {
+	partial []byte
+}
+
+// lines appends p to the buffer and returns the completed lines.
+func (b *lineBuffer) lines(p []byte) []string {
+	var result []string
+	b.partial = append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(b.partial, '\n')
+		if i < 0 {
+			return result
+		}
+		result = append(result, string(b.partial[:i]))
+		b.partial = b.partial[i+1:]
+	}
+}
+
+// recordingConn records the lines written to and read from the wrapped connection.
+type recordingConn struct {
+	net.Conn
+	recorder *Recorder
+	session  int
+	// sent and received are only used by Write and Read, which are never called concurrently with themselves.
+	sent     lineBuffer
+	received lineBuffer
+	closed   atomic.Bool
+	failed   atomic.Bool
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	for _, line := range c.sent.lines(p[:n]) {
+		c.recorder.record(c.session, DirectionSend, redact(line))
+	}
+	return n, err
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	for _, line := range c.received.lines(p[:n]) {
+		c.recorder.record(c.session, DirectionRecv, line)
+	}
+	if err != nil && !c.closed.Load() && !c.failed.Swap(true) {
+		c.recorder.record(c.session, DirectionEOF, err.Error())
+	}
+	return n, err
+}
+
+func (c *recordingConn) Close() error {
+	if !c.closed.Swap(true) {
+		c.recorder.record(c.session, DirectionClose, "")
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,138 @@
+package mpdrw
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer answers the commands with the lines in answers followed by OK, and closes the connection on stop.
+func fakeServer(answers map[string][]string) Dialer {
+	return func() (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			w := bufio.NewWriter(server)
+			write := func(lines ...string) {
+				for _, line := range lines {
+					_, _ = w.WriteString(line + "\n")
+				}
+				_ = w.Flush()
+			}
+			write("OK MPD " + version)
+			scanner := bufio.NewScanner(server)
+			for scanner.Scan() {
+				if scanner.Text() == "stop" {
+					return
+				}
+				write(append(answers[scanner.Text()], "OK")...)
+			}
+		}()
+		return client, nil
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	answers := map[string][]string{"status": {"volume: 100", "state: play"}}
+	status := commands.NewSingleCommand(commands.STATUS)
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rw, err := recorder.Wrap(fakeServer(answers)).NewMpdRW(ctx, ctx, "secret", time.Second)
+	require.NoError(t, err)
+	answer, err := rw.SendSingleCommand(ctx, status)
+	require.NoError(t, err)
+	assert.Equal(t, answers["status"], answer)
+	require.NoError(t, recorder.Err())
+
+	records, err := ReadRecords(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	var events []string
+	for _, record := range records {
+		assert.Equal(t, 1, record.Session)
+		events = append(events, string(record.Direction)+" "+record.Line)
+	}
+	assert.Equal(t, []string{
+		"dial ",
+		"recv OK MPD " + version,
+		`send password "***"`,
+		"recv OK",
+		"send status",
+		"recv volume: 100",
+		"recv state: play",
+		"recv OK",
+	}, events)
+	assert.NotContains(t, recording.String(), "secret")
+
+	t.Run("replay", func(t *testing.T) {
+		replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+		rw, err := Dialer(replayer.Dial).NewMpdRW(context.Background(), context.Background(), "other", time.Second)
+		require.NoError(t, err)
+		_, err = rw.SendSingleCommand(context.Background(), commands.NewSingleCommand(commands.PING))
+		assert.NoError(t, err)
+		answer, err := rw.SendSingleCommand(context.Background(), status)
+		require.NoError(t, err)
+		assert.Equal(t, answers["status"], answer)
+		assert.NoError(t, replayer.Err())
+
+		_, err = replayer.Dial()
+		assert.ErrorIs(t, err, ErrReplayExhausted)
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+		require.NoError(t, err)
+		rw, err := Dialer(replayer.Dial).NewMpdRW(context.Background(), context.Background(), "secret", time.Second)
+		require.NoError(t, err)
+		_, err = rw.SendSingleCommand(context.Background(), commands.NewSingleCommand(commands.PAUSE))
+		assert.ErrorIs(t, err, ErrIO)
+		assert.ErrorIs(t, replayer.Err(), ErrReplayMismatch)
+	})
+}
+
+func TestRecordLostConnection(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	ctx := context.Background()
+	rw, err := recorder.Wrap(fakeServer(nil)).NewMpdRW(ctx, ctx, "", time.Second)
+	require.NoError(t, err)
+	stop := commands.NewSingleCommand(commands.STOP)
+	_, err = rw.SendSingleCommand(ctx, stop)
+	require.ErrorIs(t, err, ErrIO)
+
+	replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	rw, err = Dialer(replayer.Dial).NewMpdRW(ctx, ctx, "", time.Second)
+	require.NoError(t, err)
+	_, err = rw.SendSingleCommand(ctx, stop)
+	assert.ErrorIs(t, err, ErrIO)
+	assert.NoError(t, replayer.Err())
+}
+
+func TestReplayDialError(t *testing.T) {
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	_, err := recorder.Wrap(func() (net.Conn, error) { return nil, errors.New("connection refused") })()
+	require.Error(t, err)
+
+	replayer, err := NewReplayer(strings.NewReader(recording.String()))
+	require.NoError(t, err)
+	_, err = replayer.Dial()
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestReadRecords(t *testing.T) {
+	_, err := ReadRecords(strings.NewReader("{\"session\":1,\"dir\":\"dial\"}\n\nnot json\n"))
+	assert.ErrorIs(t, err, ErrRecordingFormat)
+	assert.ErrorContains(t, err, "line 3")
+}
@@ -0,0 +1,231 @@
+package mpdrw
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// exchange is a recorded command with its answer.
+type exchange struct {
+	sent     []string
+	received []string
+	// eof reports whether the connection was lost after the answer.
+	eof bool
+}
+
+// isPing reports whether the exchange is a ping, which depends on timing and may be skipped on replay.
+func (e *exchange) isPing() bool {
+	return len(e.sent) == 1 && e.sent[0] == "ping"
+}
+
+type replaySession struct {
+	id        int
+	dialErr   string
+	exchanges []*exchange
+}
+
+// head returns the index of the first exchange that may answer the pending lines, or -1.
+// Recorded pings are skipped unless a ping is pending.
+func (s *replaySession) head(pending []string) int {
+	for i, e := range s.exchanges {
+		if e.isPing() && pending[0] != "ping" {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// Replayer serves the sessions of a recording written by a Recorder.
+//
+// Every dial gets the next recorded session. A command is answered with the next exchange recorded
+// for it on its own session, or else on another session, as a pool may use its connections in a different
+// order. Pings are answered even if not recorded. A command that does not match closes the connection.
+type Replayer struct {
+	mu       sync.Mutex
+	sessions []*replaySession
+	next     int
+	err      error
+}
+
+// NewReplayer reads the recording from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	records, err := ReadRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make(map[int]*replaySession)
+	current := make(map[int]*exchange)
+	for _, record := range records {
+		session, ok := sessions[record.Session]
+		if !ok {
+			session = &replaySession{id: record.Session}
+			sessions[record.Session] = session
+		}
+		e := current[record.Session]
+		switch record.Direction {
+		case DirectionDial:
+			session.dialErr = record.Line
+		case DirectionSend:
+			if e == nil || len(e.received) > 0 {
+				e = &exchange{}
+				session.exchanges = append(session.exchanges, e)
+				current[record.Session] = e
+			}
+			e.sent = append(e.sent, record.Line)
+		case DirectionRecv:
+			if e == nil {
+				e = &exchange{}
+				session.exchanges = append(session.exchanges, e)
+				current[record.Session] = e
+			}
+			e.received = append(e.received, record.Line)
+		case DirectionEOF:
+			if e == nil {
+				e = &exchange{}
+				session.exchanges = append(session.exchanges, e)
+			}
+			e.eof = true
+			current[record.Session] = nil
+		case DirectionClose:
+		default:
+			return nil, errors.Join(ErrRecordingFormat, fmt.Errorf("unknown direction %q", record.Direction))
+		}
+	}
+	result := &Replayer{}
+	for _, id := range slices.Sorted(maps.Keys(sessions)) {
+		result.sessions = append(result.sessions, sessions[id])
+	}
+	return result, nil
+}
+
+// Dial opens a connection serving the next recorded session.
+func (r *Replayer) Dial() (net.Conn, error) {
+	r.mu.Lock()
+	if r.next >= len(r.sessions) {
+		r.mu.Unlock()
+		return nil, ErrReplayExhausted
+	}
+	session := r.sessions[r.next]
+	r.next++
+	r.mu.Unlock()
+	if session.dialErr != "" {
+		return nil, errors.New(session.dialErr)
+	}
+	client, server := net.Pipe()
+	go r.serve(server, session)
+	return client, nil
+}
+
+// Err returns the first command that did not match the recording.
+func (r *Replayer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Replayer) serve(conn net.Conn, session *replaySession) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	var pending []string
+	for {
+		if !r.serveUnsolicited(writer, session) {
+			return
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		pending = append(pending, redact(strings.TrimSuffix(line, "\n")))
+		e, partial := r.match(session, pending)
+		switch {
+		case e != nil:
+			pending = nil
+			if !answer(writer, e.received) || e.eof {
+				return
+			}
+		case partial:
+		case len(pending) == 1 && (pending[0] == "ping" || pending[0] == "noidle"):
+			pending = nil
+			if !answer(writer, []string{"OK"}) {
+				return
+			}
+		case len(pending) == 1 && strings.HasPrefix(pending[0], "idle"):
+			// Nothing more was recorded, wait for noidle.
+			pending = nil
+		default:
+			r.fail(fmt.Errorf("%w: session %d: %q", ErrReplayMismatch, session.id, strings.Join(pending, "\n")))
+			return
+		}
+	}
+}
+
+// serveUnsolicited sends the answers recorded without a command, like the greeting.
+// It returns false if the connection must be closed.
+func (r *Replayer) serveUnsolicited(writer *bufio.Writer, session *replaySession) bool {
+	for {
+		r.mu.Lock()
+		var e *exchange
+		if len(session.exchanges) > 0 && len(session.exchanges[0].sent) == 0 {
+			e = session.exchanges[0]
+			session.exchanges = session.exchanges[1:]
+		}
+		r.mu.Unlock()
+		if e == nil {
+			return true
+		}
+		if !answer(writer, e.received) || e.eof {
+			return false
+		}
+	}
+}
+
+// match finds and removes the exchange recorded for the pending lines.
+// If there is none, partial reports whether the pending lines start a recorded command.
+func (r *Replayer) match(own *replaySession, pending []string) (e *exchange, partial bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := append([]*replaySession{own}, r.sessions...)
+	for _, session := range sessions {
+		i := session.head(pending)
+		if i < 0 {
+			continue
+		}
+		e := session.exchanges[i]
+		if len(e.sent) < len(pending) || !slices.Equal(e.sent[:len(pending)], pending) {
+			continue
+		}
+		if len(e.sent) > len(pending) {
+			partial = true
+			continue
+		}
+		session.exchanges = session.exchanges[i+1:]
+		return e, false
+	}
+	return nil, partial
+}
+
+func (r *Replayer) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func answer(writer *bufio.Writer, lines []string) bool {
+	for _, line := range lines {
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			return false
+		}
+	}
+	return writer.Flush() == nil
+}
@@ -3,6 +3,7 @@ package mpdrwpool
 import "errors"
 
 var (
-	ErrConnection     = errors.New("connection error")
-	ErrSendingCommand = errors.New("error sending command")
+	ErrConnection       = errors.New("connection error")
+	ErrSendingCommand   = errors.New("error sending command")
+	ErrUnknownSubsystem = errors.New("unknown idle subsystem")
 )
@@ -3,50 +3,84 @@ package mpdrwpool
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/anpotashev/go-observer/pkg/observer"
 	"github.com/anpotashev/mpdgo/internal/commands"
 	log "github.com/anpotashev/mpdgo/internal/logger"
 	"github.com/anpotashev/mpdgo/internal/mpdrw"
+	"github.com/anpotashev/mpdgo/pkg/metrics"
+	"github.com/anpotashev/mpdgo/pkg/tracing"
 )
 
 type Impl struct {
 	cancel context.CancelFunc
 	idleRW mpdrw.MpdRW
-	rws    chan mpdrw.MpdRW
+	// free holds the open connections not in use. Its capacity is sizing.MaxSize.
+	free chan *pooledRW
 	observer.Observer[[]string]
 	pingInterval time.Duration
 	ctx          context.Context
+	metrics      metrics.Metrics
+	mpdRWFactory mpdRWFactory
+	retryPolicy  RetryPolicy
+	sizing       Sizing
+	mu           sync.Mutex
+	// open is the number of open connections, free or in use, without the idle one.
+	open int
+	// conns are the open connections, free or in use, without the idle one.
+	conns map[*pooledRW]struct{}
+	// inUse is the number of conns running a command.
+	inUse int
+	// streams is the number of conns running a streamed command.
+	streams  int
+	waits    waitStats
+	lastPing time.Time
+	idle     idleStats
+	// idleSubsystems are the subsystems the idle connection waits for, all if empty. Guarded by mu.
+	idleSubsystems []string
 }
 
 type mpdRWFactory func() (mpdrw.MpdRW, error)
 
+// pooledRW is a connection of the pool.
+type pooledRW struct {
+	mpdrw.MpdRW
+	// rwStats are guarded by Impl.mu, created is not changed.
+	rwStats
+}
+
 // NewMpdRWPool creates a new MPD RW pool.
 //
 // The requestContext is used for logging.
 // poolSize specifies the number of active connections; the total includes
 // one additional connection for idle listening, so the actual count is poolSize + 1.
-// host and port specify the MPD server address.
+// WithSizing makes the pool open the connections when needed and close the unused ones instead.
+// dialer opens the connections to the MPD server.
 // password is used for authentication.
 // readTimeout defines the maximum time to read a line from the MPD server response.
+// pingInterval is the longest time a free connection stays without a command, it is pinged then.
 // onDisconnect is a callback invoked when the connection is disconnected.
+// A lost connection is replaced with a new one; the pool is disconnected only if that fails.
 //
 // Can return the following errors:
 // - ErrConnection
 func NewMpdRWPool(requestContext, ctx context.Context,
 	poolSize uint8,
-	host string,
-	port uint16,
+	dialer mpdrw.Dialer,
 	password string,
 	readTimeout, pingInterval time.Duration,
 	onDisconnect func(),
+	opts ...Option,
 ) (*Impl, error) {
-	dialer := mpdrw.NewDialer(host, port)
 	var mpdRWFactoryFunction mpdRWFactory = func() (mpdrw.MpdRW, error) {
 		return dialer.NewMpdRW(requestContext, ctx, password, readTimeout)
 	}
-	return newMpdRWPool(mpdRWFactoryFunction, requestContext, ctx, poolSize, pingInterval, onDisconnect)
+	return newMpdRWPool(mpdRWFactoryFunction, requestContext, ctx, poolSize, pingInterval, onDisconnect, opts...)
 }
 
 func newMpdRWPool(
@@ -55,100 +89,640 @@ func newMpdRWPool(
 	poolSize uint8,
 	pingInterval time.Duration,
 	onDisconnect func(),
+	opts ...Option,
 ) (*Impl, error) {
 	ctx, cancel := context.WithCancel(ctx)
+	result := &Impl{
+		cancel:       cancel,
+		Observer:     observer.New[[]string](),
+		pingInterval: pingInterval,
+		ctx:          ctx,
+		metrics:      metrics.Nop{},
+		mpdRWFactory: mpdRWFactoryFunction,
+		retryPolicy:  DefaultRetryPolicy,
+		sizing:       Sizing{MinSize: poolSize, MaxSize: poolSize},
+		conns:        make(map[*pooledRW]struct{}),
+	}
+	for _, opt := range opts {
+		opt(result)
+	}
+	if err := CheckSubsystems(result.idleSubsystems); err != nil {
+		cancel()
+		return nil, err
+	}
+	result.sizing = result.sizing.normalized()
+	result.free = make(chan *pooledRW, result.sizing.MaxSize)
 	idleRW, err := mpdRWFactoryFunction()
 	if err != nil {
 		log.ErrorContext(requestContext, "Error creating idleRW", "err", err)
 		cancel()
 		return nil, errors.Join(ErrConnection, err)
 	}
-	rws := make(chan mpdrw.MpdRW, poolSize)
-	for range poolSize {
-		rw, err := mpdRWFactoryFunction()
-		if err != nil {
-			cancel()
-			return nil, errors.Join(ErrConnection, err)
-		}
-		rws <- rw
-	}
-	result := &Impl{
-		cancel:       cancel,
-		idleRW:       idleRW,
-		rws:          rws,
-		Observer:     observer.New[[]string](),
-		pingInterval: pingInterval,
-		ctx:          ctx,
+	result.idleRW = idleRW
+	result.idle.connected = time.Now()
+	if err := result.fill(); err != nil {
+		cancel()
+		return nil, err
 	}
 	go func() {
 		<-ctx.Done()
 		onDisconnect()
 	}()
 	go result.startIdleWatching()
-	go result.startPeriodicPing()
+	go result.startMaintenance()
 	return result, nil
 }
 
 func (p *Impl) SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error) {
-	rw := <-p.rws
-	defer func() {
-		p.rws <- rw
-	}()
-	result, err := rw.SendSingleCommand(requestContext, command)
+	var result []string
+	err := p.send(requestContext, command, "single command", command.Idempotent, func(rw mpdrw.MpdRW) error {
+		var err error
+		result, err = rw.SendSingleCommand(requestContext, command)
+		return err
+	})
 	if err != nil {
-		if errors.Is(err, mpdrw.ErrIO) {
-			log.WarnContext(requestContext, "Received IO error (single command). Disconnecting.", "err", err)
-			p.cancel()
-		}
 		return nil, errors.Join(ErrSendingCommand, err)
 	}
 	return result, nil
 }
 
+// SendSingleCommandStream passes the lines of the answer to yield as they are read.
+//
+// The connection is held until the answer ends, while yield may send other commands.
+// So a stream runs on a connection of the pool only if another one is left to them,
+// otherwise on a connection of its own closed at the end of the stream.
+func (p *Impl) SendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error {
+	yielded := false
+	// Lines passed to yield can't be taken back, so the command is only retried if nothing was passed yet.
+	retryable := func() bool { return !yielded && command.Idempotent() }
+	do := func(rw mpdrw.MpdRW) error {
+		return rw.SendSingleCommandStream(requestContext, command, func(line string) bool {
+			yielded = true
+			return yield(line)
+		})
+	}
+	var err error
+	if p.reserveStream() {
+		err = p.send(requestContext, command, "single command stream", retryable, do)
+		p.unreserveStream()
+	} else {
+		err = p.sendDedicated(requestContext, command, do)
+	}
+	if err != nil {
+		return errors.Join(ErrSendingCommand, err)
+	}
+	return nil
+}
+
+// reserveStream counts a stream on a connection of the pool if one is left to the other commands.
+func (p *Impl) reserveStream() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.streams+1 >= int(p.sizing.MaxSize) {
+		return false
+	}
+	p.streams++
+	return true
+}
+
+func (p *Impl) unreserveStream() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streams--
+}
+
+// sendDedicated calls do with a new connection, which is not added to the pool and closed after.
+// It is not retried: the stream may have passed lines already and the connection is not reused.
+func (p *Impl) sendDedicated(requestContext context.Context, command commands.MpdCommand, do func(rw mpdrw.MpdRW) error) error {
+	_, span := tracing.Start(requestContext, "mpd.pool.acquire")
+	rw, err := p.mpdRWFactory()
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return errors.Join(ErrConnection, err)
+	}
+	span.End()
+	defer func() { _ = rw.Close() }()
+	start := time.Now()
+	err = do(rw)
+	p.commandDone(command, start, err)
+	return err
+}
+
 func (p *Impl) SendBatchCommand(requestContext context.Context, command commands.BatchCommand) error {
-	rw := <-p.rws
+	err := p.send(requestContext, command, "batch command", command.Idempotent, func(rw mpdrw.MpdRW) error {
+		return rw.SendBatchCommand(requestContext, command)
+	})
+	if err != nil {
+		return errors.Join(ErrSendingCommand, err)
+	}
+	return nil
+}
+
+// send calls do with a connection from the pool.
+//
+// After an IO error the connection is replaced with a new one and, if retryable returns true,
+// do is called again as allowed by the retry policy. The pool is only disconnected if
+// a new connection can't be opened. The connection of a cancelled request is replaced
+// in the background instead, so that the request can't disconnect the pool.
+func (p *Impl) send(requestContext context.Context, command commands.MpdCommand, kind string, retryable func() bool, do func(rw mpdrw.MpdRW) error) error {
+	rw, err := p.acquire(requestContext)
+	if err != nil {
+		return err
+	}
 	defer func() {
-		p.rws <- rw
+		if rw != nil {
+			p.release(rw)
+		}
 	}()
-	err := rw.SendBatchCommand(requestContext, command)
-	if err != nil {
-		if errors.Is(err, mpdrw.ErrIO) {
-			log.WarnContext(requestContext, "Received IO error (batch command). Disconnecting.", "err", err)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := do(rw)
+		p.commandDone(command, start, err)
+		p.mu.Lock()
+		rw.commandDone(err)
+		p.mu.Unlock()
+		if !errors.Is(err, mpdrw.ErrIO) || p.ctx.Err() != nil {
+			return err
+		}
+		if requestContext.Err() != nil {
+			log.DebugContext(requestContext, "Request cancelled ("+kind+"). Replacing the connection in the background.")
+			p.discard(rw)
+			rw = nil
+			return err
+		}
+		retry := requestContext.Err() == nil && attempt <= p.retryPolicy.MaxRetries && retryable()
+		var delay time.Duration
+		if retry {
+			log.WarnContext(requestContext, "Received IO error ("+kind+"). Retrying on a new connection.", "err", err, "attempt", attempt)
+			delay = p.retryPolicy.backoff(attempt)
+		} else {
+			log.WarnContext(requestContext, "Received IO error ("+kind+"). Replacing the connection.", "err", err)
+		}
+		newRW, replaceErr := p.replace(requestContext, rw.MpdRW, delay)
+		if replaceErr != nil {
+			log.WarnContext(requestContext, "Error replacing the connection. Disconnecting.", "err", replaceErr)
 			p.cancel()
+			return errors.Join(err, replaceErr)
+		}
+		rw = p.swap(rw, newRW)
+		if !retry {
+			return err
 		}
-		return errors.Join(ErrSendingCommand, err)
 	}
-	return nil
 }
 
+// discard closes the connection rw in use, whose answer was abandoned by a cancelled request.
+// The connections are opened again up to sizing.MinSize in the background. If that fails,
+// the pool is left as is: a connection is opened when needed and by the maintenance.
+func (p *Impl) discard(rw *pooledRW) {
+	p.mu.Lock()
+	p.setInUse(rw, false)
+	p.mu.Unlock()
+	p.closeRW(rw)
+	go func() {
+		if err := p.fill(); err != nil {
+			log.Warn("Error opening a new connection in place of a cancelled one.", "err", err)
+		}
+	}()
+}
+
+// replace closes the broken connection rw and opens a new one after delay.
+// The new connection is checked with a ping before it is used, and is reported as a reconnection.
+func (p *Impl) replace(requestContext context.Context, rw mpdrw.MpdRW, delay time.Duration) (mpdrw.MpdRW, error) {
+	_ = rw.Close()
+	select {
+	case <-time.After(delay):
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	}
+	_, span := tracing.Start(requestContext, "mpd.pool.redial")
+	defer span.End()
+	if requestContext.Err() != nil {
+		// The connection is replaced even if the request was cancelled during the backoff.
+		requestContext = context.WithoutCancel(requestContext)
+	}
+	rw, err := p.mpdRWFactory()
+	if err == nil {
+		_, err = rw.SendSingleCommand(requestContext, commands.NewSingleCommand(commands.PING))
+		if err != nil {
+			_ = rw.Close()
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, errors.Join(ErrConnection, err)
+	}
+	p.metrics.Reconnected()
+	return rw, nil
+}
+
+// idleRetryDelay is the delay before the idle command is sent again after an error answer.
+const idleRetryDelay = time.Second
+
 func (p *Impl) startIdleWatching() {
+	// rejected are the subsystems MPD answered the idle command with an error for,
+	// e.g. neighbor or mount on an older version. All of them are waited for instead.
+	var rejected []string
 	for {
-		result, err := p.idleRW.SendIdleCommand()
+		p.mu.Lock()
+		p.idle.waiting = true
+		idleRW, subsystems := p.idleRW, p.idleSubsystems
+		p.mu.Unlock()
+		fallback := len(subsystems) > 0 && slices.Equal(subsystems, rejected)
+		var result []string
+		var err error
+		if fallback {
+			result, err = idleRW.SendIdleCommand()
+			result = filterSubsystems(result, subsystems)
+		} else {
+			result, err = idleRW.SendIdleCommand(subsystems...)
+		}
+		p.idleDone(result, err)
+		if p.ctx.Err() != nil {
+			return
+		}
 		if err != nil {
-			if errors.Is(err, mpdrw.ErrIO) {
-				log.Warn("Received IO error. (idle) Disconnecting.", "err", err)
-			} else {
-				log.Warn("Received error. (idle) Disconnecting.", "err", err)
+			if !errors.Is(err, mpdrw.ErrIO) {
+				if errors.Is(err, mpdrw.ErrACK) && len(subsystems) > 0 && !fallback {
+					log.Warn("Subsystems rejected. (idle) Waiting for all of them.", "subsystems", subsystems, "err", err)
+					rejected = subsystems
+					continue
+				}
+				log.Warn("Received error. (idle)", "err", err)
+				select {
+				case <-time.After(idleRetryDelay):
+				case <-p.ctx.Done():
+					return
+				}
 				continue
 			}
-			p.cancel()
-			return
+			log.Warn("Received IO error. (idle) Replacing the connection.", "err", err)
+			idleRW, replaceErr := p.replace(p.ctx, idleRW, 0)
+			if replaceErr != nil {
+				log.Warn("Error replacing the connection. (idle) Disconnecting.", "err", replaceErr)
+				p.cancel()
+				return
+			}
+			p.mu.Lock()
+			p.idleRW = idleRW
+			p.idle.reconnects++
+			p.idle.connected = time.Now()
+			p.mu.Unlock()
+			// The changes while the connection was lost are unknown.
+			result = changedSubsystems(subsystems)
+		}
+		if len(result) == 0 {
+			// The idle command was interrupted with noidle.
+			continue
+		}
+		for _, line := range result {
+			if subsystem, ok := strings.CutPrefix(line, "changed: "); ok {
+				p.metrics.IdleEvent(subsystem)
+			}
 		}
 		p.Notify(result)
 	}
 }
 
-func (p *Impl) startPeriodicPing() {
-	tick := time.Tick(p.pingInterval)
+// SetIdleSubsystems replaces the subsystems the idle connection waits for, all of them if empty.
+// The in-progress idle command is interrupted with noidle and sent again with the subsystems.
+// If MPD rejects them, e.g. neighbor or mount on an older version, the idle command waits for
+// all subsystems and the changes of the other ones are dropped.
+//
+// Can return the following errors:
+// - ErrUnknownSubsystem
+// - ErrSendingCommand
+func (p *Impl) SetIdleSubsystems(subsystems ...string) error {
+	if err := CheckSubsystems(subsystems); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.idleSubsystems = slices.Clone(subsystems)
+	idleRW := p.idleRW
+	p.mu.Unlock()
+	err := idleRW.NoIdle()
+	if errors.Is(err, mpdrw.ErrIO) {
+		// The idle connection is replaced and waits for the new subsystems then.
+		log.Warn("Error sending noidle. (idle)", "err", err)
+		return nil
+	}
+	if err != nil {
+		return errors.Join(ErrSendingCommand, err)
+	}
+	return nil
+}
+
+// Subsystems are the idle subsystems of the MPD server.
+var Subsystems = []string{
+	"database",
+	"update",
+	"stored_playlist",
+	"playlist",
+	"player",
+	"mixer",
+	"output",
+	"options",
+	"partition",
+	"sticker",
+	"subscription",
+	"message",
+	"neighbor",
+	"mount",
+}
+
+// stateSubsystems are the subsystems with a state, which may have changed while the idle connection was lost.
+var stateSubsystems = []string{
+	"database",
+	"update",
+	"stored_playlist",
+	"playlist",
+	"player",
+	"mixer",
+	"output",
+	"options",
+	"partition",
+	"sticker",
+}
+
+// CheckSubsystems returns ErrUnknownSubsystem if any of subsystems is not one of Subsystems.
+func CheckSubsystems(subsystems []string) error {
+	for _, subsystem := range subsystems {
+		if !slices.Contains(Subsystems, subsystem) {
+			return fmt.Errorf("%w: %q", ErrUnknownSubsystem, subsystem)
+		}
+	}
+	return nil
+}
+
+// changedSubsystems is the answer to idle reporting a change of every subsystem with a state
+// among subsystems, or among all of them if subsystems is empty.
+func changedSubsystems(subsystems []string) []string {
+	var result []string
+	for _, subsystem := range stateSubsystems {
+		if len(subsystems) == 0 || slices.Contains(subsystems, subsystem) {
+			result = append(result, "changed: "+subsystem)
+		}
+	}
+	return result
+}
+
+// filterSubsystems keeps the changes of the subsystems of the answer to an idle command.
+func filterSubsystems(lines []string, subsystems []string) []string {
+	var result []string
+	for _, line := range lines {
+		if subsystem, ok := strings.CutPrefix(line, "changed: "); !ok || slices.Contains(subsystems, subsystem) {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// acquire takes a free connection from the pool. If there is none, a new one is opened
+// unless sizing.MaxSize are open already, otherwise it waits until one is released.
+func (p *Impl) acquire(requestContext context.Context) (*pooledRW, error) {
+	_, span := tracing.Start(requestContext, "mpd.pool.acquire")
+	defer span.End()
+	start := time.Now()
+	rw, err := p.take(requestContext)
+	wait := time.Since(start)
+	p.metrics.PoolWait(wait)
+	p.mu.Lock()
+	p.waits.add(wait)
+	if err == nil {
+		p.setInUse(rw, true)
+	}
+	p.mu.Unlock()
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return rw, nil
+}
+
+// setInUse marks rw as running a command or not. p.mu must be held, so the changes
+// of the count are reported in order.
+func (p *Impl) setInUse(rw *pooledRW, inUse bool) {
+	if rw.inUse == inUse {
+		return
+	}
+	rw.inUse = inUse
+	if inUse {
+		p.inUse++
+	} else {
+		p.inUse--
+	}
+	p.metrics.ConnectionsInUse(p.inUse)
+}
+
+func (p *Impl) take(requestContext context.Context) (*pooledRW, error) {
+	for {
+		rw, err := p.next(requestContext)
+		if err != nil {
+			return nil, err
+		}
+		if !p.expired(rw, time.Now()) {
+			return rw, nil
+		}
+		p.closeRW(rw)
+	}
+}
+
+// next returns a free connection, a new one or the first one released.
+func (p *Impl) next(requestContext context.Context) (*pooledRW, error) {
+	select {
+	case rw := <-p.free:
+		return rw, nil
+	default:
+	}
+	if p.reserve(p.sizing.MaxSize) {
+		rw, err := p.mpdRWFactory()
+		if err == nil {
+			return p.track(rw), nil
+		}
+		if p.unreserve() == 0 {
+			return nil, errors.Join(ErrConnection, err)
+		}
+		// E.g. the max_connections of the MPD server are reached.
+		log.WarnContext(requestContext, "Error opening a new connection. Waiting for a free one.", "err", err)
+	}
+	select {
+	case rw := <-p.free:
+		return rw, nil
+	case <-p.ctx.Done():
+		return nil, errors.Join(ErrConnection, p.ctx.Err())
+	case <-requestContext.Done():
+		return nil, errors.Join(ErrConnection, requestContext.Err())
+	}
+}
+
+func (p *Impl) release(rw *pooledRW) {
+	now := time.Now()
+	p.mu.Lock()
+	p.setInUse(rw, false)
+	rw.lastUsed = now
+	p.mu.Unlock()
+	if p.expired(rw, now) {
+		p.closeRW(rw)
+		return
+	}
+	p.free <- rw
+}
+
+// reserve counts a connection about to be opened if less than limit are open.
+func (p *Impl) reserve(limit uint8) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.open >= int(limit) {
+		return false
+	}
+	p.open++
+	return true
+}
+
+// unreserve uncounts a connection that was closed or failed to open. It returns the number of the open ones.
+func (p *Impl) unreserve() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.open--
+	return p.open
+}
+
+// track adds a connection counted by reserve to the pool.
+func (p *Impl) track(rw mpdrw.MpdRW) *pooledRW {
+	now := time.Now()
+	result := &pooledRW{MpdRW: rw, rwStats: rwStats{created: now, lastUsed: now, lastActive: now}}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns[result] = struct{}{}
+	return result
+}
+
+// swap replaces the connection old in use with rw.
+func (p *Impl) swap(old *pooledRW, rw mpdrw.MpdRW) *pooledRW {
+	result := p.track(rw)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.conns, old)
+	// The count in use is unchanged, rw runs the command of old.
+	result.inUse = true
+	return result
+}
+
+func (p *Impl) closeRW(rw *pooledRW) {
+	_ = rw.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.open--
+	delete(p.conns, rw)
+}
+
+// expired reports whether rw is older than the max lifetime.
+func (p *Impl) expired(rw *pooledRW, now time.Time) bool {
+	return p.sizing.MaxLifetime > 0 && now.Sub(rw.created) >= p.sizing.MaxLifetime
+}
+
+// fill opens connections until sizing.MinSize are open.
+func (p *Impl) fill() error {
+	for p.reserve(p.sizing.MinSize) {
+		rw, err := p.mpdRWFactory()
+		if err != nil {
+			p.unreserve()
+			return errors.Join(ErrConnection, err)
+		}
+		p.free <- p.track(rw)
+	}
+	return nil
+}
+
+func (p *Impl) commandDone(command commands.MpdCommand, start time.Time, err error) {
+	result := metrics.ResultOK
+	switch {
+	case errors.Is(err, mpdrw.ErrACK):
+		result = metrics.ResultACK
+	case err != nil:
+		result = metrics.ResultError
+	}
+	p.metrics.CommandDone(command.Name(), result, time.Since(start))
+}
+
+// keepaliveChecks is the number of times the free connections are checked per ping interval.
+const keepaliveChecks = 4
+
+// startMaintenance checks the free connections several times per ping interval. It pings the ones
+// without any command sent for the ping interval, so that the MPD server does not close them
+// on its connection_timeout, and closes the broken ones, the ones older than the max lifetime
+// and the ones above sizing.MinSize unused for the idle timeout.
+// The pool is disconnected if sizing.MinSize connections can't be kept open.
+func (p *Impl) startMaintenance() {
+	tick := time.Tick(p.pingInterval / keepaliveChecks)
 	for {
 		select {
 		case <-tick:
-			for i := 0; i < cap(p.rws); i++ {
-				//lint:ignore SA1012 ignore
-				p.SendSingleCommand(nil, commands.NewSingleCommand(commands.PING))
+			p.maintain()
+			if err := p.fill(); err != nil {
+				log.Warn("Error opening a new connection. Disconnecting.", "err", err)
+				p.cancel()
+				return
 			}
 		case <-p.ctx.Done():
 			return
 		}
 	}
 }
+
+// maintain checks the connections free at the beginning. They are taken one at a time,
+// so that the commands sent meanwhile can use the other ones.
+func (p *Impl) maintain() {
+	ping := commands.NewSingleCommand(commands.PING)
+	for range len(p.free) {
+		var rw *pooledRW
+		select {
+		case rw = <-p.free:
+		default:
+			return
+		}
+		now := time.Now()
+		switch {
+		case p.expired(rw, now):
+			log.Debug("Closing the connection reaching the max lifetime")
+			p.closeRW(rw)
+			continue
+		case p.sizing.IdleTimeout > 0 && now.Sub(rw.lastUsed) >= p.sizing.IdleTimeout && p.reap(rw):
+			log.Debug("Closing the unused connection")
+			_ = rw.Close()
+			continue
+		case now.Sub(rw.lastActive) < p.pingInterval:
+			p.free <- rw
+			continue
+		}
+		start := time.Now()
+		//lint:ignore SA1012 ignore
+		_, err := rw.SendSingleCommand(nil, ping)
+		p.commandDone(ping, start, err)
+		p.mu.Lock()
+		rw.commandDone(err)
+		if err == nil {
+			p.lastPing = time.Now()
+		}
+		p.mu.Unlock()
+		if errors.Is(err, mpdrw.ErrIO) {
+			log.Warn("Received IO error (ping). Closing the connection.", "err", err)
+			p.closeRW(rw)
+			continue
+		}
+		p.free <- rw
+	}
+}
+
+// reap removes the connection rw about to be closed if more than sizing.MinSize are open.
+func (p *Impl) reap(rw *pooledRW) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.open <= int(p.sizing.MinSize) {
+		return false
+	}
+	p.open--
+	delete(p.conns, rw)
+	return true
+}
@@ -2,11 +2,15 @@ package mpdrwpool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/anpotashev/mpdgo/internal/commands"
 	"github.com/anpotashev/mpdgo/internal/mpdrw"
+	"github.com/anpotashev/mpdgo/pkg/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -54,7 +58,7 @@ func TestNewMpdRWPool(t *testing.T) {
 		}
 		pool.cancel()
 		select {
-		case <-time.NewTimer(time.Microsecond * 100).C:
+		case <-time.NewTimer(time.Millisecond * 100).C:
 			t.Error("onDisconnect was not called")
 		case <-onDisconnectCalled:
 		}
@@ -102,6 +106,10 @@ func TestImpl_VerifyPingGoroutine(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -148,6 +156,10 @@ func TestImpl_VerifyPingGoroutine(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -168,7 +180,7 @@ func TestImpl_VerifyPingGoroutine(t *testing.T) {
 		// Verifying that onDisconnect was called.
 		select {
 		case <-onDisconnectCalled:
-		case <-time.NewTimer(time.Microsecond * 100).C:
+		case <-time.NewTimer(time.Millisecond * 100).C:
 			t.Error("onDisconnect was not called")
 		}
 	})
@@ -190,6 +202,10 @@ func TestImpl_SendSingleCommand(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -226,6 +242,10 @@ func TestImpl_SendSingleCommand(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -267,6 +287,10 @@ func TestImpl_SendSingleCommand(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -287,7 +311,99 @@ func TestImpl_SendSingleCommand(t *testing.T) {
 		// Verifying that onDisconnect was called.
 		select {
 		case <-onDisconnectCalled:
-		case <-time.NewTimer(time.Microsecond * 100).C:
+		case <-time.NewTimer(time.Millisecond * 100).C:
+			t.Error("onDisconnect was not called")
+		}
+	})
+}
+
+func TestImpl_SendSingleCommandStream(t *testing.T) {
+	t.Run("streaming command (no error)", func(t *testing.T) {
+		// Creating an mpdRW slice
+		rws := make([]*mockMpdRW, defaultConnectParams.poolSize+1)
+		for i := range rws {
+			rws[i] = &mockMpdRW{}
+		}
+		idleChan := make(chan struct{})
+		// The first element of the slice is idleRw. Mocking its behavior.
+		rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+			<-idleChan
+		}).Return([]string{}, nil)
+		// Creating a mpdRWFactoryFunction
+		mpdRWCounter := -1
+		f := func() (mpdrw.MpdRW, error) {
+			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
+			return rws[mpdRWCounter], nil
+		}
+		onDisconnect := func() {}
+		// Creating an mpdRWPool
+		pool, err := newMpdRWPool(f, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, onDisconnect)
+		assert.Nil(t, err)
+		assert.NotNil(t, pool)
+		// Mocking responses for LISTALLINFO command
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		response := []string{"aaaa", "bbbb"}
+		for _, rw := range rws[1:] {
+			rw.On("SendSingleCommandStream", defaultConnectParams.requestContext, cmd).
+				Return(response, nil)
+		}
+		var lines []string
+		err = pool.SendSingleCommandStream(defaultConnectParams.requestContext, cmd, func(line string) bool {
+			lines = append(lines, line)
+			return true
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, response, lines)
+		// Verifying that the connection was returned to the pool
+		assert.Equal(t, int(defaultConnectParams.poolSize), len(pool.free))
+
+		pool.cancel()
+	})
+	t.Run("streaming command (IO error)", func(t *testing.T) {
+		// Creating an mpdRW slice
+		rws := make([]*mockMpdRW, defaultConnectParams.poolSize+1)
+		for i := range rws {
+			rws[i] = &mockMpdRW{}
+		}
+		idleChan := make(chan struct{})
+		// The first element of the slice is idleRw. Mocking its behavior.
+		rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+			<-idleChan
+		}).Return([]string{}, nil)
+		// Creating a mpdRWFactoryFunction
+		mpdRWCounter := -1
+		f := func() (mpdrw.MpdRW, error) {
+			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
+			return rws[mpdRWCounter], nil
+		}
+		onDisconnectCalled := make(chan struct{})
+		onDisconnect := func() { onDisconnectCalled <- struct{}{} }
+		// Creating an mpdRWPool
+		pool, err := newMpdRWPool(f, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, onDisconnect)
+		assert.Nil(t, err)
+		assert.NotNil(t, pool)
+		// Mocking responses for LISTALLINFO command
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		for _, rw := range rws[1:] {
+			rw.On("SendSingleCommandStream", defaultConnectParams.requestContext, cmd).
+				Return([]string{"aaaa"}, mpdrw.ErrIO)
+		}
+		err = pool.SendSingleCommandStream(defaultConnectParams.requestContext, cmd, func(line string) bool {
+			return true
+		})
+		assert.ErrorIs(t, err, ErrSendingCommand)
+		// Verifying that onDisconnect was called.
+		select {
+		case <-onDisconnectCalled:
+		case <-time.NewTimer(time.Millisecond * 100).C:
 			t.Error("onDisconnect was not called")
 		}
 	})
@@ -309,6 +425,10 @@ func TestImpl_SendBatchCommand(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -348,6 +468,10 @@ func TestImpl_SendBatchCommand(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -394,6 +518,10 @@ func TestImpl_SendBatchCommand(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -419,7 +547,7 @@ func TestImpl_SendBatchCommand(t *testing.T) {
 		// Verifying that onDisconnect was not called.
 		select {
 		case <-onDisconnectCalled:
-		case <-time.NewTimer(time.Microsecond * 100).C:
+		case <-time.NewTimer(time.Millisecond * 100).C:
 			t.Error("onDisconnect was not called")
 		}
 	})
@@ -442,6 +570,10 @@ func TestImpl_IdleListener(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnect := func() {}
@@ -478,6 +610,10 @@ func TestImpl_IdleListener(t *testing.T) {
 		mpdRWCounter := -1
 		f := func() (mpdrw.MpdRW, error) {
 			mpdRWCounter++
+			if mpdRWCounter >= len(rws) {
+				// The server is unreachable after the pool was created.
+				return nil, mpdrw.ErrIO
+			}
 			return rws[mpdRWCounter], nil
 		}
 		onDisconnectCalled := make(chan struct{})
@@ -491,8 +627,627 @@ func TestImpl_IdleListener(t *testing.T) {
 		// Verifying that onDisconnect was called.
 		select {
 		case <-onDisconnectCalled:
-		case <-time.NewTimer(time.Microsecond * 100).C:
+		case <-time.NewTimer(time.Millisecond * 100).C:
 			t.Error("onDisconnect was not called")
 		}
 	})
 }
+
+// recordingMetrics collects the calls of the metrics hooks.
+type recordingMetrics struct {
+	mu         sync.Mutex
+	commands   []string
+	poolWaits  int
+	inUse      []int
+	reconnects int
+	idleEvents []string
+}
+
+func (m *recordingMetrics) CommandDone(command string, result metrics.Result, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands = append(m.commands, command+" "+string(result))
+}
+
+func (m *recordingMetrics) PoolWait(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.poolWaits++
+}
+
+func (m *recordingMetrics) ConnectionsInUse(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inUse = append(m.inUse, count)
+}
+
+func (m *recordingMetrics) Reconnected() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+func (m *recordingMetrics) IdleEvent(subsystem string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.idleEvents = append(m.idleEvents, subsystem)
+}
+
+func TestImpl_Metrics(t *testing.T) {
+	rws := make([]*mockMpdRW, defaultConnectParams.poolSize+1)
+	for i := range rws {
+		rws[i] = &mockMpdRW{}
+	}
+	idleChan := make(chan struct{})
+	rws[0].On("SendIdleCommand").Return([]string{"changed: player", "changed: mixer"}, nil).Once()
+	rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+		<-idleChan
+	}).Return([]string{}, nil)
+	mpdRWCounter := -1
+	f := func() (mpdrw.MpdRW, error) {
+		mpdRWCounter++
+		return rws[mpdRWCounter], nil
+	}
+	status := commands.NewSingleCommand(commands.STATUS)
+	play := commands.NewSingleCommand(commands.PLAY)
+	for _, rw := range rws[1:] {
+		rw.On("SendSingleCommand", defaultConnectParams.requestContext, status).Return([]string{}, nil)
+		rw.On("SendSingleCommand", defaultConnectParams.requestContext, play).Return(nil, mpdrw.ErrACK)
+	}
+	collector := &recordingMetrics{}
+	pool, err := newMpdRWPool(f, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() {}, WithMetrics(collector))
+	assert.Nil(t, err)
+	defer pool.cancel()
+
+	_, err = pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+	assert.Nil(t, err)
+	_, err = pool.SendSingleCommand(defaultConnectParams.requestContext, play)
+	assert.ErrorIs(t, err, mpdrw.ErrACK)
+	assert.Eventually(t, func() bool {
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		return len(collector.idleEvents) == 2
+	}, time.Second, time.Millisecond)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	assert.Equal(t, []string{"status ok", "play ack"}, collector.commands)
+	assert.Equal(t, 2, collector.poolWaits)
+	assert.Equal(t, []int{1, 0, 1, 0}, collector.inUse)
+	assert.Equal(t, []string{"player", "mixer"}, collector.idleEvents)
+}
+
+// newFailingPool creates a pool whose connections fail sending cmd with ErrIO. The connections opened
+// after the pool was created are the elements of redialed, nil makes the dial fail.
+// The redialed connections answer the health check ping, unless mocked otherwise before.
+func newFailingPool(t *testing.T, cmd commands.SingleCommand, redialed ...*mockMpdRW) (*Impl, []*mockMpdRW, chan struct{}) {
+	return newFailingPoolWith(t, cmd, nil, redialed...)
+}
+
+// newFailingPoolWith is newFailingPool with the options opts.
+func newFailingPoolWith(t *testing.T, cmd commands.SingleCommand, opts []Option, redialed ...*mockMpdRW) (*Impl, []*mockMpdRW, chan struct{}) {
+	rws := make([]*mockMpdRW, defaultConnectParams.poolSize+1)
+	for i := range rws {
+		rws[i] = &mockMpdRW{}
+	}
+	idleChan := make(chan struct{})
+	rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+		<-idleChan
+	}).Return([]string{}, nil)
+	for _, rw := range rws[1:] {
+		rw.On("SendSingleCommand", defaultConnectParams.requestContext, cmd).Return(nil, mpdrw.ErrIO)
+		rw.On("SendSingleCommandStream", defaultConnectParams.requestContext, cmd).Return(nil, mpdrw.ErrIO)
+	}
+	for _, rw := range redialed {
+		if rw != nil {
+			rw.On("SendSingleCommand", defaultConnectParams.requestContext, commands.NewSingleCommand(commands.PING)).Return([]string{}, nil)
+		}
+	}
+	all := append(rws, redialed...)
+	mpdRWCounter := -1
+	f := func() (mpdrw.MpdRW, error) {
+		mpdRWCounter++
+		if mpdRWCounter >= len(all) || all[mpdRWCounter] == nil {
+			return nil, mpdrw.ErrIO
+		}
+		return all[mpdRWCounter], nil
+	}
+	onDisconnectCalled := make(chan struct{}, 1)
+	onDisconnect := func() { onDisconnectCalled <- struct{}{} }
+	pool, err := newMpdRWPool(f, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, onDisconnect,
+		append([]Option{WithRetryPolicy(RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})}, opts...)...)
+	assert.Nil(t, err)
+	return pool, rws, onDisconnectCalled
+}
+
+func assertDisconnected(t *testing.T, onDisconnectCalled chan struct{}, expected bool) {
+	wait := time.Millisecond * 10
+	if expected {
+		wait = time.Second
+	}
+	select {
+	case <-onDisconnectCalled:
+		if !expected {
+			t.Error("onDisconnect was called")
+		}
+	case <-time.NewTimer(wait).C:
+		if expected {
+			t.Error("onDisconnect was not called")
+		}
+	}
+}
+
+// assertInPool checks that the pool holds rw and not replaced.
+func assertInPool(t *testing.T, pool *Impl, rw, replaced *mockMpdRW) {
+	found := false
+	for range defaultConnectParams.poolSize {
+		pooled, err := pool.acquire(defaultConnectParams.requestContext)
+		assert.Nil(t, err)
+		defer pool.release(pooled)
+		found = found || pooled.MpdRW == mpdrw.MpdRW(rw)
+		assert.NotSame(t, replaced, pooled.MpdRW)
+	}
+	assert.True(t, found)
+}
+
+func TestImpl_Retry(t *testing.T) {
+	t.Run("idempotent command is retried on a new connection", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.STATUS)
+		failing, working := &mockMpdRW{}, &mockMpdRW{}
+		failing.On("SendSingleCommand", defaultConnectParams.requestContext, cmd).Return(nil, mpdrw.ErrIO)
+		working.On("SendSingleCommand", defaultConnectParams.requestContext, cmd).Return([]string{"state: play"}, nil)
+		pool, rws, onDisconnectCalled := newFailingPool(t, cmd, failing, working)
+		defer pool.cancel()
+
+		actual, err := pool.SendSingleCommand(defaultConnectParams.requestContext, cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"state: play"}, actual)
+		assert.True(t, rws[1].closed.Load())
+		assert.True(t, failing.closed.Load())
+		assert.False(t, working.closed.Load())
+		assertDisconnected(t, onDisconnectCalled, false)
+		assertInPool(t, pool, working, rws[1])
+	})
+	t.Run("mutation is not retried", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.CLEAR)
+		replacement := &mockMpdRW{}
+		pool, rws, onDisconnectCalled := newFailingPool(t, cmd, replacement)
+		defer pool.cancel()
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, cmd)
+		assert.ErrorIs(t, err, mpdrw.ErrIO)
+		rws[1].AssertNumberOfCalls(t, "SendSingleCommand", 1)
+		replacement.AssertNotCalled(t, "SendSingleCommand", defaultConnectParams.requestContext, cmd)
+		assertDisconnected(t, onDisconnectCalled, false)
+		assertInPool(t, pool, replacement, rws[1])
+	})
+	t.Run("retries are limited", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.STATUS)
+		first, second, replacement := &mockMpdRW{}, &mockMpdRW{}, &mockMpdRW{}
+		for _, rw := range []*mockMpdRW{first, second} {
+			rw.On("SendSingleCommand", defaultConnectParams.requestContext, cmd).Return(nil, mpdrw.ErrIO)
+		}
+		collector := &recordingMetrics{}
+		pool, _, onDisconnectCalled := newFailingPoolWith(t, cmd, []Option{WithMetrics(collector)}, first, second, replacement)
+		defer pool.cancel()
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, cmd)
+		assert.ErrorIs(t, err, mpdrw.ErrIO)
+		second.AssertNumberOfCalls(t, "SendSingleCommand", 2)
+		collector.mu.Lock()
+		assert.Equal(t, 3, collector.reconnects)
+		collector.mu.Unlock()
+		assertDisconnected(t, onDisconnectCalled, false)
+		assertInPool(t, pool, replacement, second)
+	})
+	t.Run("stream is retried before the first line only", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		working, replacement := &mockMpdRW{}, &mockMpdRW{}
+		working.On("SendSingleCommandStream", defaultConnectParams.requestContext, cmd).Return([]string{"file: a"}, mpdrw.ErrIO)
+		pool, _, onDisconnectCalled := newFailingPool(t, cmd, working, replacement)
+		defer pool.cancel()
+
+		var lines []string
+		err := pool.SendSingleCommandStream(defaultConnectParams.requestContext, cmd, func(line string) bool {
+			lines = append(lines, line)
+			return true
+		})
+		assert.ErrorIs(t, err, mpdrw.ErrIO)
+		assert.Equal(t, []string{"file: a"}, lines)
+		assertDisconnected(t, onDisconnectCalled, false)
+		assertInPool(t, pool, replacement, working)
+	})
+}
+
+func TestImpl_ReplaceConnection(t *testing.T) {
+	t.Run("failed dial disconnects", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.STATUS)
+		pool, _, onDisconnectCalled := newFailingPool(t, cmd, nil)
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, cmd)
+		assert.ErrorIs(t, err, ErrConnection)
+		assertDisconnected(t, onDisconnectCalled, true)
+	})
+	t.Run("failed health check disconnects", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.CLEAR)
+		unhealthy := &mockMpdRW{}
+		unhealthy.On("SendSingleCommand", defaultConnectParams.requestContext, commands.NewSingleCommand(commands.PING)).Return(nil, mpdrw.ErrIO)
+		pool, _, onDisconnectCalled := newFailingPool(t, cmd, unhealthy)
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, cmd)
+		assert.ErrorIs(t, err, ErrConnection)
+		assert.True(t, unhealthy.closed.Load())
+		assertDisconnected(t, onDisconnectCalled, true)
+	})
+	t.Run("connection of a cancelled request is replaced in the background", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.STATUS)
+		replacement := &mockMpdRW{}
+		pool, rws, onDisconnectCalled := newFailingPool(t, cmd, replacement)
+		defer pool.cancel()
+		requestContext, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, rw := range rws[1:] {
+			rw.On("SendSingleCommand", requestContext, cmd).Return(nil, errors.Join(mpdrw.ErrIO, context.Canceled))
+		}
+
+		_, err := pool.SendSingleCommand(requestContext, cmd)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Eventually(t, func() bool { return pool.Stats().Open == int(defaultConnectParams.poolSize) }, time.Second, time.Millisecond)
+		assert.Equal(t, 0, pool.Stats().InUse)
+		replacement.AssertNotCalled(t, "SendSingleCommand", mock.Anything, mock.Anything)
+		assertDisconnected(t, onDisconnectCalled, false)
+	})
+	t.Run("failed redial for a cancelled request does not disconnect", func(t *testing.T) {
+		cmd := commands.NewSingleCommand(commands.STATUS)
+		pool, rws, onDisconnectCalled := newFailingPool(t, cmd, nil)
+		defer pool.cancel()
+		requestContext, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, rw := range rws[1:] {
+			rw.On("SendSingleCommand", requestContext, cmd).Return(nil, errors.Join(mpdrw.ErrIO, context.Canceled))
+		}
+
+		_, err := pool.SendSingleCommand(requestContext, cmd)
+		assert.ErrorIs(t, err, context.Canceled)
+		assertDisconnected(t, onDisconnectCalled, false)
+		assert.Equal(t, int(defaultConnectParams.poolSize)-1, pool.Stats().Open)
+	})
+	t.Run("broken idle connection is replaced", func(t *testing.T) {
+		rws := make([]*mockMpdRW, defaultConnectParams.poolSize+2)
+		for i := range rws {
+			rws[i] = &mockMpdRW{}
+		}
+		broken := make(chan struct{})
+		rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+			<-broken
+		}).Return(nil, mpdrw.ErrIO)
+		idleChan := make(chan struct{})
+		replacement := rws[len(rws)-1]
+		replacement.On("SendSingleCommand", mock.Anything, commands.NewSingleCommand(commands.PING)).Return([]string{}, nil)
+		replacement.On("SendIdleCommand").Run(func(args mock.Arguments) {
+			<-idleChan
+		}).Return([]string{}, nil)
+		mpdRWCounter := -1
+		f := func() (mpdrw.MpdRW, error) {
+			mpdRWCounter++
+			return rws[mpdRWCounter], nil
+		}
+		onDisconnectCalled := make(chan struct{}, 1)
+		pool, err := newMpdRWPool(f, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() { onDisconnectCalled <- struct{}{} })
+		assert.Nil(t, err)
+		defer pool.cancel()
+		events := pool.Subscribe(time.Second)
+		defer pool.Unsubscribe(events)
+		close(broken)
+
+		select {
+		case event := <-events:
+			assert.Equal(t, changedSubsystems(nil), event)
+		case <-time.After(time.Second):
+			t.Error("no event after replacing the idle connection")
+		}
+		assert.True(t, rws[0].closed.Load())
+		assertDisconnected(t, onDisconnectCalled, false)
+	})
+}
+
+// dialedRWs is a factory of connections answering status and ping, recording the opened ones.
+type dialedRWs struct {
+	mu  sync.Mutex
+	rws []*mockMpdRW
+	// fail makes the dials fail, except the first one for the idle connection.
+	fail atomic.Bool
+	// block makes status wait for the channel to be closed.
+	block chan struct{}
+	// blockPing makes ping wait for the channel to be closed.
+	blockPing chan struct{}
+	pings     atomic.Int32
+}
+
+func (d *dialedRWs) dial() (mpdrw.MpdRW, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.rws) > 0 && d.fail.Load() {
+		return nil, mpdrw.ErrIO
+	}
+	rw := &mockMpdRW{}
+	if len(d.rws) == 0 {
+		rw.On("SendIdleCommand").Run(func(args mock.Arguments) {
+			select {}
+		}).Return([]string{}, nil)
+	}
+	rw.On("SendSingleCommand", mock.Anything, commands.NewSingleCommand(commands.PING)).Run(func(args mock.Arguments) {
+		d.pings.Add(1)
+		if d.blockPing != nil {
+			<-d.blockPing
+		}
+	}).Return([]string{}, nil)
+	rw.On("SendSingleCommand", mock.Anything, commands.NewSingleCommand(commands.STATUS)).Run(func(args mock.Arguments) {
+		if d.block != nil {
+			<-d.block
+		}
+	}).Return([]string{}, nil)
+	d.rws = append(d.rws, rw)
+	return rw, nil
+}
+
+func (d *dialedRWs) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.rws)
+}
+
+// closed returns the number of the closed connections.
+func (d *dialedRWs) closed() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := 0
+	for _, rw := range d.rws {
+		if rw.closed.Load() {
+			result++
+		}
+	}
+	return result
+}
+
+func TestImpl_Sizing(t *testing.T) {
+	status := commands.NewSingleCommand(commands.STATUS)
+	newPool := func(t *testing.T, dialed *dialedRWs, pingInterval time.Duration, sizing Sizing) *Impl {
+		pool, err := newMpdRWPool(dialed.dial, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, pingInterval, func() {}, WithSizing(sizing))
+		assert.Nil(t, err)
+		t.Cleanup(pool.cancel)
+		return pool
+	}
+	// sendConcurrently sends n status commands at once, returning after all of them are sent.
+	sendConcurrently := func(t *testing.T, pool *Impl, dialed *dialedRWs, n int, wait func()) {
+		dialed.block = make(chan struct{})
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+				assert.Nil(t, err)
+			}()
+		}
+		wait()
+		close(dialed.block)
+		wg.Wait()
+	}
+
+	t.Run("connections are opened when needed up to the max size", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		pool := newPool(t, dialed, time.Hour, Sizing{MinSize: 0, MaxSize: 2})
+		assert.Equal(t, 1, dialed.count())
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, dialed.count())
+
+		sendConcurrently(t, pool, dialed, 3, func() {
+			assert.Eventually(t, func() bool { return pool.Stats().InUse == 2 }, time.Second, time.Millisecond)
+		})
+		assert.Equal(t, 3, dialed.count())
+		assert.Equal(t, 0, dialed.closed())
+	})
+	t.Run("unused connections are closed down to the min size", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		pool := newPool(t, dialed, 10*time.Millisecond, Sizing{MinSize: 1, MaxSize: 3, IdleTimeout: 20 * time.Millisecond})
+		assert.Equal(t, 2, dialed.count())
+
+		sendConcurrently(t, pool, dialed, 3, func() {
+			assert.Eventually(t, func() bool { return pool.Stats().InUse == 3 }, time.Second, time.Millisecond)
+		})
+		assert.Equal(t, 4, dialed.count())
+		assert.Eventually(t, func() bool { return dialed.closed() == 2 }, time.Second, time.Millisecond)
+		assert.Never(t, func() bool { return dialed.closed() > 2 }, 50*time.Millisecond, time.Millisecond)
+	})
+	t.Run("connections are replaced after the max lifetime", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		pool := newPool(t, dialed, time.Hour, Sizing{MinSize: 1, MaxSize: 1, MaxLifetime: 20 * time.Millisecond})
+		time.Sleep(30 * time.Millisecond)
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, dialed.count())
+		assert.Equal(t, 1, dialed.closed())
+	})
+	t.Run("command waits for a free connection if a new one can't be opened", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		pool := newPool(t, dialed, time.Hour, Sizing{MinSize: 1, MaxSize: 3})
+		dialed.fail.Store(true)
+
+		sendConcurrently(t, pool, dialed, 2, func() {
+			assert.Eventually(t, func() bool { return pool.Stats().InUse == 1 }, time.Second, time.Millisecond)
+			time.Sleep(10 * time.Millisecond)
+		})
+		assert.Equal(t, 2, dialed.count())
+	})
+	t.Run("command fails if no connection can be opened", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		pool := newPool(t, dialed, time.Hour, Sizing{MinSize: 0, MaxSize: 3})
+		dialed.fail.Store(true)
+
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+		assert.ErrorIs(t, err, ErrConnection)
+	})
+}
+
+func TestImpl_Keepalive(t *testing.T) {
+	status := commands.NewSingleCommand(commands.STATUS)
+	ping := commands.NewSingleCommand(commands.PING)
+	newPool := func(t *testing.T, dialed *dialedRWs, pingInterval time.Duration, size uint8) *Impl {
+		pool, err := newMpdRWPool(dialed.dial, defaultConnectParams.requestContext, defaultConnectParams.ctx, size, pingInterval, func() {})
+		assert.Nil(t, err)
+		t.Cleanup(pool.cancel)
+		return pool
+	}
+
+	t.Run("every free connection is pinged once per interval", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		newPool(t, dialed, 100*time.Millisecond, 3)
+		time.Sleep(160 * time.Millisecond)
+		dialed.mu.Lock()
+		defer dialed.mu.Unlock()
+		for _, rw := range dialed.rws[1:] {
+			rw.AssertNumberOfCalls(t, "SendSingleCommand", 1)
+			rw.AssertCalled(t, "SendSingleCommand", mock.Anything, ping)
+		}
+	})
+	t.Run("recently used connection is not pinged", func(t *testing.T) {
+		dialed := &dialedRWs{}
+		pool := newPool(t, dialed, 200*time.Millisecond, 1)
+		time.Sleep(120 * time.Millisecond)
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+		assert.Nil(t, err)
+		time.Sleep(140 * time.Millisecond)
+		assert.Equal(t, int32(0), dialed.pings.Load())
+		assert.Eventually(t, func() bool { return dialed.pings.Load() == 1 }, time.Second, time.Millisecond)
+	})
+	t.Run("pinging does not block commands", func(t *testing.T) {
+		dialed := &dialedRWs{blockPing: make(chan struct{})}
+		pool := newPool(t, dialed, 20*time.Millisecond, 2)
+		defer close(dialed.blockPing)
+		assert.Eventually(t, func() bool { return dialed.pings.Load() == 1 }, time.Second, time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+			assert.Nil(t, err)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("command blocked by the ping")
+		}
+		assert.Equal(t, int32(1), dialed.pings.Load())
+	})
+}
+
+func TestImpl_IdleSubsystems(t *testing.T) {
+	newRWs := func() []*mockMpdRW {
+		rws := make([]*mockMpdRW, defaultConnectParams.poolSize+1)
+		for i := range rws {
+			rws[i] = &mockMpdRW{}
+		}
+		return rws
+	}
+	t.Run("noidle interrupts idle to wait for the new subsystems", func(t *testing.T) {
+		rws := newRWs()
+		interrupted := make(chan struct{})
+		idleChan := make(chan struct{})
+		rws[0].On("SendIdleCommand", "player").Run(func(args mock.Arguments) {
+			<-interrupted
+		}).Return([]string{}, nil).Once()
+		rws[0].On("SendIdleCommand", "mixer").Run(func(args mock.Arguments) {
+			<-idleChan
+		}).Return([]string{"changed: mixer"}, nil)
+		rws[0].On("NoIdle").Run(func(args mock.Arguments) {
+			interrupted <- struct{}{}
+		}).Return(nil)
+		pool, err := newMpdRWPool(newFactory(rws), defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() {}, WithIdleSubsystems("player"))
+		assert.Nil(t, err)
+		t.Cleanup(pool.cancel)
+		idleSubscribeChannel := pool.Subscribe(time.Millisecond * 100)
+		assert.Eventually(t, func() bool { return pool.Stats().IdleWatcher.Waiting }, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"player"}, pool.Stats().IdleWatcher.Subsystems)
+
+		assert.Nil(t, pool.SetIdleSubsystems("mixer"))
+		assert.Eventually(t, func() bool {
+			stats := pool.Stats().IdleWatcher
+			return stats.Waiting && slices.Equal(stats.Subsystems, []string{"mixer"})
+		}, time.Second, time.Millisecond)
+		idleChan <- struct{}{}
+		select {
+		case event := <-idleSubscribeChannel:
+			assert.Equal(t, []string{"changed: mixer"}, event)
+		case <-time.After(time.Second):
+			t.Error("No IDLE events were received")
+		}
+		rws[0].AssertNumberOfCalls(t, "NoIdle", 1)
+	})
+	t.Run("rejected subsystems fall back to all of them", func(t *testing.T) {
+		rws := newRWs()
+		idleChan := make(chan struct{})
+		ackErr := errors.Join(&mpdrw.AckError{Code: 2, Command: "idle", Message: "Unrecognized idle event: neighbor"}, mpdrw.ErrACK)
+		rws[0].On("SendIdleCommand", "player", "neighbor").Return(nil, ackErr).Once()
+		rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+			<-idleChan
+		}).Return([]string{"changed: mixer", "changed: player"}, nil)
+		pool, err := newMpdRWPool(newFactory(rws), defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() {}, WithIdleSubsystems("player", "neighbor"))
+		assert.Nil(t, err)
+		t.Cleanup(pool.cancel)
+		idleSubscribeChannel := pool.Subscribe(time.Millisecond * 100)
+		idleChan <- struct{}{}
+		select {
+		case event := <-idleSubscribeChannel:
+			assert.Equal(t, []string{"changed: player"}, event)
+		case <-time.After(time.Second):
+			t.Error("No IDLE events were received")
+		}
+		rws[0].AssertCalled(t, "SendIdleCommand", "player", "neighbor")
+		rws[0].AssertCalled(t, "SendIdleCommand")
+	})
+	t.Run("error answer is sent again after a delay", func(t *testing.T) {
+		rws := newRWs()
+		ackErr := errors.Join(&mpdrw.AckError{Code: 5, Command: "idle", Message: "unknown command"}, mpdrw.ErrACK)
+		rws[0].On("SendIdleCommand").Return(nil, ackErr)
+		pool, err := newMpdRWPool(newFactory(rws), defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() {})
+		assert.Nil(t, err)
+		t.Cleanup(pool.cancel)
+		assert.Eventually(t, func() bool { return pool.Stats().IdleWatcher.LastError != nil }, time.Second, time.Millisecond)
+		assert.ErrorIs(t, pool.Stats().IdleWatcher.LastError, mpdrw.ErrACK)
+		time.Sleep(100 * time.Millisecond)
+		rws[0].AssertNumberOfCalls(t, "SendIdleCommand", 1)
+	})
+	t.Run("unknown subsystem", func(t *testing.T) {
+		_, err := newMpdRWPool(newFactory(newRWs()), defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() {}, WithIdleSubsystems("player", "unknown"))
+		assert.ErrorIs(t, err, ErrUnknownSubsystem)
+
+		rws := newRWs()
+		rws[0].On("SendIdleCommand").Run(func(args mock.Arguments) {
+			select {}
+		}).Return(nil, nil)
+		pool, err := newMpdRWPool(newFactory(rws), defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, defaultConnectParams.pingInterval, func() {})
+		assert.Nil(t, err)
+		t.Cleanup(pool.cancel)
+		assert.ErrorIs(t, pool.SetIdleSubsystems("unknown"), ErrUnknownSubsystem)
+		rws[0].AssertNotCalled(t, "NoIdle")
+	})
+}
+
+// newFactory returns the connections of rws in order, the server is unreachable afterwards.
+func newFactory(rws []*mockMpdRW) mpdRWFactory {
+	var mu sync.Mutex
+	next := 0
+	return func() (mpdrw.MpdRW, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= len(rws) {
+			return nil, mpdrw.ErrIO
+		}
+		next++
+		return rws[next-1], nil
+	}
+}
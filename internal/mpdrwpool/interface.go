@@ -16,6 +16,16 @@ type MpdRWPool interface {
 	// - ErrSendingCommand
 	SendSingleCommand(requestContext context.Context, command commands.SingleCommand) ([]string, error)
 
+	// SendSingleCommandStream sends a command to the MPD server and passes
+	// the lines of the raw response to yield as they arrive.
+	// The connection is held until the whole response is read.
+	//
+	// The requestContext is used for logging.
+	//
+	// Can return the following errors:
+	// - ErrSendingCommand
+	SendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error
+
 	// SendBatchCommand sends a batch command to the MPD server
 	//
 	// The requestContext is used for logging.
@@ -23,5 +33,15 @@ type MpdRWPool interface {
 	// Can return the following errors:
 	// - ErrSendingCommand
 	SendBatchCommand(requestContext context.Context, command commands.BatchCommand) error
+
+	// Stats returns a snapshot of the connections of the pool.
+	Stats() Stats
+
+	// SetIdleSubsystems replaces the subsystems the idle connection waits for, all of them if empty.
+	//
+	// Can return the following errors:
+	// - ErrUnknownSubsystem
+	// - ErrSendingCommand
+	SetIdleSubsystems(subsystems ...string) error
 	observer.Observer[[]string]
 }
@@ -4,14 +4,20 @@ import (
 	"context"
 	"github.com/anpotashev/mpdgo/internal/commands"
 	"github.com/stretchr/testify/mock"
+	"sync/atomic"
 )
 
 type mockMpdRW struct {
 	mock.Mock
+	closed atomic.Bool
 }
 
-func (m *mockMpdRW) SendIdleCommand() ([]string, error) {
-	args := m.Called()
+func (m *mockMpdRW) SendIdleCommand(subsystems ...string) ([]string, error) {
+	arguments := make([]any, len(subsystems))
+	for i, subsystem := range subsystems {
+		arguments[i] = subsystem
+	}
+	args := m.Called(arguments...)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -24,7 +30,26 @@ func (m *mockMpdRW) SendSingleCommand(requestContext context.Context, command co
 	}
 	return args.Get(0).([]string), nil
 }
+func (m *mockMpdRW) SendSingleCommandStream(requestContext context.Context, command commands.SingleCommand, yield func(line string) bool) error {
+	args := m.Called(requestContext, command)
+	if args.Get(0) != nil {
+		for _, line := range args.Get(0).([]string) {
+			if !yield(line) {
+				break
+			}
+		}
+	}
+	return args.Error(1)
+}
 func (m *mockMpdRW) SendBatchCommand(requestContext context.Context, command commands.BatchCommand) error {
 	args := m.Called(requestContext, command)
 	return args.Error(0)
 }
+func (m *mockMpdRW) Close() error {
+	m.closed.Store(true)
+	return nil
+}
+func (m *mockMpdRW) NoIdle() error {
+	args := m.Called()
+	return args.Error(0)
+}
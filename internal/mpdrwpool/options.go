@@ -0,0 +1,79 @@
+package mpdrwpool
+
+import (
+	"slices"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/metrics"
+)
+
+// Option configures an Impl created with NewMpdRWPool.
+type Option func(*Impl)
+
+// WithMetrics reports the commands, the pool usage and the idle events to m.
+func WithMetrics(m metrics.Metrics) Option {
+	return func(p *Impl) {
+		p.metrics = m
+	}
+}
+
+// RetryPolicy defines how idempotent commands are retried on a new connection after an IO error.
+// Commands changing the state of the MPD server are never retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries of a command, 0 disables retrying.
+	MaxRetries int
+	// Backoff is the delay before the first retry. It is doubled for every next one.
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of a pool created without WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 2, Backoff: 50 * time.Millisecond}
+
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	return r.Backoff << (attempt - 1)
+}
+
+// WithRetryPolicy sets the retry policy of idempotent commands.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Impl) {
+		p.retryPolicy = policy
+	}
+}
+
+// Sizing defines how many connections the pool keeps open, not counting the one watching idle events.
+//
+// MinSize connections are opened with the pool and kept open. Up to MaxSize are opened
+// when all of them are in use; if a new one can't be opened, e.g. as the max_connections
+// of the MPD server are reached, the command waits until one of them is free.
+type Sizing struct {
+	MinSize uint8
+	MaxSize uint8
+	// IdleTimeout closes the connections above MinSize unused for that time, 0 keeps them open.
+	IdleTimeout time.Duration
+	// MaxLifetime closes the connections open for that time, 0 keeps them open.
+	// They are replaced if needed.
+	MaxLifetime time.Duration
+}
+
+// normalized makes MaxSize at least 1 and MinSize at most MaxSize.
+func (s Sizing) normalized() Sizing {
+	s.MaxSize = max(s.MaxSize, 1)
+	s.MinSize = min(s.MinSize, s.MaxSize)
+	return s
+}
+
+// WithSizing replaces the fixed pool size. The unused connections are closed and the
+// connections are pinged every ping interval.
+func WithSizing(sizing Sizing) Option {
+	return func(p *Impl) {
+		p.sizing = sizing
+	}
+}
+
+// WithIdleSubsystems makes the idle connection wait for changes of the subsystems only,
+// e.g. "player" and "mixer", instead of all of them. See Subsystems.
+func WithIdleSubsystems(subsystems ...string) Option {
+	return func(p *Impl) {
+		p.idleSubsystems = slices.Clone(subsystems)
+	}
+}
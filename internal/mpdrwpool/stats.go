@@ -0,0 +1,186 @@
+package mpdrwpool
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// waitWindow is the number of the last waits for a connection the percentiles are computed over.
+const waitWindow = 1024
+
+// Stats is a snapshot of the state of a pool.
+type Stats struct {
+	// MinSize and MaxSize are the limits of the open connections, see Sizing.
+	MinSize int
+	MaxSize int
+	// Open is the number of open connections, InUse the number of those sending a command.
+	// The connection watching the idle events is not counted.
+	Open  int
+	InUse int
+	// Waits is the number of times a connection was taken from the pool.
+	Waits uint64
+	// WaitP50, WaitP90, WaitP99 and WaitMax are the percentiles of the time waited for a connection
+	// over the last 1024 waits.
+	WaitP50 time.Duration
+	WaitP90 time.Duration
+	WaitP99 time.Duration
+	WaitMax time.Duration
+	// LastPing is the time of the last successful ping, zero if there was none.
+	LastPing time.Time
+	// Connections are the open connections, the oldest first.
+	Connections []ConnectionStats
+	IdleWatcher IdleWatcherStats
+}
+
+// ConnectionStats is the state of a connection of the pool.
+type ConnectionStats struct {
+	Age time.Duration
+	// LastUsed is the time the connection was last released by a command,
+	// LastActive the time a command or a ping was last sent.
+	LastUsed   time.Time
+	LastActive time.Time
+	InUse      bool
+	// Commands is the number of commands sent, including the pings.
+	Commands uint64
+	// LastError is the last error sending a command, including ACK errors, nil if there was none.
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// IdleWatcherStats is the state of the connection watching the idle events.
+type IdleWatcherStats struct {
+	// Waiting reports whether the idle command is sent and the changes are awaited.
+	Waiting bool
+	// Subsystems are the subsystems waited for, all of them if empty.
+	Subsystems []string
+	// Age is the time since the connection was opened.
+	Age time.Duration
+	// Events is the number of changed subsystems received.
+	Events    uint64
+	LastEvent time.Time
+	// Reconnects is the number of times the connection was lost and replaced.
+	Reconnects  uint64
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// rwStats are the statistics of a connection, guarded by Impl.mu.
+type rwStats struct {
+	created     time.Time
+	lastUsed    time.Time
+	lastActive  time.Time
+	inUse       bool
+	commands    uint64
+	lastError   error
+	lastErrorAt time.Time
+}
+
+func (s *rwStats) commandDone(err error) {
+	s.commands++
+	s.lastActive = time.Now()
+	if err != nil {
+		s.lastError = err
+		s.lastErrorAt = time.Now()
+	}
+}
+
+// idleStats are the statistics of the idle watcher, guarded by Impl.mu.
+type idleStats struct {
+	waiting     bool
+	connected   time.Time
+	events      uint64
+	lastEvent   time.Time
+	reconnects  uint64
+	lastError   error
+	lastErrorAt time.Time
+}
+
+// waitStats keeps the last waits for a connection, guarded by Impl.mu.
+type waitStats struct {
+	count  uint64
+	recent [waitWindow]time.Duration
+}
+
+func (s *waitStats) add(wait time.Duration) {
+	s.recent[s.count%waitWindow] = wait
+	s.count++
+}
+
+// percentiles returns the given percentiles of the recent waits.
+func (s *waitStats) percentiles(ps ...int) []time.Duration {
+	waits := slices.Clone(s.recent[:min(s.count, waitWindow)])
+	slices.Sort(waits)
+	result := make([]time.Duration, len(ps))
+	if len(waits) == 0 {
+		return result
+	}
+	for i, p := range ps {
+		result[i] = waits[(len(waits)-1)*p/100]
+	}
+	return result
+}
+
+// Stats returns a snapshot of the state of the pool.
+func (p *Impl) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	percentiles := p.waits.percentiles(50, 90, 99, 100)
+	result := Stats{
+		MinSize:  int(p.sizing.MinSize),
+		MaxSize:  int(p.sizing.MaxSize),
+		Open:     p.open,
+		Waits:    p.waits.count,
+		WaitP50:  percentiles[0],
+		WaitP90:  percentiles[1],
+		WaitP99:  percentiles[2],
+		WaitMax:  percentiles[3],
+		LastPing: p.lastPing,
+		IdleWatcher: IdleWatcherStats{
+			Waiting:     p.idle.waiting,
+			Subsystems:  slices.Clone(p.idleSubsystems),
+			Age:         now.Sub(p.idle.connected),
+			Events:      p.idle.events,
+			LastEvent:   p.idle.lastEvent,
+			Reconnects:  p.idle.reconnects,
+			LastError:   p.idle.lastError,
+			LastErrorAt: p.idle.lastErrorAt,
+		},
+	}
+	result.InUse = p.inUse
+	for rw := range p.conns {
+		result.Connections = append(result.Connections, ConnectionStats{
+			Age:         now.Sub(rw.created),
+			LastUsed:    rw.lastUsed,
+			LastActive:  rw.lastActive,
+			InUse:       rw.inUse,
+			Commands:    rw.commands,
+			LastError:   rw.lastError,
+			LastErrorAt: rw.lastErrorAt,
+		})
+	}
+	slices.SortFunc(result.Connections, func(a, b ConnectionStats) int {
+		return cmp.Compare(b.Age, a.Age)
+	})
+	return result
+}
+
+// idleDone records the answer to an idle command.
+func (p *Impl) idleDone(lines []string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idle.waiting = false
+	if err != nil {
+		p.idle.lastError = err
+		p.idle.lastErrorAt = time.Now()
+		return
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "changed: ") {
+			p.idle.events++
+			p.idle.lastEvent = time.Now()
+		}
+	}
+}
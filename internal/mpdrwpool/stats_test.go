@@ -0,0 +1,75 @@
+package mpdrwpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/anpotashev/mpdgo/internal/mpdrw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWaitStats_percentiles(t *testing.T) {
+	var stats waitStats
+	assert.Equal(t, []time.Duration{0, 0}, stats.percentiles(50, 100))
+	for i := 1; i <= 100; i++ {
+		stats.add(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond}, stats.percentiles(50, 99, 100))
+	// Only the last waits are kept.
+	for range waitWindow {
+		stats.add(time.Millisecond)
+	}
+	assert.Equal(t, uint64(100+waitWindow), stats.count)
+	assert.Equal(t, []time.Duration{time.Millisecond}, stats.percentiles(100))
+}
+
+func TestImpl_Stats(t *testing.T) {
+	status := commands.NewSingleCommand(commands.STATUS)
+	dialed := &dialedRWs{}
+	pool, err := newMpdRWPool(dialed.dial, defaultConnectParams.requestContext, defaultConnectParams.ctx, defaultConnectParams.poolSize, 10*time.Millisecond, func() {},
+		WithSizing(Sizing{MinSize: 1, MaxSize: 3}))
+	assert.Nil(t, err)
+	defer pool.cancel()
+
+	stats := pool.Stats()
+	assert.Equal(t, 1, stats.MinSize)
+	assert.Equal(t, 3, stats.MaxSize)
+	assert.Equal(t, 1, stats.Open)
+	assert.Len(t, stats.Connections, 1)
+	assert.Eventually(t, func() bool { return pool.Stats().IdleWatcher.Waiting }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return !pool.Stats().LastPing.IsZero() }, time.Second, time.Millisecond)
+
+	dialed.block = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := pool.SendSingleCommand(defaultConnectParams.requestContext, status)
+		assert.Nil(t, err)
+	}()
+	assert.Eventually(t, func() bool { return pool.Stats().InUse == 1 }, time.Second, time.Millisecond)
+	close(dialed.block)
+	<-done
+
+	failing := commands.NewSingleCommand(commands.PLAY)
+	dialed.mu.Lock()
+	for _, rw := range dialed.rws[1:] {
+		rw.On("SendSingleCommand", mock.Anything, failing).Return(nil, mpdrw.ErrACK)
+	}
+	dialed.mu.Unlock()
+	_, err = pool.SendSingleCommand(defaultConnectParams.requestContext, failing)
+	assert.ErrorIs(t, err, mpdrw.ErrACK)
+
+	stats = pool.Stats()
+	assert.Equal(t, 0, stats.InUse)
+	assert.Equal(t, uint64(2), stats.Waits)
+	assert.Len(t, stats.Connections, 1)
+	connection := stats.Connections[0]
+	assert.False(t, connection.InUse)
+	assert.GreaterOrEqual(t, connection.Commands, uint64(2))
+	assert.ErrorIs(t, connection.LastError, mpdrw.ErrACK)
+	assert.False(t, connection.LastErrorAt.IsZero())
+	assert.Positive(t, connection.Age)
+	assert.Equal(t, uint64(0), stats.IdleWatcher.Events)
+}
@@ -0,0 +1,126 @@
+// Package notifier implements an observer.Observer which delivers the events to every subscriber
+// in order, and which can be unsubscribed from while an event is sent.
+//
+// observer.Impl of go-observer sends every event from a goroutine of its own, so the events may
+// arrive in any order, and closes the channel on Unsubscribe even if one of them is still
+// sending to it, which panics.
+package notifier
+
+import (
+	"sync"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/logger"
+)
+
+type Notifier[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan T]*subscriber[T]
+}
+
+// subscriber sends the queued events to ch, one at a time.
+type subscriber[T any] struct {
+	ch      chan T
+	timeout time.Duration
+
+	mu    sync.Mutex
+	queue []T
+	// signal is sent to when an event is queued.
+	signal chan struct{}
+	// done is closed on Unsubscribe, it stops the sends. stopped is closed when they are stopped.
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func New[T any]() *Notifier[T] {
+	return &Notifier[T]{subscribers: make(map[chan T]*subscriber[T])}
+}
+
+// Subscribe returns a channel receiving the events. An event not received within timeout is dropped.
+func (n *Notifier[T]) Subscribe(timeout time.Duration) chan T {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s := &subscriber[T]{
+		ch:      make(chan T, 1),
+		timeout: timeout,
+		signal:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	n.subscribers[s.ch] = s
+	go s.run()
+	return s.ch
+}
+
+// Unsubscribe stops the events and closes ch once no event is being sent to it.
+func (n *Notifier[T]) Unsubscribe(ch chan T) {
+	n.Remove(ch)
+}
+
+// Remove unsubscribes ch, it reports whether ch was subscribed.
+func (n *Notifier[T]) Remove(ch chan T) bool {
+	n.mu.Lock()
+	s, ok := n.subscribers[ch]
+	delete(n.subscribers, ch)
+	n.mu.Unlock()
+	if !ok {
+		return false
+	}
+	close(s.done)
+	<-s.stopped
+	close(ch)
+	return true
+}
+
+// Notify queues the event for every subscriber without blocking.
+func (n *Notifier[T]) Notify(event T) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, s := range n.subscribers {
+		s.mu.Lock()
+		s.queue = append(s.queue, event)
+		s.mu.Unlock()
+		select {
+		case s.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *subscriber[T]) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case <-s.signal:
+		case <-s.done:
+			return
+		}
+		for {
+			s.mu.Lock()
+			if len(s.queue) == 0 {
+				s.mu.Unlock()
+				break
+			}
+			event := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			if !s.send(event) {
+				return
+			}
+		}
+	}
+}
+
+// send sends event to the channel, it reports false if the subscriber was removed meanwhile.
+func (s *subscriber[T]) send(event T) bool {
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case s.ch <- event:
+	case <-timer.C:
+		logger.Warn("Timeout sending an event to a subscriber")
+	case <-s.done:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifier(t *testing.T) {
+	t.Run("events are received in order", func(t *testing.T) {
+		n := New[int]()
+		ch := n.Subscribe(time.Second)
+		defer n.Unsubscribe(ch)
+		for i := range 100 {
+			n.Notify(i)
+		}
+		for i := range 100 {
+			assert.Equal(t, i, <-ch)
+		}
+	})
+	t.Run("unsubscribe while sending", func(t *testing.T) {
+		n := New[int]()
+		ch := n.Subscribe(time.Second)
+		n.Notify(1)
+		assert.Equal(t, 1, <-ch)
+
+		// The events being sent are stopped instead of being sent to the closed channel.
+		n.Notify(2)
+		n.Notify(3)
+		n.Notify(4)
+		n.Unsubscribe(ch)
+		for range ch {
+		}
+		n.Notify(5)
+		assert.False(t, n.Remove(ch))
+	})
+	t.Run("event not received in time is dropped", func(t *testing.T) {
+		n := New[int]()
+		ch := n.Subscribe(10 * time.Millisecond)
+		defer n.Unsubscribe(ch)
+		n.Notify(1)
+		n.Notify(2)
+		n.Notify(3)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, 1, <-ch)
+		n.Notify(4)
+		assert.Equal(t, 4, <-ch)
+	})
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"reflect"
+	"sync"
+)
+
+// DecodeFunc stores the value of a response line with the given key in target, and reports
+// whether target has a field for key. Lines with keys matching no field must be ignored,
+// or stored in the catch-all field, in which case the key is reported as known.
+//
+// DecodeFunc implementations are normally generated by internal/cmd/mpdgen.
+type DecodeFunc[T any] func(target *T, key, value string) (bool, error)
+
+type decoder[T any] struct {
+	decode         DecodeFunc[T]
+	newElementKeys map[string]struct{}
+}
+
+// decoders maps a reflect.Type to the *decoder registered for it.
+var decoders sync.Map
+
+// RegisterDecoder registers decode as the parsing function of T, so ParseSingleValue,
+// ParseMultiValue and ParseMultiValueSeq do not use reflection for T.
+// newElementKeys are the keys starting a new element in a multi-value response.
+//
+// It is meant to be called from init functions of generated code.
+func RegisterDecoder[T any](decode DecodeFunc[T], newElementKeys ...string) {
+	d := &decoder[T]{
+		decode:         decode,
+		newElementKeys: make(map[string]struct{}, len(newElementKeys)),
+	}
+	for _, key := range newElementKeys {
+		d.newElementKeys[key] = struct{}{}
+	}
+	decoders.Store(reflect.TypeFor[T](), d)
+}
+
+func lookupDecoder[T any]() (*decoder[T], bool) {
+	d, ok := decoders.Load(reflect.TypeFor[T]())
+	if !ok {
+		return nil, false
+	}
+	return d.(*decoder[T]), true
+}
+
+// decoderBuilder is the builder used for types with a registered decoder.
+type decoderBuilder[T any] struct {
+	decoder *decoder[T]
+	current *T
+}
+
+func (b *decoderBuilder[T]) hasNewElementKeys() bool {
+	return len(b.decoder.newElementKeys) > 0
+}
+
+func (b *decoderBuilder[T]) isNewElementKey(key string) bool {
+	_, ok := b.decoder.newElementKeys[key]
+	return ok
+}
+
+func (b *decoderBuilder[T]) reset() {
+	b.current = new(T)
+}
+
+func (b *decoderBuilder[T]) set(key, value string) (bool, error) {
+	return b.decoder.decode(b.current, key, value)
+}
+
+func (b *decoderBuilder[T]) value() T {
+	return *b.current
+}
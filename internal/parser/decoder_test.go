@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type decodedType struct {
+	Name  string `mpd_prefix:"name" is_new_element_prefix:"true"`
+	Count int    `mpd_prefix:"count"`
+	// decoded reports that the registered decoder was used.
+	decoded bool
+}
+
+type decodedTypeWithoutNewElement struct {
+	Name string `mpd_prefix:"name"`
+}
+
+func decodeDecodedType(target *decodedType, key, value string) (bool, error) {
+	target.decoded = true
+	switch key {
+	case "name":
+		target.Name = value
+	case "count":
+		v, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, NewFieldTypeParsingError("Count", value, "int", err)
+		}
+		target.Count = int(v)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func init() {
+	RegisterDecoder(decodeDecodedType, "name")
+	RegisterDecoder(func(target *decodedTypeWithoutNewElement, key, value string) (bool, error) { return false, nil })
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	t.Run("single value", func(t *testing.T) {
+		actual, err := ParseSingleValue[decodedType]([]string{"name: a", "", "count: 2", "unknown: x"})
+		assert.NoError(t, err)
+		assert.Equal(t, decodedType{Name: "a", Count: 2, decoded: true}, actual)
+	})
+	t.Run("multi value", func(t *testing.T) {
+		actual, err := ParseMultiValue[decodedType]([]string{"ignored: x", "name: a", "count: 1", "name: b"})
+		assert.NoError(t, err)
+		assert.Equal(t, []decodedType{{Name: "a", Count: 1, decoded: true}, {Name: "b", decoded: true}}, actual)
+	})
+	t.Run("decoder error", func(t *testing.T) {
+		_, err := ParseMultiValue[decodedType]([]string{"name: a", "count: x"})
+		assert.True(t, errors.Is(err, ErrParsingField))
+		_, err = ParseSingleValue[decodedType]([]string{"count: x"})
+		assert.True(t, errors.Is(err, ErrParsingField))
+	})
+	t.Run("malformed line", func(t *testing.T) {
+		_, err := ParseMultiValue[decodedType]([]string{"name: a", "malformed"})
+		assert.Error(t, err)
+	})
+	t.Run("no new element keys", func(t *testing.T) {
+		_, err := ParseMultiValue[decodedTypeWithoutNewElement]([]string{"name: a"})
+		assert.True(t, errors.Is(err, ErrNoFieldMarkedAsNewElement))
+	})
+}
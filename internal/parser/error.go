@@ -12,8 +12,21 @@ var (
 	ErrTargetTypeMustBeStruct       = fmt.Errorf("T must be a struct")
 	ErrUnsupportedFieldType         = fmt.Errorf("unsupported field type")
 	ErrParsingField           error = fmt.Errorf("field parsing error")
+	ErrMarshalingField        error = fmt.Errorf("field marshaling error")
+	//lint:ignore ST1005 ignore
+	ErrNoNewElementValue = fmt.Errorf("T value must have a field marked as \"new element prefix\" which is not nil")
 )
 
 func NewFieldParsingError(fieldName, value string, fieldVal reflect.Value, err error) error {
-	return fmt.Errorf("error parsing field %s: cannot convert %q to %v: %w", fieldName, value, fieldVal.Type(), fmt.Errorf("%w: %v", ErrParsingField, err))
+	return NewFieldTypeParsingError(fieldName, value, fieldVal.Type().String(), err)
+}
+
+// NewFieldTypeParsingError works like NewFieldParsingError, but takes the name of the field type.
+// It is used by generated decoders, which do not work with reflect.Value.
+func NewFieldTypeParsingError(fieldName, value, typeName string, err error) error {
+	return fmt.Errorf("error parsing field %s: cannot convert %q to %s: %w", fieldName, value, typeName, fmt.Errorf("%w: %v", ErrParsingField, err))
+}
+
+func NewFieldMarshalingError(fieldName string, err error) error {
+	return fmt.Errorf("error marshaling field %s: %w: %w", fieldName, ErrMarshalingField, err)
 }
@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshaler is implemented by types that can format themselves as the value
+// of an MPD response line. It is the inverse of Unmarshaler.
+type Marshaler interface {
+	MarshalMPD() (string, error)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// MarshalSingleValue formats value as MPD response lines. It is the inverse of ParseSingleValue.
+//
+// Fields are written in declaration order, one "prefix: value" line per field, slice
+// fields produce one line per element and nil pointers are skipped. Booleans are written
+// as 1 or 0, time.Time as RFC3339 and time.Duration as seconds with three decimals.
+// The entries of the catch-all field are written last, sorted by key.
+func MarshalSingleValue[T any](value T) ([]string, error) {
+	val := reflect.ValueOf(value)
+	if val.Kind() != reflect.Struct {
+		return nil, ErrTargetTypeMustBeStruct
+	}
+	return marshalStruct(getTypeInfo(val.Type()), val, nil)
+}
+
+// MarshalMultiValue formats values as MPD response lines. It is the inverse of ParseMultiValue.
+//
+// Each value is formatted like with MarshalSingleValue, except that the fields marked
+// with `is_new_element_prefix:"true"` are written first, so the value boundaries are
+// preserved. Zero new element fields are skipped, unless all of them are zero. A value
+// without a new element field to write, e.g. with all of them nil, can't be told apart from
+// the previous one and ErrNoNewElementValue is returned.
+func MarshalMultiValue[T any](values []T) ([]string, error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, ErrTargetTypeMustBeStruct
+	}
+	info := getTypeInfo(typ)
+	if len(info.newElementPrefixes) == 0 {
+		return nil, ErrNoFieldMarkedAsNewElement
+	}
+	var result []string
+	for _, value := range values {
+		val := reflect.ValueOf(value)
+		var newElementFields []fieldInfo
+		for _, field := range info.ordered {
+			if field.isNewElementPrefix && !val.FieldByIndex(field.index).IsZero() {
+				newElementFields = append(newElementFields, field)
+			}
+		}
+		if len(newElementFields) == 0 {
+			// A zero value is written as the boundary, nil pointers and empty slices are not written.
+			for _, field := range info.ordered {
+				if field.isNewElementPrefix && !isNilOrEmpty(val.FieldByIndex(field.index)) {
+					newElementFields = append(newElementFields, field)
+					break
+				}
+			}
+			if len(newElementFields) == 0 {
+				return nil, ErrNoNewElementValue
+			}
+		}
+		lines, err := marshalStruct(info, val, newElementFields)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, lines...)
+	}
+	return result, nil
+}
+
+func isNilOrEmpty(fieldVal reflect.Value) bool {
+	switch fieldVal.Kind() {
+	case reflect.Ptr:
+		return fieldVal.IsNil()
+	case reflect.Slice:
+		return fieldVal.Len() == 0
+	}
+	return false
+}
+
+// marshalStruct formats first, then the other fields of val except the new element ones
+// if first is not nil, and then the catch-all entries.
+func marshalStruct(info *typeInfo, val reflect.Value, first []fieldInfo) ([]string, error) {
+	var result []string
+	fields := info.ordered
+	if first != nil {
+		fields = slices.Clone(first)
+		for _, field := range info.ordered {
+			if !field.isNewElementPrefix {
+				fields = append(fields, field)
+			}
+		}
+	}
+	for _, field := range fields {
+		fieldName := val.Type().FieldByIndex(field.index).Name
+		values, err := formatFieldValue(val.FieldByIndex(field.index))
+		if err != nil {
+			return nil, NewFieldMarshalingError(fieldName, err)
+		}
+		for _, value := range values {
+			line, err := formatLine(field.name, value)
+			if err != nil {
+				return nil, NewFieldMarshalingError(fieldName, err)
+			}
+			result = append(result, line)
+		}
+	}
+	if info.catchAll != nil {
+		lines, err := formatCatchAllValue(val.FieldByIndex(info.catchAll))
+		if err != nil {
+			return nil, NewFieldMarshalingError(val.Type().FieldByIndex(info.catchAll).Name, err)
+		}
+		result = append(result, lines...)
+	}
+	return result, nil
+}
+
+func formatLine(key, value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", errors.New("value contains a line break")
+	}
+	return key + ": " + value, nil
+}
+
+// formatFieldValue returns the values of the lines representing fieldVal:
+// none for a nil pointer, one per element for a slice and one otherwise.
+func formatFieldValue(fieldVal reflect.Value) ([]string, error) {
+	if fieldVal.Kind() == reflect.Ptr {
+		if fieldVal.IsNil() {
+			return nil, nil
+		}
+		return formatFieldValue(fieldVal.Elem())
+	}
+	fieldType := fieldVal.Type()
+	if fieldType.Implements(marshalerType) || reflect.PointerTo(fieldType).Implements(marshalerType) {
+		return formatSingle(fieldVal)
+	}
+	if fieldVal.Kind() == reflect.Slice {
+		result := make([]string, 0, fieldVal.Len())
+		for i := 0; i < fieldVal.Len(); i++ {
+			values, err := formatFieldValue(fieldVal.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, values...)
+		}
+		return result, nil
+	}
+	return formatSingle(fieldVal)
+}
+
+func formatSingle(fieldVal reflect.Value) ([]string, error) {
+	value, err := formatValue(fieldVal)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+// formatValue converts fieldVal to the value of an MPD response line.
+func formatValue(fieldVal reflect.Value) (string, error) {
+	fieldType := fieldVal.Type()
+	if fieldType.Implements(marshalerType) {
+		return fieldVal.Interface().(Marshaler).MarshalMPD()
+	}
+	if reflect.PointerTo(fieldType).Implements(marshalerType) {
+		ptr := reflect.New(fieldType)
+		ptr.Elem().Set(fieldVal)
+		return ptr.Interface().(Marshaler).MarshalMPD()
+	}
+	switch fieldType {
+	case timeType:
+		return FormatTime(fieldVal.Interface().(time.Time)), nil
+	case durationType:
+		return FormatDuration(time.Duration(fieldVal.Int())), nil
+	}
+	if fieldType.Implements(textMarshalerType) {
+		text, err := fieldVal.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch fieldVal.Kind() {
+	case reflect.String:
+		return fieldVal.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fieldVal.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fieldVal.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fieldVal.Float(), 'f', -1, fieldType.Bits()), nil
+	case reflect.Bool:
+		if fieldVal.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	}
+	return "", ErrUnsupportedFieldType
+}
+
+// formatCatchAllValue returns the lines of a map[string]string or map[string][]string
+// catch-all field, sorted by key.
+func formatCatchAllValue(fieldVal reflect.Value) ([]string, error) {
+	fieldType := fieldVal.Type()
+	if fieldType.Kind() != reflect.Map || fieldType.Key().Kind() != reflect.String {
+		return nil, ErrUnsupportedFieldType
+	}
+	keys := fieldVal.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	var result []string
+	for _, key := range keys {
+		entry := fieldVal.MapIndex(key)
+		var values []string
+		switch {
+		case fieldType.Elem().Kind() == reflect.String:
+			values = []string{entry.String()}
+		case fieldType.Elem().Kind() == reflect.Slice && fieldType.Elem().Elem().Kind() == reflect.String:
+			for i := 0; i < entry.Len(); i++ {
+				values = append(values, entry.Index(i).String())
+			}
+		default:
+			return nil, ErrUnsupportedFieldType
+		}
+		for _, value := range values {
+			line, err := formatLine(key.String(), value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+// FormatTime formats a time the way MPD sends it (RFC3339).
+func FormatTime(value time.Time) string {
+	return value.Format(time.RFC3339)
+}
+
+// FormatDuration formats a duration the way MPD sends it, as seconds with three decimals.
+func FormatDuration(value time.Duration) string {
+	return strconv.FormatFloat(value.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func (f testFormat) MarshalMPD() (string, error) {
+	return fmt.Sprintf("%d:%s:%d", f.SampleRate, f.Bits, f.Channels), nil
+}
+
+func TestMarshalSingleValue(t *testing.T) {
+	t.Run("struct with all supported field types", func(t *testing.T) {
+		var value ParsedType
+		faker.FakeData(&value)
+		expected := toMpdResponse(&value)
+		actual, err := MarshalSingleValue(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+	t.Run("extended types round trip", func(t *testing.T) {
+		type parsedType struct {
+			Duration time.Duration     `mpd_prefix:"duration"`
+			Float    float64           `mpd_prefix:"float"`
+			Int64    int64             `mpd_prefix:"int64"`
+			State    testState         `mpd_prefix:"state"`
+			Level    *testLevel        `mpd_prefix:"level"`
+			Format   testFormat        `mpd_prefix:"format"`
+			Artists  []string          `mpd_prefix:"Artist"`
+			Other    map[string]string `is_catch_all:"true"`
+		}
+		level := testLevel(3)
+		value := parsedType{
+			Duration: 245123 * time.Millisecond,
+			Float:    1.5,
+			Int64:    -7,
+			State:    "play",
+			Level:    &level,
+			Format:   testFormat{SampleRate: 44100, Bits: "16", Channels: 2},
+			Artists:  []string{"a", "b"},
+			Other:    map[string]string{"z": "1", "b": "2"},
+		}
+		lines, err := MarshalSingleValue(value)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"duration: 245.123", "float: 1.5", "int64: -7", "state: play", "level: 3",
+			"format: 44100:16:2", "Artist: a", "Artist: b", "b: 2", "z: 1",
+		}, lines)
+		parsed, err := ParseSingleValue[parsedType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, value, parsed)
+	})
+	t.Run("value with a line break", func(t *testing.T) {
+		type parsedType struct {
+			Field string `mpd_prefix:"field"`
+		}
+		_, err := MarshalSingleValue(parsedType{Field: "a\nb"})
+		assert.True(t, errors.Is(err, ErrMarshalingField))
+	})
+	t.Run("unsupported field type", func(t *testing.T) {
+		type parsedType struct {
+			Field complex128 `mpd_prefix:"field"`
+		}
+		_, err := MarshalSingleValue(parsedType{})
+		assert.True(t, errors.Is(err, ErrUnsupportedFieldType))
+	})
+	t.Run("wrong target type", func(t *testing.T) {
+		_, err := MarshalSingleValue(1)
+		assert.True(t, errors.Is(err, ErrTargetTypeMustBeStruct))
+	})
+}
+
+func TestMarshalMultiValue(t *testing.T) {
+	t.Run("new element fields are written first", func(t *testing.T) {
+		type parsedType struct {
+			Title     *string  `mpd_prefix:"Title"`
+			File      *string  `mpd_prefix:"file" is_new_element_prefix:"true"`
+			Directory *string  `mpd_prefix:"directory" is_new_element_prefix:"true"`
+			Artist    []string `mpd_prefix:"Artist"`
+		}
+		file, dir, title := "a/1.mp3", "a", "Title"
+		values := []parsedType{
+			{Directory: &dir},
+			{Title: &title, File: &file, Artist: []string{"x", "y"}},
+		}
+		lines, err := MarshalMultiValue(values)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"directory: a", "file: a/1.mp3", "Title: Title", "Artist: x", "Artist: y"}, lines)
+		parsed, err := ParseMultiValue[parsedType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, values, parsed)
+	})
+	t.Run("zero new element field is written when it is the only one", func(t *testing.T) {
+		type parsedType struct {
+			Pos   int    `mpd_prefix:"Pos" is_new_element_prefix:"true"`
+			Title string `mpd_prefix:"Title"`
+		}
+		lines, err := MarshalMultiValue([]parsedType{{Title: "a"}, {Pos: 1, Title: "b"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Pos: 0", "Title: a", "Pos: 1", "Title: b"}, lines)
+	})
+	t.Run("values without new element fields round trip", func(t *testing.T) {
+		type parsedType struct {
+			File  *string `mpd_prefix:"file" is_new_element_prefix:"true"`
+			Pos   int     `mpd_prefix:"Pos" is_new_element_prefix:"true"`
+			Title string  `mpd_prefix:"Title"`
+		}
+		values := []parsedType{{Title: "a"}, {Title: "b"}}
+		lines, err := MarshalMultiValue(values)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Pos: 0", "Title: a", "Pos: 0", "Title: b"}, lines)
+		parsed, err := ParseMultiValue[parsedType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, values, parsed)
+	})
+	t.Run("value with nil new element fields", func(t *testing.T) {
+		type parsedType struct {
+			File      *string `mpd_prefix:"file" is_new_element_prefix:"true"`
+			Directory *string `mpd_prefix:"directory" is_new_element_prefix:"true"`
+			Title     string  `mpd_prefix:"Title"`
+		}
+		file := "1.mp3"
+		_, err := MarshalMultiValue([]parsedType{{File: &file, Title: "a"}, {Title: "b"}})
+		assert.ErrorIs(t, err, ErrNoNewElementValue)
+	})
+	t.Run("No field in the target struct is marked with is_new_element_prefix:\"true\"", func(t *testing.T) {
+		type parsedType struct {
+			Field string `mpd_prefix:"field"`
+		}
+		_, err := MarshalMultiValue([]parsedType{{}})
+		assert.True(t, errors.Is(err, ErrNoFieldMarkedAsNewElement))
+	})
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMalformedLine = errors.New("malformed line")
+	ErrUnknownKey    = errors.New("unknown key")
+)
+
+// LineError is returned when parsing fails because of a response line.
+type LineError struct {
+	// Line is the 1-based number of the line in the response.
+	Line int
+	// Text is the line content.
+	Text string
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d %q: %v", e.Line, e.Text, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LineDiagnostic describes a response line that was not mapped to any field.
+type LineDiagnostic struct {
+	// Line is the 1-based number of the line in the response.
+	Line int
+	// Text is the line content.
+	Text string
+}
+
+// Diagnostics collects the response lines that were not mapped to any field.
+type Diagnostics struct {
+	// UnknownKeys are the lines whose key matches no field of the target type.
+	UnknownKeys []LineDiagnostic
+	// MalformedLines are the lines that are not "key: value" pairs.
+	MalformedLines []LineDiagnostic
+}
+
+// IsEmpty reports whether all the response lines were mapped.
+func (d *Diagnostics) IsEmpty() bool {
+	return len(d.UnknownKeys) == 0 && len(d.MalformedLines) == 0
+}
+
+// Option configures the way ParseSingleValue, ParseMultiValue and ParseMultiValueSeq
+// handle unknown keys and malformed lines.
+//
+// By default, lines with unknown keys are ignored and a malformed line stops parsing
+// with a *LineError wrapping ErrMalformedLine.
+type Option func(*options)
+
+// WithDiagnostics collects unknown keys and malformed lines in report.
+// Malformed lines no longer stop parsing, unless Strict is used as well.
+func WithDiagnostics(report *Diagnostics) Option {
+	return func(o *options) {
+		o.diagnostics = report
+	}
+}
+
+// Strict makes parsing stop with a *LineError on the first line with an unknown key
+// (wrapping ErrUnknownKey) or malformed line (wrapping ErrMalformedLine).
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// IgnoreKeys makes the lines with the given keys ignored even in strict mode. They are not
+// reported as unknown keys either. It is meant for the keys known to be sent but not mapped.
+func IgnoreKeys(keys ...string) Option {
+	return func(o *options) {
+		if o.ignoredKeys == nil {
+			o.ignoredKeys = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			o.ignoredKeys[key] = struct{}{}
+		}
+	}
+}
+
+type options struct {
+	diagnostics *Diagnostics
+	strict      bool
+	ignoredKeys map[string]struct{}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) unknownKey(line int, text, key string) error {
+	if _, ok := o.ignoredKeys[key]; ok {
+		return nil
+	}
+	if o.diagnostics != nil {
+		o.diagnostics.UnknownKeys = append(o.diagnostics.UnknownKeys, LineDiagnostic{Line: line, Text: text})
+	}
+	if o.strict {
+		return &LineError{Line: line, Text: text, Err: ErrUnknownKey}
+	}
+	return nil
+}
+
+func (o *options) malformedLine(line int, text string) error {
+	if o.diagnostics != nil {
+		o.diagnostics.MalformedLines = append(o.diagnostics.MalformedLines, LineDiagnostic{Line: line, Text: text})
+	}
+	if o.strict || o.diagnostics == nil {
+		return &LineError{Line: line, Text: text, Err: ErrMalformedLine}
+	}
+	return nil
+}
+
+// setValue sets the field of the value built by b matching key.
+func setValue[T any](o *options, b builder[T], line int, text, key, value string) error {
+	known, err := b.set(key, value)
+	if err != nil {
+		return &LineError{Line: line, Text: text, Err: err}
+	}
+	if !known {
+		return o.unknownKey(line, text, key)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	type parsedType struct {
+		Field string `mpd_prefix:"field" is_new_element_prefix:"true"`
+		Other string `mpd_prefix:"other"`
+	}
+	lines := []string{"preamble: x", "field: a", "", "unknown: 1", "malformed", "field: b", "other: 2"}
+
+	t.Run("default mode stops on a malformed line", func(t *testing.T) {
+		_, err := ParseMultiValue[parsedType](lines)
+		var lineErr *LineError
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 5, lineErr.Line)
+		assert.Equal(t, "malformed", lineErr.Text)
+		assert.True(t, errors.Is(err, ErrMalformedLine))
+	})
+	t.Run("diagnostics are collected", func(t *testing.T) {
+		var report Diagnostics
+		actual, err := ParseMultiValue[parsedType](lines, WithDiagnostics(&report))
+		assert.NoError(t, err)
+		assert.Equal(t, []parsedType{{Field: "a"}, {Field: "b", Other: "2"}}, actual)
+		assert.Equal(t, []LineDiagnostic{{Line: 1, Text: "preamble: x"}, {Line: 4, Text: "unknown: 1"}}, report.UnknownKeys)
+		assert.Equal(t, []LineDiagnostic{{Line: 5, Text: "malformed"}}, report.MalformedLines)
+		assert.False(t, report.IsEmpty())
+	})
+	t.Run("malformed line before the first element", func(t *testing.T) {
+		_, err := ParseMultiValue[parsedType]([]string{"malformed", "field: a"})
+		var lineErr *LineError
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 1, lineErr.Line)
+		assert.True(t, errors.Is(err, ErrMalformedLine))
+
+		var report Diagnostics
+		actual, err := ParseMultiValue[parsedType]([]string{"malformed", "field: a"}, WithDiagnostics(&report))
+		assert.NoError(t, err)
+		assert.Equal(t, []parsedType{{Field: "a"}}, actual)
+		assert.Equal(t, []LineDiagnostic{{Line: 1, Text: "malformed"}}, report.MalformedLines)
+		assert.Empty(t, report.UnknownKeys)
+	})
+	t.Run("strict mode stops on an unknown key", func(t *testing.T) {
+		var report Diagnostics
+		_, err := ParseSingleValue[parsedType]([]string{"field: a", "unknown: 1"}, Strict(), WithDiagnostics(&report))
+		var lineErr *LineError
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 2, lineErr.Line)
+		assert.True(t, errors.Is(err, ErrUnknownKey))
+		assert.Len(t, report.UnknownKeys, 1)
+	})
+	t.Run("ignored keys are neither reported nor rejected", func(t *testing.T) {
+		var report Diagnostics
+		actual, err := ParseSingleValue[parsedType]([]string{"field: a", "unknown: 1"}, Strict(), WithDiagnostics(&report), IgnoreKeys("unknown"))
+		assert.NoError(t, err)
+		assert.Equal(t, parsedType{Field: "a"}, actual)
+		assert.True(t, report.IsEmpty())
+	})
+	t.Run("catch-all keys are known", func(t *testing.T) {
+		type catchAllType struct {
+			Field string            `mpd_prefix:"field"`
+			Other map[string]string `is_catch_all:"true"`
+		}
+		var report Diagnostics
+		_, err := ParseSingleValue[catchAllType]([]string{"field: a", "unknown: 1"}, Strict(), WithDiagnostics(&report))
+		assert.NoError(t, err)
+		assert.True(t, report.IsEmpty())
+	})
+	t.Run("field errors name the line", func(t *testing.T) {
+		type intType struct {
+			Field int `mpd_prefix:"field"`
+		}
+		_, err := ParseSingleValue[intType]([]string{"", "field: x"})
+		var lineErr *LineError
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 2, lineErr.Line)
+		assert.True(t, errors.Is(err, ErrParsingField))
+	})
+	t.Run("registered decoder reports unknown keys", func(t *testing.T) {
+		var report Diagnostics
+		_, err := ParseMultiValue[decodedType]([]string{"name: a", "unknown: 1"}, WithDiagnostics(&report))
+		assert.NoError(t, err)
+		assert.Equal(t, []LineDiagnostic{{Line: 2, Text: "unknown: 1"}}, report.UnknownKeys)
+	})
+}
@@ -1,23 +1,101 @@
 package parser
 
 import (
-	"errors"
+	"encoding"
+	"iter"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// getPrefixFieldMap parses a reflect.Type and returns a map of fields tagged with `mpd_prefix`.
-// The map keys are tag values, and values are corresponding StructField definitions.
-func getPrefixFieldMap(typ reflect.Type) map[string]reflect.StructField {
-	result := make(map[string]reflect.StructField)
+// Unmarshaler is implemented by types that can parse themselves from the value
+// of an MPD response line. A field whose pointer implements Unmarshaler is always
+// parsed with UnmarshalMPD, regardless of its kind.
+type Unmarshaler interface {
+	UnmarshalMPD(value string) error
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// fieldInfo describes a field tagged with `mpd_prefix`.
+// index is the path to the field, nested structs included (see reflect.Value.FieldByIndex).
+type fieldInfo struct {
+	name               string
+	index              []int
+	isNewElementPrefix bool
+}
+
+// isNestedStruct reports whether a field of type typ without the `mpd_prefix` tag
+// is a struct whose tagged fields are parsed as if they belonged to the outer struct.
+func isNestedStruct(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct &&
+		typ != timeType &&
+		!reflect.PointerTo(typ).Implements(unmarshalerType) &&
+		!reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// collectFields returns the fields of typ tagged with `mpd_prefix`, including the ones of nested structs.
+func collectFields(typ reflect.Type, parentIndex []int) []fieldInfo {
+	var result []fieldInfo
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
 		prefix := field.Tag.Get("mpd_prefix")
-		if prefix != "" {
-			result[prefix] = field
+		if prefix == "" {
+			if isNestedStruct(field.Type) && field.IsExported() {
+				result = append(result, collectFields(field.Type, index)...)
+			}
+			continue
 		}
+		result = append(result, fieldInfo{
+			name:               prefix,
+			index:              index,
+			isNewElementPrefix: field.Tag.Get("is_new_element_prefix") == "true",
+		})
+	}
+	return result
+}
+
+// typeInfo holds the parsing metadata of a struct type.
+type typeInfo struct {
+	ordered            []fieldInfo
+	fields             map[string]fieldInfo
+	newElementPrefixes []string
+	catchAll           []int
+}
+
+// typeInfoCache maps a reflect.Type to its *typeInfo, so struct tags are read only once per type.
+var typeInfoCache sync.Map
+
+// getTypeInfo returns the parsing metadata of typ, building and caching it on first use.
+func getTypeInfo(typ reflect.Type) *typeInfo {
+	if info, ok := typeInfoCache.Load(typ); ok {
+		return info.(*typeInfo)
+	}
+	info := &typeInfo{
+		ordered:            collectFields(typ, nil),
+		fields:             getPrefixFieldMap(typ),
+		newElementPrefixes: getNewElementPrefixesSlice(typ),
+		catchAll:           getCatchAllFieldIndex(typ),
+	}
+	actual, _ := typeInfoCache.LoadOrStore(typ, info)
+	return actual.(*typeInfo)
+}
+
+// getPrefixFieldMap parses a reflect.Type and returns a map of fields tagged with `mpd_prefix`.
+// The map keys are tag values, and values are corresponding field definitions.
+func getPrefixFieldMap(typ reflect.Type) map[string]fieldInfo {
+	result := make(map[string]fieldInfo)
+	for _, field := range collectFields(typ, nil) {
+		result[field.name] = field
 	}
 	return result
 }
@@ -27,129 +105,248 @@ func getPrefixFieldMap(typ reflect.Type) map[string]reflect.StructField {
 // flag `is_new_element_prefix=true`.
 func getNewElementPrefixesSlice(typ reflect.Type) []string {
 	var result []string
+	for _, field := range collectFields(typ, nil) {
+		if field.isNewElementPrefix {
+			result = append(result, field.name+":")
+		}
+	}
+	return result
+}
+
+// getCatchAllFieldIndex returns the index of the field tagged with `is_catch_all:"true"`,
+// or nil if there is no such field. The field must be a map[string]string or a map[string][]string
+// and receives the lines whose keys match no other field.
+func getCatchAllFieldIndex(typ reflect.Type) []int {
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		prefix := field.Tag.Get("mpd_prefix")
-		if str := field.Tag.Get("is_new_element_prefix"); str == "true" {
-			result = append(result, prefix+":")
+		if field.Tag.Get("is_catch_all") == "true" {
+			return field.Index
 		}
 	}
-	return result
+	return nil
 }
 
-// parseLineAndSetFieldValue parses a line, extracts a value from it, and sets the corresponding field
-// on the targetElement using the provided map of field definitions.
+// splitLine splits an MPD response line into its key and value.
+func splitLine(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+// parseLineAndSetFieldValue sets the field of targetElement matching key to value,
+// using the provided map of field definitions.
 //
 // The 'fields' map contains struct fields indexed by expected prefixes.
 // The 'targetElement' must be a reflect.Value pointing to a struct.
-// Returns an error if parsing or assignment fails.
-func parseLineAndSetFieldValue(fields map[string]reflect.StructField, targetElement reflect.Value, line string) error {
-	parts := strings.SplitN(line, ":", 2)
-	if len(parts) != 2 {
-		return errors.New("mpd_prefix does not contain an element prefix")
-	}
-	key := strings.TrimSpace(parts[0])
-	value := strings.TrimSpace(parts[1])
-	if field, ok := fields[key]; ok {
-		fieldVal := targetElement.FieldByName(field.Name)
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			switch fieldVal.Type() {
-			case reflect.TypeOf((*string)(nil)):
-				fieldVal.Set(reflect.ValueOf(&value))
-			case reflect.TypeOf((*bool)(nil)):
-				v, err := strconv.ParseBool(value)
-				if err != nil {
-					err = NewFieldParsingError(field.Name, value, fieldVal, err)
-					return err
-				}
-				fieldVal.Set(reflect.ValueOf(&v))
-			case reflect.TypeOf((*int)(nil)):
-				v, err := strconv.Atoi(value)
-				if err != nil {
-					err = NewFieldParsingError(field.Name, value, fieldVal, err)
-					return err
-				}
-				fieldVal.Set(reflect.ValueOf(&v))
-			case reflect.TypeOf((*uint16)(nil)):
-				v, err := strconv.Atoi(value)
-				if err != nil {
-					err = NewFieldParsingError(field.Name, value, fieldVal, err)
-					return err
-				}
-				uintVal := uint16(v)
-				fieldVal.Set(reflect.ValueOf(&uintVal))
-			case reflect.TypeOf((*time.Time)(nil)):
-				parsedTime, err := time.Parse(time.RFC3339, value)
-				if err != nil {
-					err = NewFieldParsingError(field.Name, value, fieldVal, err)
-					return err
-				}
-				fieldVal.Set(reflect.ValueOf(&parsedTime))
-			default:
-				return ErrUnsupportedFieldType
-			}
-		case reflect.String:
-			fieldVal.SetString(value)
-		case reflect.Int:
-			intVal, err := strconv.Atoi(value)
-			if err != nil {
-				err = NewFieldParsingError(field.Name, value, fieldVal, err)
-				return err
-			}
-			fieldVal.SetInt(int64(intVal))
-		case reflect.Uint16:
-			uint16Val, err := strconv.ParseUint(value, 10, 64)
-			if err != nil {
-				err = NewFieldParsingError(field.Name, value, fieldVal, err)
-				return err
-			}
-			fieldVal.SetUint(uint16Val)
-		case reflect.Bool:
-			boolVal, err := strconv.ParseBool(value)
-			if err != nil {
-				err = NewFieldParsingError(field.Name, value, fieldVal, err)
-				return err
-			}
-			fieldVal.SetBool(boolVal)
-		case reflect.Struct:
-			if fieldVal.Type() == reflect.TypeOf(time.Time{}) {
-				parsedTime, err := time.Parse(time.RFC3339, value)
-				if err != nil {
-					err = NewFieldParsingError(field.Name, value, fieldVal, err)
-					return err
-				}
-				fieldVal.Set(reflect.ValueOf(parsedTime))
-			}
-		default:
-			return ErrUnsupportedFieldType
+// Lines with unknown prefixes are stored in the catch-all field, if catchAll is not nil.
+// Reports whether a field for key was found, and returns an error if parsing or assignment fails.
+func parseLineAndSetFieldValue(fields map[string]fieldInfo, catchAll []int, targetElement reflect.Value, key, value string) (bool, error) {
+	field, ok := fields[key]
+	if !ok {
+		if catchAll != nil {
+			return true, setCatchAllValue(targetElement.FieldByIndex(catchAll), key, value)
 		}
+		return false, nil
+	}
+	fieldVal := targetElement.FieldByIndex(field.index)
+	return true, setFieldValue(targetElement.Type().FieldByIndex(field.index).Name, fieldVal, value)
+}
+
+// setFieldValue converts value to the type of fieldVal and stores it.
+func setFieldValue(fieldName string, fieldVal reflect.Value, value string) error {
+	fieldType := fieldVal.Type()
+	if reflect.PointerTo(fieldType).Implements(unmarshalerType) {
+		if err := fieldVal.Addr().Interface().(Unmarshaler).UnmarshalMPD(value); err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		return nil
+	}
+	switch fieldType {
+	case timeType:
+		parsedTime, err := ParseTime(value)
+		if err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		fieldVal.Set(reflect.ValueOf(parsedTime))
+		return nil
+	case durationType:
+		duration, err := ParseDuration(value)
+		if err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		fieldVal.SetInt(int64(duration))
+		return nil
+	}
+	if reflect.PointerTo(fieldType).Implements(textUnmarshalerType) {
+		if err := fieldVal.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		return nil
+	}
+	switch fieldVal.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(fieldType.Elem())
+		if err := setFieldValue(fieldName, elem.Elem(), value); err != nil {
+			return err
+		}
+		fieldVal.Set(elem)
+	case reflect.String:
+		fieldVal.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intVal, err := strconv.ParseInt(value, 10, fieldType.Bits())
+		if err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		fieldVal.SetInt(intVal)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintVal, err := strconv.ParseUint(value, 10, fieldType.Bits())
+		if err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		fieldVal.SetUint(uintVal)
+	case reflect.Float32, reflect.Float64:
+		floatVal, err := strconv.ParseFloat(value, fieldType.Bits())
+		if err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		fieldVal.SetFloat(floatVal)
+	case reflect.Bool:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return NewFieldParsingError(fieldName, value, fieldVal, err)
+		}
+		fieldVal.SetBool(boolVal)
+	case reflect.Slice:
+		// Repeated keys accumulate in slice fields (e.g. several Artist lines).
+		elem := reflect.New(fieldType.Elem()).Elem()
+		if err := setFieldValue(fieldName, elem, value); err != nil {
+			return err
+		}
+		fieldVal.Set(reflect.Append(fieldVal, elem))
+	default:
+		return ErrUnsupportedFieldType
+	}
+	return nil
+}
+
+// setCatchAllValue stores a line with an unknown key in a map[string]string
+// or map[string][]string field.
+func setCatchAllValue(fieldVal reflect.Value, key, value string) error {
+	fieldType := fieldVal.Type()
+	if fieldType.Kind() != reflect.Map || fieldType.Key().Kind() != reflect.String {
+		return ErrUnsupportedFieldType
+	}
+	if fieldVal.IsNil() {
+		fieldVal.Set(reflect.MakeMap(fieldType))
+	}
+	keyVal := reflect.ValueOf(key).Convert(fieldType.Key())
+	switch {
+	case fieldType.Elem().Kind() == reflect.String:
+		fieldVal.SetMapIndex(keyVal, reflect.ValueOf(value).Convert(fieldType.Elem()))
+	case fieldType.Elem().Kind() == reflect.Slice && fieldType.Elem().Elem().Kind() == reflect.String:
+		values := fieldVal.MapIndex(keyVal)
+		if !values.IsValid() {
+			values = reflect.MakeSlice(fieldType.Elem(), 0, 1)
+		}
+		fieldVal.SetMapIndex(keyVal, reflect.Append(values, reflect.ValueOf(value).Convert(fieldType.Elem().Elem())))
+	default:
+		return ErrUnsupportedFieldType
 	}
 	return nil
 }
 
+// builder creates values of type T and sets their fields from response lines.
+type builder[T any] interface {
+	// hasNewElementKeys reports whether T has fields marked as new element prefixes.
+	hasNewElementKeys() bool
+	isNewElementKey(key string) bool
+	// reset starts building a new value.
+	reset()
+	// set sets the field matching key and reports whether there is such a field.
+	set(key, value string) (bool, error)
+	value() T
+}
+
+// reflectBuilder is the builder used for types without a registered decoder.
+type reflectBuilder[T any] struct {
+	typ     reflect.Type
+	info    *typeInfo
+	current reflect.Value
+}
+
+func (b *reflectBuilder[T]) hasNewElementKeys() bool {
+	return len(b.info.newElementPrefixes) > 0
+}
+
+func (b *reflectBuilder[T]) isNewElementKey(key string) bool {
+	field, ok := b.info.fields[key]
+	return ok && field.isNewElementPrefix
+}
+
+func (b *reflectBuilder[T]) reset() {
+	b.current = reflect.New(b.typ).Elem()
+}
+
+func (b *reflectBuilder[T]) set(key, value string) (bool, error) {
+	return parseLineAndSetFieldValue(b.info.fields, b.info.catchAll, b.current, key, value)
+}
+
+func (b *reflectBuilder[T]) value() T {
+	return b.current.Interface().(T)
+}
+
+// newBuilder returns the builder of T, using the decoder registered for T, if any.
+func newBuilder[T any]() (builder[T], error) {
+	if d, ok := lookupDecoder[T](); ok {
+		return &decoderBuilder[T]{decoder: d}, nil
+	}
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, ErrTargetTypeMustBeStruct
+	}
+	return &reflectBuilder[T]{typ: typ, info: getTypeInfo(typ)}, nil
+}
+
 // ParseSingleValue parses the provided MPD response lines into a single value of type T.
 //
 // Fields in the struct T must be tagged with `mpd_prefix` to allow correct mapping.
-// Returns an error if parsing fails or the response is invalid.
-func ParseSingleValue[T any](mpdAnswer []string) (T, error) {
-	val := reflect.ValueOf(new(T))
-	if val.Elem().Kind() != reflect.Struct {
-		return *new(T), ErrTargetTypeMustBeStruct
-	}
-	typ := val.Elem().Type()
-	fields := getPrefixFieldMap(typ)
-	result := reflect.New(typ).Elem()
-	for _, line := range mpdAnswer {
+// Supported field types are strings, integers, floats, booleans, time.Time (RFC3339),
+// time.Duration (seconds with a fractional part), types implementing Unmarshaler or
+// encoding.TextUnmarshaler, pointers to and slices of all of them. Slice fields collect
+// the values of all the lines with their prefix, other fields keep the last value.
+// Tagged fields of nested structs are parsed as if they belonged to T, and lines with
+// unknown prefixes are stored in the field tagged with `is_catch_all:"true"`, if any.
+// Returns an error if parsing fails or the response is invalid. Errors caused by a line
+// are returned as *LineError.
+//
+// If a decoder is registered for T with RegisterDecoder, it is used instead of reflection.
+// See Option for the ways to handle unknown keys and malformed lines.
+func ParseSingleValue[T any](mpdAnswer []string, opts ...Option) (T, error) {
+	b, err := newBuilder[T]()
+	if err != nil {
+		return *new(T), err
+	}
+	o := newOptions(opts)
+	b.reset()
+	for i, line := range mpdAnswer {
 		line = strings.TrimSpace(line)
 		if len(line) == 0 {
 			continue
 		}
-		if err := parseLineAndSetFieldValue(fields, result, line); err != nil {
+		key, value, ok := splitLine(line)
+		if !ok {
+			if err := o.malformedLine(i+1, line); err != nil {
+				return *new(T), err
+			}
+			continue
+		}
+		if err := setValue(o, b, i+1, line, key, value); err != nil {
 			return *new(T), err
 		}
 	}
-	return result.Interface().(T), nil
+	return b.value(), nil
 }
 
 // ParseMultiValue parses the provided MPD response lines into a slice of values of type T.
@@ -159,38 +356,97 @@ func ParseSingleValue[T any](mpdAnswer []string) (T, error) {
 // At least one of these tags must also have the flag `is_new_element_prefix=true`
 // to indicate the beginning of a new element in the response.
 // Returns an error if parsing fails or the input format is invalid.
-func ParseMultiValue[T any](mpdAnswer []string) ([]T, error) {
-	val := reflect.ValueOf(new(T))
-	if val.Elem().Kind() != reflect.Struct {
-		return nil, ErrTargetTypeMustBeStruct
-	}
-	typ := val.Elem().Type()
+func ParseMultiValue[T any](mpdAnswer []string, opts ...Option) ([]T, error) {
 	var results []T
-	fields := getPrefixFieldMap(typ)
-	var newElementPrefix = getNewElementPrefixesSlice(typ)
-	if len(newElementPrefix) == 0 {
-		return nil, ErrNoFieldMarkedAsNewElement
+	for item, err := range ParseMultiValueSeq[T](sliceSeq(mpdAnswer), opts...) {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, item)
 	}
-	var current reflect.Value
-	for _, line := range mpdAnswer {
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			continue
+	return results, nil
+}
+
+// ParseMultiValueSeq works like ParseMultiValue, but consumes the response lines
+// from an iterator and yields every value of type T as soon as it is complete,
+// so the whole response never has to be kept in memory.
+//
+// An error, either parsing or coming from mpdAnswer, is yielded as the last element.
+// Lines preceding the first new element prefix are ignored, and reported as unknown
+// keys when diagnostics are enabled.
+func ParseMultiValueSeq[T any](mpdAnswer iter.Seq2[string, error], opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		b, err := newBuilder[T]()
+		if err != nil {
+			yield(*new(T), err)
+			return
 		}
-		for _, prefix := range newElementPrefix {
-			if strings.HasPrefix(line, prefix) {
-				if current.IsValid() {
-					results = append(results, current.Interface().(T))
+		if !b.hasNewElementKeys() {
+			yield(*new(T), ErrNoFieldMarkedAsNewElement)
+			return
+		}
+		o := newOptions(opts)
+		started := false
+		lineNumber := 0
+		for line, err := range mpdAnswer {
+			if err != nil {
+				yield(*new(T), err)
+				return
+			}
+			lineNumber++
+			line = strings.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			key, value, ok := splitLine(line)
+			if ok && b.isNewElementKey(key) {
+				if started && !yield(b.value(), nil) {
+					return
 				}
-				current = reflect.New(typ).Elem()
+				b.reset()
+				started = true
+			}
+			switch {
+			case !ok:
+				err = o.malformedLine(lineNumber, line)
+			case !started:
+				// No element has a field for the lines before the first one.
+				err = o.unknownKey(lineNumber, line, key)
+			default:
+				err = setValue(o, b, lineNumber, line, key, value)
+			}
+			if err != nil {
+				yield(*new(T), err)
+				return
 			}
 		}
-		if err := parseLineAndSetFieldValue(fields, current, line); err != nil {
-			return nil, err
+		if started {
+			yield(b.value(), nil)
 		}
 	}
-	if current.IsValid() {
-		results = append(results, current.Interface().(T))
+}
+
+func sliceSeq(lines []string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for _, line := range lines {
+			if !yield(line, nil) {
+				return
+			}
+		}
 	}
-	return results, nil
+}
+
+// ParseTime parses a time sent by MPD (RFC3339).
+func ParseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339, value)
+}
+
+// ParseDuration parses a duration sent by MPD as seconds with a fractional part, e.g. "245.123".
+func ParseDuration(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	// Rounded, as e.g. 1.005 is 1.00499999... as a float.
+	return time.Duration(math.Round(seconds * float64(time.Second))), nil
 }
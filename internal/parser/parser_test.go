@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -130,7 +131,7 @@ func TestParseSingleValue(t *testing.T) {
 	})
 	t.Run("error parsing with unsupported field type", func(t *testing.T) {
 		type parsedType struct {
-			Field uint64 `mpd_prefix:"field"`
+			Field complex128 `mpd_prefix:"field"`
 		}
 		lines := []string{"field: 111"}
 		_, err := ParseSingleValue[parsedType](lines)
@@ -139,13 +140,90 @@ func TestParseSingleValue(t *testing.T) {
 	})
 	t.Run("error parsing with unsupported field type (ptr)", func(t *testing.T) {
 		type parsedType struct {
-			Field *uint64 `mpd_prefix:"field"`
+			Field *complex128 `mpd_prefix:"field"`
 		}
 		lines := []string{"field: 111"}
 		_, err := ParseSingleValue[parsedType](lines)
 		assert.Error(t, err)
 		assert.True(t, errors.Is(err, ErrUnsupportedFieldType))
 	})
+	t.Run("parsing extended field types", func(t *testing.T) {
+		type parsedType struct {
+			Float    float64        `mpd_prefix:"float"`
+			Duration time.Duration  `mpd_prefix:"duration"`
+			DurPtr   *time.Duration `mpd_prefix:"duration_ptr"`
+			Uint     uint           `mpd_prefix:"uint"`
+			Int64    int64          `mpd_prefix:"int64"`
+			State    testState      `mpd_prefix:"state"`
+			Level    testLevel      `mpd_prefix:"level"`
+			Format   testFormat     `mpd_prefix:"format"`
+		}
+		lines := []string{
+			"float: 1.5",
+			"duration: 245.123",
+			"duration_ptr: 10",
+			"uint: 42",
+			"int64: -9000000000",
+			"state: play",
+			"level: 3",
+			"format: 44100:24:2",
+		}
+		actual, err := ParseSingleValue[parsedType](lines)
+		assert.NoError(t, err)
+		tenSeconds := 10 * time.Second
+		assert.Equal(t, parsedType{
+			Float:    1.5,
+			Duration: 245123 * time.Millisecond,
+			DurPtr:   &tenSeconds,
+			Uint:     42,
+			Int64:    -9000000000,
+			State:    testState("play"),
+			Level:    testLevel(3),
+			Format:   testFormat{SampleRate: 44100, Bits: "24", Channels: 2},
+		}, actual)
+	})
+	t.Run("error from unmarshaler", func(t *testing.T) {
+		type parsedType struct {
+			Format testFormat `mpd_prefix:"format"`
+		}
+		_, err := ParseSingleValue[parsedType]([]string{"format: wrong"})
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, ErrParsingField))
+	})
+	t.Run("parsing nested structs", func(t *testing.T) {
+		type audio struct {
+			Bitrate int `mpd_prefix:"bitrate"`
+		}
+		type Embedded struct {
+			Volume int `mpd_prefix:"volume"`
+		}
+		type parsedType struct {
+			Embedded
+			State string `mpd_prefix:"state"`
+			Audio audio
+		}
+		lines := []string{"volume: 50", "state: play", "bitrate: 320"}
+		actual, err := ParseSingleValue[parsedType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, parsedType{Embedded: Embedded{Volume: 50}, State: "play", Audio: audio{Bitrate: 320}}, actual)
+	})
+	t.Run("parsing unknown keys into catch-all fields", func(t *testing.T) {
+		type parsedType struct {
+			State string              `mpd_prefix:"state"`
+			Other map[string][]string `is_catch_all:"true"`
+		}
+		lines := []string{"state: play", "Label: a", "Label: b", "MUSICBRAINZ_TRACKID: c"}
+		actual, err := ParseSingleValue[parsedType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, parsedType{State: "play", Other: map[string][]string{"Label": {"a", "b"}, "MUSICBRAINZ_TRACKID": {"c"}}}, actual)
+
+		type singleValueType struct {
+			Other map[string]string `is_catch_all:"true"`
+		}
+		single, err := ParseSingleValue[singleValueType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"state": "play", "Label": "b", "MUSICBRAINZ_TRACKID": "c"}, single.Other)
+	})
 	t.Run("wrong target type", func(t *testing.T) {
 		type parsedType interface{}
 		var lines []string
@@ -155,6 +233,37 @@ func TestParseSingleValue(t *testing.T) {
 	})
 }
 
+func TestParseDuration(t *testing.T) {
+	for _, tt := range []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "0", expected: 0},
+		{value: "0.001", expected: time.Millisecond},
+		{value: "1.001", expected: 1001 * time.Millisecond},
+		{value: "1.005", expected: 1005 * time.Millisecond},
+		{value: "254.849", expected: 254849 * time.Millisecond},
+		{value: "245.123", expected: 245123 * time.Millisecond},
+		{value: "60", expected: time.Minute},
+	} {
+		t.Run(tt.value, func(t *testing.T) {
+			actual, err := ParseDuration(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+			assert.Equal(t, tt.expected, mustParseDuration(t, FormatDuration(actual)))
+		})
+	}
+	_, err := ParseDuration("asdf")
+	assert.Error(t, err)
+}
+
+func mustParseDuration(t *testing.T, value string) time.Duration {
+	t.Helper()
+	result, err := ParseDuration(value)
+	assert.NoError(t, err)
+	return result
+}
+
 func TestParseMultiValue(t *testing.T) {
 	t.Run("successful parsing to struct with all supported field types", func(t *testing.T) {
 		expectedSlice := make([]ParsedType, 3)
@@ -190,6 +299,30 @@ func TestParseMultiValue(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, expected, actual)
 	})
+	t.Run("parsing repeated keys into slice fields", func(t *testing.T) {
+		type parsedType struct {
+			File   string   `mpd_prefix:"file" is_new_element_prefix:"true"`
+			Artist []string `mpd_prefix:"Artist"`
+			Disc   []int    `mpd_prefix:"Disc"`
+		}
+		expected := []parsedType{
+			{File: "a", Artist: []string{"Artist1", "Artist2"}, Disc: []int{1, 2}},
+			{File: "b", Artist: []string{"Artist3"}},
+		}
+		lines := []string{"file: a", "Artist: Artist1", "Disc: 1", "Artist: Artist2", "Disc: 2", "file: b", "Artist: Artist3"}
+		actual, err := ParseMultiValue[parsedType](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+	t.Run("error parsing value for []int field", func(t *testing.T) {
+		type parsedType struct {
+			Field []int `mpd_prefix:"field" is_new_element_prefix:"true"`
+		}
+		lines := []string{"field: asdf"}
+		_, err := ParseMultiValue[parsedType](lines)
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, ErrParsingField))
+	})
 	t.Run("No field in the target struct is marked with is_new_element_prefix:\"true\"", func(t *testing.T) {
 		type targetStruct struct {
 			//lint:ignore U1000 ignore
@@ -216,7 +349,7 @@ func TestParseMultiValue(t *testing.T) {
 	})
 	t.Run("error parsing with unsupported field type", func(t *testing.T) {
 		type parsedType struct {
-			Field uint64 `mpd_prefix:"field" is_new_element_prefix:"true"`
+			Field complex128 `mpd_prefix:"field" is_new_element_prefix:"true"`
 		}
 		lines := []string{"field: 100"}
 		_, err := ParseMultiValue[parsedType](lines)
@@ -226,9 +359,55 @@ func TestParseMultiValue(t *testing.T) {
 
 }
 
+func TestParseMultiValueSeq(t *testing.T) {
+	type parsedType struct {
+		Field string `mpd_prefix:"field" is_new_element_prefix:"true"`
+		Other string `mpd_prefix:"other"`
+	}
+	t.Run("values are yielded as soon as they are complete", func(t *testing.T) {
+		var consumed int
+		lines := func(yield func(string, error) bool) {
+			for _, line := range []string{"field: a", "other: 1", "field: b", "other: 2"} {
+				consumed++
+				if !yield(line, nil) {
+					return
+				}
+			}
+		}
+		var actual []parsedType
+		for item, err := range ParseMultiValueSeq[parsedType](lines) {
+			assert.NoError(t, err)
+			actual = append(actual, item)
+			break
+		}
+		assert.Equal(t, []parsedType{{Field: "a", Other: "1"}}, actual)
+		assert.Equal(t, 3, consumed)
+	})
+	t.Run("source error is yielded last and the incomplete value is dropped", func(t *testing.T) {
+		sourceErr := errors.New("source error")
+		lines := func(yield func(string, error) bool) {
+			if !yield("field: a", nil) || !yield("field: b", nil) {
+				return
+			}
+			yield("", sourceErr)
+		}
+		var items []parsedType
+		var lastErr error
+		for item, err := range ParseMultiValueSeq[parsedType](lines) {
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			items = append(items, item)
+		}
+		assert.Equal(t, []parsedType{{Field: "a"}}, items)
+		assert.ErrorIs(t, lastErr, sourceErr)
+	})
+}
+
 func toMpdResponse(value *ParsedType) []string {
-	value.DateField = value.DateField.Round(time.Second)
-	datePtrValue := (*value.DatePtrField).Round(time.Second)
+	value.DateField = value.DateField.Round(time.Second).UTC()
+	datePtrValue := (*value.DatePtrField).Round(time.Second).UTC()
 	value.DatePtrField = &datePtrValue
 	value.IntPtrFieldNilValue = nil
 	value.Uint16PtrFieldNilValue = nil
@@ -249,6 +428,22 @@ func toMpdResponse(value *ParsedType) []string {
 	}
 }
 
+type testState string
+
+type testLevel int
+
+// testFormat parses the MPD audio format "samplerate:bits:channels".
+type testFormat struct {
+	SampleRate int
+	Bits       string
+	Channels   int
+}
+
+func (f *testFormat) UnmarshalMPD(value string) error {
+	_, err := fmt.Sscanf(strings.ReplaceAll(value, ":", " "), "%d %s %d", &f.SampleRate, &f.Bits, &f.Channels)
+	return err
+}
+
 func b2int(b bool) int8 {
 	if b {
 		return 1
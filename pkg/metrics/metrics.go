@@ -0,0 +1,44 @@
+// Package metrics defines the hooks the client calls to report its activity,
+// see mpdapi.WithMetrics. Package prommetrics exports them to Prometheus.
+package metrics
+
+import "time"
+
+// Result is the outcome of a command.
+type Result string
+
+const (
+	// ResultOK means the command succeeded.
+	ResultOK Result = "ok"
+	// ResultACK means MPD answered the command with an ACK error.
+	ResultACK Result = "ack"
+	// ResultError means the command failed with an IO error or a timeout.
+	ResultError Result = "error"
+)
+
+// Metrics receives the measurements of a client. The methods are called synchronously
+// from the goroutines sending the commands, so they must be fast and safe for concurrent use.
+type Metrics interface {
+	// CommandDone is called when the answer to a command is read, with the command name
+	// (e.g. "status", or "command_list" for a command list), the result and the time from sending the command to the end of the answer.
+	CommandDone(command string, result Result, duration time.Duration)
+	// PoolWait is called with the time a command waited for a free connection of the pool.
+	PoolWait(duration time.Duration)
+	// ConnectionsInUse is called with the number of pool connections running a command
+	// whenever a connection is taken from or returned to the pool.
+	ConnectionsInUse(count int)
+	// Reconnected is called when the client connects again after a disconnection,
+	// and when a pool connection is redialed in place of a broken one.
+	Reconnected()
+	// IdleEvent is called for every changed subsystem reported by MPD, e.g. "player".
+	IdleEvent(subsystem string)
+}
+
+// Nop is a Metrics ignoring all measurements.
+type Nop struct{}
+
+func (Nop) CommandDone(string, Result, time.Duration) {}
+func (Nop) PoolWait(time.Duration)                    {}
+func (Nop) ConnectionsInUse(int)                      {}
+func (Nop) Reconnected()                              {}
+func (Nop) IdleEvent(string)                          {}
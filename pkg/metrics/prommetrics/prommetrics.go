@@ -0,0 +1,109 @@
+// Package prommetrics exports the client metrics to Prometheus:
+//
+//	m := prommetrics.New("jukebox")
+//	prometheus.MustRegister(m)
+//	api, err := mpdapi.NewMpdApi(ctx, host, port, password, false, 100, 4, time.Second, time.Second,
+//		mpdapi.WithMetrics(m))
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a metrics.Metrics and a prometheus.Collector of the following metrics:
+//
+//   - mpd_commands_total{command, result}: commands answered, result is "ok", "ack" or "error";
+//   - mpd_command_duration_seconds{command}: time from sending a command to the end of its answer;
+//   - mpd_pool_wait_seconds: time waiting for a free pool connection;
+//   - mpd_pool_connections_in_use: pool connections running a command;
+//   - mpd_reconnects_total: connections after a disconnection, and redials of broken pool connections;
+//   - mpd_idle_events_total{subsystem}: changed subsystems reported by MPD.
+//
+// The metric names are prefixed with the namespace passed to New, if any.
+type Metrics struct {
+	commands         *prometheus.CounterVec
+	commandDuration  *prometheus.HistogramVec
+	poolWait         prometheus.Histogram
+	connectionsInUse prometheus.Gauge
+	reconnects       prometheus.Counter
+	idleEvents       *prometheus.CounterVec
+	collectors       []prometheus.Collector
+}
+
+var _ metrics.Metrics = (*Metrics)(nil)
+
+// New returns the metrics with the names prefixed with namespace (may be empty).
+func New(namespace string) *Metrics {
+	m := &Metrics{
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mpd_commands_total",
+			Help:      "Number of MPD commands by command and result.",
+		}, []string{"command", "result"}),
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "mpd_command_duration_seconds",
+			Help:      "Time from sending an MPD command to the end of its answer.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"command"}),
+		poolWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "mpd_pool_wait_seconds",
+			Help:      "Time waiting for a free connection of the pool.",
+			Buckets:   []float64{.00001, .0001, .001, .005, .01, .05, .1, .5, 1},
+		}),
+		connectionsInUse: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "mpd_pool_connections_in_use",
+			Help:      "Number of pool connections running a command.",
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mpd_reconnects_total",
+			Help:      "Number of connections after a disconnection and redials of broken pool connections.",
+		}),
+		idleEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mpd_idle_events_total",
+			Help:      "Number of changed subsystems reported by MPD.",
+		}, []string{"subsystem"}),
+	}
+	m.collectors = []prometheus.Collector{m.commands, m.commandDuration, m.poolWait, m.connectionsInUse, m.reconnects, m.idleEvents}
+	return m
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors {
+		c.Describe(ch)
+	}
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) CommandDone(command string, result metrics.Result, duration time.Duration) {
+	m.commands.WithLabelValues(command, string(result)).Inc()
+	m.commandDuration.WithLabelValues(command).Observe(duration.Seconds())
+}
+
+func (m *Metrics) PoolWait(duration time.Duration) {
+	m.poolWait.Observe(duration.Seconds())
+}
+
+func (m *Metrics) ConnectionsInUse(count int) {
+	m.connectionsInUse.Set(float64(count))
+}
+
+func (m *Metrics) Reconnected() {
+	m.reconnects.Inc()
+}
+
+func (m *Metrics) IdleEvent(subsystem string) {
+	m.idleEvents.WithLabelValues(subsystem).Inc()
+}
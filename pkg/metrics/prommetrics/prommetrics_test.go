@@ -0,0 +1,52 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("test")
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(m))
+
+	m.CommandDone("status", metrics.ResultOK, 2*time.Millisecond)
+	m.CommandDone("status", metrics.ResultOK, 3*time.Millisecond)
+	m.CommandDone("play", metrics.ResultACK, time.Millisecond)
+	m.PoolWait(time.Microsecond)
+	m.ConnectionsInUse(2)
+	m.Reconnected()
+	m.IdleEvent("player")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.commands.WithLabelValues("status", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.commands.WithLabelValues("play", "ack")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.connectionsInUse))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.reconnects))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.idleEvents.WithLabelValues("player")))
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP test_mpd_pool_wait_seconds Time waiting for a free connection of the pool.
+# TYPE test_mpd_pool_wait_seconds histogram
+test_mpd_pool_wait_seconds_bucket{le="1e-05"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.0001"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.001"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.005"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.01"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.05"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.1"} 1
+test_mpd_pool_wait_seconds_bucket{le="0.5"} 1
+test_mpd_pool_wait_seconds_bucket{le="1"} 1
+test_mpd_pool_wait_seconds_bucket{le="+Inf"} 1
+test_mpd_pool_wait_seconds_sum 1e-06
+test_mpd_pool_wait_seconds_count 1
+`), "test_mpd_pool_wait_seconds"))
+	count, err := testutil.GatherAndCount(registry, "test_mpd_command_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
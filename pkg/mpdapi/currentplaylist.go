@@ -2,11 +2,11 @@ package mpdapi
 
 import (
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/anpotashev/mpdgo/internal/commands"
 	log "github.com/anpotashev/mpdgo/internal/logger"
-	"github.com/anpotashev/mpdgo/internal/parser"
 )
 
 type Playlist struct {
@@ -16,18 +16,32 @@ type Playlist struct {
 }
 
 type PlaylistItem struct {
-	File   string  `mpd_prefix:"file" is_new_element_prefix:"true"`
-	Artist *string `mpd_prefix:"Artist"`
-	Title  *string `mpd_prefix:"Title"`
-	Album  *string `mpd_prefix:"Album"`
-	Track  *string `mpd_prefix:"Track"`
-	Time   int     `mpd_prefix:"Time"`
-	Pos    int     `mpd_prefix:"Pos"`
-	Id     int     `mpd_prefix:"Id"`
+	File        string         `mpd_prefix:"file" is_new_element_prefix:"true"`
+	Artist      []string       `mpd_prefix:"Artist"`
+	AlbumArtist []string       `mpd_prefix:"AlbumArtist"`
+	Title       *string        `mpd_prefix:"Title"`
+	Album       *string        `mpd_prefix:"Album"`
+	Track       *string        `mpd_prefix:"Track"`
+	Genre       []string       `mpd_prefix:"Genre"`
+	Composer    []string       `mpd_prefix:"Composer"`
+	Performer   []string       `mpd_prefix:"Performer"`
+	Time        int            `mpd_prefix:"Time"`
+	Duration    *time.Duration `mpd_prefix:"duration"`
+	Format      *string        `mpd_prefix:"Format"`
+	Pos         int            `mpd_prefix:"Pos"`
+	Id          int            `mpd_prefix:"Id"`
+	// Other contains the tags not mapped to any other field. They are not reported as unknown
+	// keys, see WithDiagnosticsHandler and WithStrictParsing.
+	Other map[string][]string `is_catch_all:"true"`
 }
 
 type CurrentPlaylist interface {
 	Playlist() (*Playlist, error)
+	// PlaylistSeq returns an iterator over the items of the current playlist,
+	// parsed as they are received from MPD. An error is yielded as the last element.
+	// The loop holds a connection, but a connection is always left to the commands sent from
+	// the loop body: the stream opens a connection of its own if it would hold the last one of the pool.
+	PlaylistSeq() iter.Seq2[PlaylistItem, error]
 	PlaylistInfo(name string) (*Playlist, error)
 	Clear() error
 	Add(path string) error
@@ -40,27 +54,36 @@ type CurrentPlaylist interface {
 	AddStoredToPos(name string, pos int) error
 }
 
-func (api *Impl) Playlist() (*Playlist, error) {
-	cmd := commands.NewSingleCommand(commands.PLAYLIST_INFO)
-	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
-	if err != nil {
-		return nil, wrapPkgError(err)
-	}
-	playlistItems, err := parser.ParseMultiValue[PlaylistItem](list)
-	if err != nil {
-		return nil, wrapPkgError(err)
+func (api *Impl) Playlist() (_ *Playlist, err error) {
+	api, end := api.traced("Playlist")
+	defer func() { end(err) }()
+	var playlistItems []PlaylistItem
+	for item, err := range api.PlaylistSeq() {
+		if err != nil {
+			return nil, err
+		}
+		playlistItems = append(playlistItems, item)
 	}
 	return &Playlist{
 		Items: playlistItems,
 	}, nil
 }
-func (api *Impl) PlaylistInfo(name string) (*Playlist, error) {
+
+func (api *Impl) PlaylistSeq() iter.Seq2[PlaylistItem, error] {
+	return tracedSeq(api, "PlaylistSeq", func(api *Impl) iter.Seq2[PlaylistItem, error] {
+		cmd := commands.NewSingleCommand(commands.PLAYLIST_INFO)
+		return wrapPkgErrorSeq(parseMultiValueSeq[PlaylistItem](api.parsing, cmd, api.mpdClient.SendSingleCommandSeq(api.requestContext, cmd)))
+	})
+}
+func (api *Impl) PlaylistInfo(name string) (_ *Playlist, err error) {
+	api, end := api.traced("PlaylistInfo")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.LISTPLAYLIST_INFO).AddParams(name)
 	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
 	if err != nil {
 		return nil, wrapPkgError(err)
 	}
-	playlistItems, err := parser.ParseMultiValue[PlaylistItem](list)
+	playlistItems, err := parseMultiValue[PlaylistItem](api.parsing, cmd, list)
 	if err != nil {
 		return nil, wrapPkgError(err)
 	}
@@ -69,12 +92,16 @@ func (api *Impl) PlaylistInfo(name string) (*Playlist, error) {
 	}, nil
 }
 
-func (api *Impl) Clear() error {
+func (api *Impl) Clear() (err error) {
+	api, end := api.traced("Clear")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.CLEAR)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Add(path string) error {
+func (api *Impl) Add(path string) (err error) {
+	api, end := api.traced("Add")
+	defer func() { end(err) }()
 	log.DebugContext(api.requestContext, "getting files by path")
 	paths, err := api.getFilesPaths(path)
 	if err != nil {
@@ -93,7 +120,9 @@ func (api *Impl) Add(path string) error {
 	return wrapPkgError(api.mpdClient.SendBatchCommand(api.requestContext, cmds))
 }
 
-func (api *Impl) AddToPos(pos int, path string) error {
+func (api *Impl) AddToPos(pos int, path string) (err error) {
+	api, end := api.traced("AddToPos")
+	defer func() { end(err) }()
 	paths, err := api.getFilesPaths(path)
 	if err != nil {
 		return err
@@ -111,18 +140,12 @@ func (api *Impl) AddToPos(pos int, path string) error {
 
 func (api *Impl) getFilesPaths(path string) ([]string, error) {
 	cmd := commands.NewSingleCommand(commands.LISTALL).AddParams(path)
-	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
-	if err != nil {
-		return nil, wrapPkgError(err)
-	}
-	//log.DebugContext(api.requestContext, "list", list)
-	items, err := parser.ParseMultiValue[ParsedItem](list)
-	if err != nil {
-		return nil, wrapPkgError(err)
-	}
-	log.DebugContext(api.requestContext, "after parsing", "items size", len(items))
+	lines := api.mpdClient.SendSingleCommandSeq(api.requestContext, cmd)
 	var paths []string
-	for _, item := range items {
+	for item, err := range parseMultiValueSeq[ParsedItem](api.parsing, cmd, lines) {
+		if err != nil {
+			return nil, wrapPkgError(err)
+		}
 		log.DebugContext(api.requestContext, "processing", "item", item)
 		if item.File != nil {
 			log.DebugContext(api.requestContext, "added")
@@ -133,38 +156,50 @@ func (api *Impl) getFilesPaths(path string) ([]string, error) {
 	return paths, nil
 }
 
-func (api *Impl) DeleteByPos(pos int) error {
+func (api *Impl) DeleteByPos(pos int) (err error) {
+	api, end := api.traced("DeleteByPos")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.DELETE).AddParams(pos)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Move(fromPos, toPos int) error {
+func (api *Impl) Move(fromPos, toPos int) (err error) {
+	api, end := api.traced("Move")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.MOVE).AddParams(fromPos).AddParams(toPos)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) BatchMove(fromStartPos, fromEndPos, toPos int) error {
+func (api *Impl) BatchMove(fromStartPos, fromEndPos, toPos int) (err error) {
+	api, end := api.traced("BatchMove")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.MOVE).AddParams(fmt.Sprintf("%d:%d", fromStartPos, fromEndPos)).AddParams(toPos)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) ShuffleAll() error {
+func (api *Impl) ShuffleAll() (err error) {
+	api, end := api.traced("ShuffleAll")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.SHUFFLE)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Shuffle(fromPos, toPos int) error {
+func (api *Impl) Shuffle(fromPos, toPos int) (err error) {
+	api, end := api.traced("Shuffle")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.SHUFFLE).AddParams(fmt.Sprintf("%d:%d", fromPos, toPos))
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) AddStoredToPos(name string, pos int) error {
+func (api *Impl) AddStoredToPos(name string, pos int) (err error) {
+	api, end := api.traced("AddStoredToPos")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.LISTPLAYLIST_INFO).AddParams(name)
 	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
 	if err != nil {
 		return wrapPkgError(err)
 	}
-	playlistItems, err := parser.ParseMultiValue[PlaylistItem](list)
+	playlistItems, err := parseMultiValue[PlaylistItem](api.parsing, cmd, list)
 	if err != nil {
 		return wrapPkgError(err)
 	}
@@ -0,0 +1,306 @@
+// Code generated by mpdgen. DO NOT EDIT.
+
+package mpdapi
+
+import (
+	"strconv"
+
+	"github.com/anpotashev/mpdgo/internal/parser"
+)
+
+func init() {
+	parser.RegisterDecoder(decodeOutput, "outputid")
+	parser.RegisterDecoder(decodeParsedItem, "file", "directory", "playlist")
+	parser.RegisterDecoder(decodePlaylist, "playlist")
+	parser.RegisterDecoder(decodePlaylistItem, "file")
+	parser.RegisterDecoder(decodeStatus)
+	parser.RegisterDecoder(decodeUpdateAnswer)
+}
+
+func decodeOutput(target *Output, key, value string) (bool, error) {
+	switch key {
+	case "outputname":
+		target.Name = value
+	case "outputid":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Id", value, "int", err)
+		}
+		target.Id = int(v1)
+	case "outputenabled":
+		v1, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Enabled", value, "bool", err)
+		}
+		target.Enabled = v1
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func decodeParsedItem(target *ParsedItem, key, value string) (bool, error) {
+	switch key {
+	case "file":
+		v1 := value
+		target.File = &v1
+	case "directory":
+		v1 := value
+		target.Directory = &v1
+	case "playlist":
+		v1 := value
+		target.Playlist = &v1
+	case "Last-Modified":
+		v1, err := parser.ParseTime(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("LastModified", value, "time.Time", err)
+		}
+		target.LastModified = &v1
+	case "Time":
+		v1 := value
+		target.Time = &v1
+	case "duration":
+		v1, err := parser.ParseDuration(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Duration", value, "time.Duration", err)
+		}
+		target.Duration = &v1
+	case "Format":
+		v1 := value
+		target.Format = &v1
+	case "Artist":
+		target.Artist = append(target.Artist, value)
+	case "AlbumArtist":
+		target.AlbumArtist = append(target.AlbumArtist, value)
+	case "Title":
+		v1 := value
+		target.Title = &v1
+	case "Album":
+		v1 := value
+		target.Album = &v1
+	case "Track":
+		v1 := value
+		target.Track = &v1
+	case "Date":
+		v1 := value
+		target.Date = &v1
+	case "Genre":
+		target.Genre = append(target.Genre, value)
+	case "Composer":
+		target.Composer = append(target.Composer, value)
+	case "Performer":
+		target.Performer = append(target.Performer, value)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func decodePlaylist(target *Playlist, key, value string) (bool, error) {
+	switch key {
+	case "playlist":
+		v1 := value
+		target.Name = &v1
+	case "Last-Modified":
+		v1, err := parser.ParseTime(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("LastModified", value, "time.Time", err)
+		}
+		target.LastModified = &v1
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func decodePlaylistItem(target *PlaylistItem, key, value string) (bool, error) {
+	switch key {
+	case "file":
+		target.File = value
+	case "Artist":
+		target.Artist = append(target.Artist, value)
+	case "AlbumArtist":
+		target.AlbumArtist = append(target.AlbumArtist, value)
+	case "Title":
+		v1 := value
+		target.Title = &v1
+	case "Album":
+		v1 := value
+		target.Album = &v1
+	case "Track":
+		v1 := value
+		target.Track = &v1
+	case "Genre":
+		target.Genre = append(target.Genre, value)
+	case "Composer":
+		target.Composer = append(target.Composer, value)
+	case "Performer":
+		target.Performer = append(target.Performer, value)
+	case "Time":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Time", value, "int", err)
+		}
+		target.Time = int(v1)
+	case "duration":
+		v1, err := parser.ParseDuration(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Duration", value, "time.Duration", err)
+		}
+		target.Duration = &v1
+	case "Format":
+		v1 := value
+		target.Format = &v1
+	case "Pos":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Pos", value, "int", err)
+		}
+		target.Pos = int(v1)
+	case "Id":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Id", value, "int", err)
+		}
+		target.Id = int(v1)
+	default:
+		if target.Other == nil {
+			target.Other = make(map[string][]string)
+		}
+		target.Other[key] = append(target.Other[key], value)
+	}
+	return true, nil
+}
+
+func decodeStatus(target *status, key, value string) (bool, error) {
+	switch key {
+	case "volume":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Volume", value, "int", err)
+		}
+		v2 := int(v1)
+		target.Volume = &v2
+	case "repeat":
+		v1, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Repeat", value, "bool", err)
+		}
+		target.Repeat = &v1
+	case "random":
+		v1, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Random", value, "bool", err)
+		}
+		target.Random = &v1
+	case "single":
+		v1, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Single", value, "bool", err)
+		}
+		target.Single = &v1
+	case "consume":
+		v1, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Consume", value, "bool", err)
+		}
+		target.Consume = &v1
+	case "playlist":
+		v1 := value
+		target.Playlist = &v1
+	case "playlistlength":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("PlaylistLength", value, "int", err)
+		}
+		v2 := int(v1)
+		target.PlaylistLength = &v2
+	case "xfade":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Xfade", value, "int", err)
+		}
+		v2 := int(v1)
+		target.Xfade = &v2
+	case "state":
+		v1 := value
+		target.State = &v1
+	case "song":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Song", value, "int", err)
+		}
+		v2 := int(v1)
+		target.Song = &v2
+	case "songid":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("SongId", value, "int", err)
+		}
+		v2 := int(v1)
+		target.SongId = &v2
+	case "time":
+		v1 := value
+		target.Time = &v1
+	case "elapsed":
+		v1, err := parser.ParseDuration(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Elapsed", value, "time.Duration", err)
+		}
+		target.Elapsed = &v1
+	case "duration":
+		v1, err := parser.ParseDuration(value)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Duration", value, "time.Duration", err)
+		}
+		target.Duration = &v1
+	case "bitrate":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("Bitrate", value, "int", err)
+		}
+		v2 := int(v1)
+		target.Bitrate = &v2
+	case "audio":
+		v1 := value
+		target.Audio = &v1
+	case "nextsong":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("NextSong", value, "int", err)
+		}
+		v2 := int(v1)
+		target.NextSong = &v2
+	case "nextsongid":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("NextSongId", value, "int", err)
+		}
+		v2 := int(v1)
+		target.NextSongId = &v2
+	case "updating_db":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("UpdatingDb", value, "int", err)
+		}
+		v2 := int(v1)
+		target.UpdatingDb = &v2
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func decodeUpdateAnswer(target *updateAnswer, key, value string) (bool, error) {
+	switch key {
+	case "updating_db":
+		v1, err := strconv.ParseInt(value, 10, 0)
+		if err != nil {
+			return true, parser.NewFieldTypeParsingError("JobId", value, "int", err)
+		}
+		target.JobId = int(v1)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package mpdapi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/anpotashev/mpdgo/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// Types with the same fields as the generated ones, but without registered decoders,
+// so they are parsed with reflection.
+type (
+	reflectPlaylistItem PlaylistItem
+	reflectParsedItem   ParsedItem
+	reflectStatus       status
+)
+
+func playlistInfoAnswer(n int) []string {
+	var lines []string
+	for i := range n {
+		lines = append(lines,
+			fmt.Sprintf("file: music/artist/album/%02d.flac", i),
+			"Last-Modified: 2024-05-01T10:20:30Z",
+			"Artist: Artist",
+			"Artist: Guest",
+			"AlbumArtist: Artist",
+			fmt.Sprintf("Title: Title %d", i),
+			"Album: Album",
+			fmt.Sprintf("Track: %d", i+1),
+			"Date: 2001",
+			"Genre: Rock",
+			"MUSICBRAINZ_TRACKID: 0b1a4e0e-5d4c-4e4b-8f3c-123456789abc",
+			"Time: 245",
+			"duration: 245.123",
+			"Format: 44100:16:2",
+			fmt.Sprintf("Pos: %d", i),
+			fmt.Sprintf("Id: %d", i+100),
+		)
+	}
+	return lines
+}
+
+func TestGeneratedDecoders(t *testing.T) {
+	t.Run("playlist items", func(t *testing.T) {
+		lines := playlistInfoAnswer(3)
+		generated, err := parser.ParseMultiValue[PlaylistItem](lines)
+		assert.NoError(t, err)
+		reflected, err := parser.ParseMultiValue[reflectPlaylistItem](lines)
+		assert.NoError(t, err)
+		assert.Len(t, generated, 3)
+		for i := range generated {
+			assert.Equal(t, PlaylistItem(reflected[i]), generated[i])
+		}
+	})
+	t.Run("database items", func(t *testing.T) {
+		lines := append([]string{"directory: music", "Last-Modified: 2024-05-01T10:20:30Z", "playlist: music/list.m3u"},
+			playlistInfoAnswer(2)...)
+		generated, err := parser.ParseMultiValue[ParsedItem](lines)
+		assert.NoError(t, err)
+		reflected, err := parser.ParseMultiValue[reflectParsedItem](lines)
+		assert.NoError(t, err)
+		assert.Len(t, generated, 4)
+		for i := range generated {
+			assert.Equal(t, ParsedItem(reflected[i]), generated[i])
+		}
+	})
+	t.Run("status", func(t *testing.T) {
+		lines := []string{"volume: 50", "repeat: 1", "random: 0", "single: 0", "consume: 1", "playlist: 12",
+			"playlistlength: 3", "xfade: 5", "state: play", "song: 1", "songid: 101", "time: 12:245",
+			"elapsed: 12.345", "duration: 245.123", "bitrate: 320", "audio: 44100:16:2", "nextsong: 2",
+			"nextsongid: 102", "updating_db: 7"}
+		generated, err := parser.ParseSingleValue[status](lines)
+		assert.NoError(t, err)
+		reflected, err := parser.ParseSingleValue[reflectStatus](lines)
+		assert.NoError(t, err)
+		assert.Equal(t, status(reflected), generated)
+	})
+	t.Run("parsing errors", func(t *testing.T) {
+		lines := []string{"volume: loud"}
+		_, generatedErr := parser.ParseSingleValue[status](lines)
+		_, reflectedErr := parser.ParseSingleValue[reflectStatus](lines)
+		assert.ErrorIs(t, generatedErr, parser.ErrParsingField)
+		assert.EqualError(t, generatedErr, reflectedErr.Error())
+	})
+}
+
+func BenchmarkParsePlaylistInfo(b *testing.B) {
+	lines := playlistInfoAnswer(1000)
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := parser.ParseMultiValue[PlaylistItem](lines); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := parser.ParseMultiValue[reflectPlaylistItem](lines); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseStatus(b *testing.B) {
+	lines := []string{"volume: 50", "repeat: 1", "random: 0", "single: 0", "consume: 1", "playlist: 12",
+		"playlistlength: 3", "xfade: 5", "state: play", "song: 1", "songid: 101", "time: 12:245",
+		"elapsed: 12.345", "duration: 245.123", "bitrate: 320", "audio: 44100:16:2"}
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := parser.ParseSingleValue[status](lines); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := parser.ParseSingleValue[reflectStatus](lines); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package mpdapi
+
+import (
+	"iter"
+	"strings"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/anpotashev/mpdgo/internal/parser"
+)
+
+// LineError is returned when an MPD response cannot be parsed because of one of its lines.
+type LineError = parser.LineError
+
+var (
+	// ErrMalformedLine is wrapped by the LineError returned for a line that is not a "key: value" pair.
+	ErrMalformedLine = parser.ErrMalformedLine
+	// ErrUnknownKey is wrapped by the LineError returned in strict mode for a line with an unknown key.
+	ErrUnknownKey = parser.ErrUnknownKey
+)
+
+// DiagnosticLine is a line of an MPD response that was not mapped to any field.
+type DiagnosticLine struct {
+	// Line is the 1-based number of the line in the response.
+	Line int
+	// Text is the line content.
+	Text string
+}
+
+// Diagnostics describes the lines of the response to Command that were not mapped to any field.
+type Diagnostics struct {
+	Command        string
+	UnknownKeys    []DiagnosticLine
+	MalformedLines []DiagnosticLine
+}
+
+// DiagnosticsHandler is called after parsing a response having unknown keys or malformed lines.
+type DiagnosticsHandler func(d Diagnostics)
+
+// WithDiagnosticsHandler sets the handler reporting the unknown keys and malformed lines
+// of the MPD responses. The keys of the playlist items are all known, see PlaylistItem.Other. Malformed lines are skipped instead of failing the request
+// while a handler is set.
+func WithDiagnosticsHandler(h DiagnosticsHandler) Option {
+	return func(o *options) {
+		o.parsing.diagnosticsHandler = h
+	}
+}
+
+// WithStrictParsing makes requests fail with a LineError when the MPD response has
+// an unknown key or a malformed line. It is useful to detect protocol changes.
+// The keys sent by MPD which are not mapped to any field, e.g. the MusicBrainz tags,
+// are not considered unknown. Neither are the keys of the playlist items, which are
+// all collected: the ones of no other field by PlaylistItem.Other.
+func WithStrictParsing() Option {
+	return func(o *options) {
+		o.parsing.strict = true
+	}
+}
+
+// The keys sent by MPD which are not mapped to any field of the type their response is parsed as.
+// They are not reported as unknown, so the diagnostics and strict parsing point at the keys new to the library.
+var (
+	statusUnmappedKeys = []string{"partition", "mixrampdb", "mixrampdelay", "error", "lastloadedplaylist"}
+	songUnmappedKeys   = []string{
+		"Added", "Range", "Name", "Comment", "Disc", "Label", "OriginalDate",
+		"ArtistSort", "AlbumSort", "AlbumArtistSort", "TitleSort", "ComposerSort",
+		"Work", "Movement", "MovementNumber", "ShowMovement", "Location", "Grouping", "Conductor", "Ensemble", "Mood",
+		"MUSICBRAINZ_ARTISTID", "MUSICBRAINZ_ALBUMID", "MUSICBRAINZ_ALBUMARTISTID", "MUSICBRAINZ_TRACKID",
+		"MUSICBRAINZ_RELEASETRACKID", "MUSICBRAINZ_RELEASEGROUPID", "MUSICBRAINZ_WORKID",
+	}
+	outputUnmappedKeys = []string{"plugin", "attribute"}
+)
+
+// unmappedKeys returns the keys sent by MPD which are not mapped to any field of T.
+// PlaylistItem has none: the keys of no other field are collected by PlaylistItem.Other.
+func unmappedKeys[T any]() []string {
+	switch any(new(T)).(type) {
+	case *status:
+		return statusUnmappedKeys
+	case *ParsedItem:
+		return songUnmappedKeys
+	case *Output:
+		return outputUnmappedKeys
+	}
+	return nil
+}
+
+// parsing configures the parsing of the responses of an api, see WithDiagnosticsHandler and WithStrictParsing.
+type parsing struct {
+	diagnosticsHandler DiagnosticsHandler
+	strict             bool
+}
+
+// options returns the parser options ignoring the keys unmapped, and a function reporting
+// the diagnostics collected for cmd, to be called after parsing.
+func (p parsing) options(cmd commands.SingleCommand, unmapped []string) ([]parser.Option, func()) {
+	opts := []parser.Option{parser.IgnoreKeys(unmapped...)}
+	if p.strict {
+		opts = append(opts, parser.Strict())
+	}
+	h := p.diagnosticsHandler
+	if h == nil {
+		return opts, func() {}
+	}
+	report := &parser.Diagnostics{}
+	opts = append(opts, parser.WithDiagnostics(report))
+	return opts, func() {
+		if report.IsEmpty() {
+			return
+		}
+		h(Diagnostics{
+			Command:        strings.TrimSpace(cmd.String()),
+			UnknownKeys:    diagnosticLines(report.UnknownKeys),
+			MalformedLines: diagnosticLines(report.MalformedLines),
+		})
+	}
+}
+
+func diagnosticLines(lines []parser.LineDiagnostic) []DiagnosticLine {
+	result := make([]DiagnosticLine, len(lines))
+	for i, line := range lines {
+		result[i] = DiagnosticLine{Line: line.Line, Text: line.Text}
+	}
+	return result
+}
+
+func parseSingleValue[T any](p parsing, cmd commands.SingleCommand, lines []string) (T, error) {
+	opts, report := p.options(cmd, unmappedKeys[T]())
+	defer report()
+	return parser.ParseSingleValue[T](lines, opts...)
+}
+
+func parseMultiValue[T any](p parsing, cmd commands.SingleCommand, lines []string) ([]T, error) {
+	opts, report := p.options(cmd, unmappedKeys[T]())
+	defer report()
+	return parser.ParseMultiValue[T](lines, opts...)
+}
+
+func parseMultiValueSeq[T any](p parsing, cmd commands.SingleCommand, lines iter.Seq2[string, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		opts, report := p.options(cmd, unmappedKeys[T]())
+		defer report()
+		for item, err := range parser.ParseMultiValueSeq[T](lines, opts...) {
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
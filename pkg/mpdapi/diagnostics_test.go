@@ -0,0 +1,73 @@
+package mpdapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	cmd := commands.NewSingleCommand(commands.OUTPUTS)
+	lines := []string{"outputid: 0", "outputname: out", "outputvolume: 50", "outputenabled: 1", "garbage"}
+
+	t.Run("handler receives unknown keys and malformed lines", func(t *testing.T) {
+		var received []Diagnostics
+		p := newOptions([]Option{WithDiagnosticsHandler(func(d Diagnostics) { received = append(received, d) })}).parsing
+		outputs, err := parseMultiValue[Output](p, cmd, lines)
+		assert.NoError(t, err)
+		assert.Equal(t, []Output{{Id: 0, Name: "out", Enabled: true}}, outputs)
+		assert.Equal(t, []Diagnostics{{
+			Command:        "outputs",
+			UnknownKeys:    []DiagnosticLine{{Line: 3, Text: "outputvolume: 50"}},
+			MalformedLines: []DiagnosticLine{{Line: 5, Text: "garbage"}},
+		}}, received)
+	})
+	t.Run("handler is not called for clean responses", func(t *testing.T) {
+		called := false
+		p := newOptions([]Option{WithDiagnosticsHandler(func(d Diagnostics) { called = true })}).parsing
+		_, err := parseMultiValue[Output](p, cmd, lines[:2])
+		assert.NoError(t, err)
+		assert.False(t, called)
+	})
+	t.Run("strict parsing", func(t *testing.T) {
+		p := newOptions([]Option{WithStrictParsing()}).parsing
+		_, err := parseMultiValue[Output](p, cmd, lines)
+		var lineErr *LineError
+		assert.True(t, errors.As(err, &lineErr))
+		assert.Equal(t, 3, lineErr.Line)
+		assert.True(t, errors.Is(err, ErrUnknownKey))
+
+		// Other apis are not strict.
+		_, err = parseMultiValue[Output](parsing{}, cmd, lines[:4])
+		assert.NoError(t, err)
+	})
+	t.Run("keys sent by MPD but not mapped are not unknown", func(t *testing.T) {
+		p := newOptions([]Option{WithStrictParsing()}).parsing
+		outputs, err := parseMultiValue[Output](p, cmd, []string{"outputid: 0", "outputname: out", "plugin: alsa", "attribute: dop=0", "outputenabled: 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, []Output{{Id: 0, Name: "out", Enabled: true}}, outputs)
+		status, err := parseSingleValue[status](p, commands.NewSingleCommand(commands.STATUS), []string{"volume: 50", "partition: default", "mixrampdb: 0", "lastloadedplaylist: "})
+		assert.NoError(t, err)
+		assert.Equal(t, 50, *status.Volume)
+	})
+	t.Run("unmapped keys are ignored for their response only", func(t *testing.T) {
+		p := newOptions([]Option{WithStrictParsing()}).parsing
+		items, err := parseMultiValue[ParsedItem](p, commands.NewSingleCommand(commands.LSINFO), []string{"file: a.mp3", "Date: 2001", "Disc: 1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "2001", *items[0].Date)
+		_, err = parseSingleValue[status](p, commands.NewSingleCommand(commands.STATUS), []string{"volume: 50", "Disc: 1"})
+		assert.True(t, errors.Is(err, ErrUnknownKey))
+		_, err = parseMultiValue[Output](p, cmd, []string{"outputid: 0", "partition: default"})
+		assert.True(t, errors.Is(err, ErrUnknownKey))
+	})
+	t.Run("playlist items collect the other keys", func(t *testing.T) {
+		called := false
+		p := newOptions([]Option{WithStrictParsing(), WithDiagnosticsHandler(func(d Diagnostics) { called = true })}).parsing
+		items, err := parseMultiValue[PlaylistItem](p, commands.NewSingleCommand(commands.PLAYLIST_INFO), []string{"file: a.mp3", "Pos: 0", "Id: 1", "Unknown: x"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"Unknown": {"x"}}, items[0].Other)
+		assert.False(t, called)
+	})
+}
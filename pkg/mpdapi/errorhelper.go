@@ -1,5 +1,15 @@
 package mpdapi
 
+import (
+	"iter"
+
+	"github.com/anpotashev/mpdgo/internal/mpdrw"
+)
+
+// AckError is an error answer of MPD with its code, e.g. 50 when the requested object does not exist.
+// Use errors.As to get it from the error returned by an api call.
+type AckError = mpdrw.AckError
+
 // Заворачивет ошибку полученную при вызове функции из internal
 func wrapPkgError(err error) error {
 	if err == nil {
@@ -12,3 +22,14 @@ func wrapPkgError(err error) error {
 func wrapPkgErrorIgnoringAnswer(_ []string, err error) error {
 	return wrapPkgError(err)
 }
+
+// wrapPkgErrorSeq wraps the error yielded by an iterator built on internal functions.
+func wrapPkgErrorSeq[T any](seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for item, err := range seq {
+			if !yield(item, wrapPkgError(err)) {
+				return
+			}
+		}
+	}
+}
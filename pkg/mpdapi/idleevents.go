@@ -0,0 +1,62 @@
+package mpdapi
+
+import (
+	"slices"
+
+	"github.com/anpotashev/mpdgo/internal/mpdclient"
+)
+
+// cacheEvents are the events the cache of NewMpdApi is cleared on, so they are always received with the cache.
+var cacheEvents = []MpdEventType{ON_DATABASE_CHANGED, ON_PLAYLIST_CHANGED}
+
+// WithIdleEvents makes the api receive only the events of the given types from the MPD server,
+// e.g. an application showing the player state only doesn't wake up for every database or sticker change:
+//
+//	mpdapi.WithIdleEvents(mpdapi.ON_PLAYER_CHANGED, mpdapi.ON_MIXER_CHANGED)
+//
+// ON_CONNECT and ON_DISCONNECT are always received, with no other type all events are received.
+// With the cache, ON_DATABASE_CHANGED and ON_PLAYLIST_CHANGED are received as well.
+// SubscribeTreeDiffs needs ON_DATABASE_CHANGED. See also MpdApi.SetIdleEvents.
+func WithIdleEvents(events ...MpdEventType) Option {
+	return func(o *options) {
+		o.idleEvents = events
+	}
+}
+
+// SetIdleEvents replaces the types of the events received from the MPD server, see WithIdleEvents.
+// The wait for the events in progress is interrupted and started again with the new types.
+func (api *Impl) SetIdleEvents(events ...MpdEventType) (err error) {
+	api, end := api.traced("SetIdleEvents")
+	defer func() { end(err) }()
+	return wrapPkgError(api.mpdClient.SetIdleSubsystems(api.requestContext, idleSubsystems(events, api.requiredEvents)...))
+}
+
+// idleSubsystems returns the idle subsystems of events and required. There are none if events
+// has no event of a subsystem, so all of them are waited for.
+func idleSubsystems(events, required []MpdEventType) []string {
+	var result []string
+	for _, event := range events {
+		if subsystem, ok := subsystemOf(event); ok && !slices.Contains(result, subsystem) {
+			result = append(result, subsystem)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	for _, event := range required {
+		if subsystem, ok := subsystemOf(event); ok && !slices.Contains(result, subsystem) {
+			result = append(result, subsystem)
+		}
+	}
+	return result
+}
+
+// subsystemOf returns the idle subsystem reporting the events of type event.
+func subsystemOf(event MpdEventType) (string, bool) {
+	for name, eventType := range eventsMap {
+		if eventType == event && name != mpdclient.OnConnect && name != mpdclient.OnDisconnect {
+			return name, true
+		}
+	}
+	return "", false
+}
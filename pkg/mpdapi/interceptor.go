@@ -0,0 +1,83 @@
+package mpdapi
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+	"github.com/anpotashev/mpdgo/internal/mpdclient"
+)
+
+// Command is an MPD command with its unquoted arguments.
+type Command struct {
+	Name string
+	Args []string
+}
+
+// String returns the command as sent to MPD, without the line break.
+func (c Command) String() string {
+	return strings.TrimSuffix(commands.NewCustomCommand(c.Name, c.Args...).String(), "\n")
+}
+
+// Invoker sends the commands to MPD and passes the lines of the answer to yield.
+// The commands of a command list are sent as a command list, which has no answer lines.
+type Invoker func(ctx context.Context, cmds []Command, yield func(line string) bool) error
+
+// Interceptor is called instead of sending the commands of an api call, which are a single command,
+// or the commands of a command list (e.g. for Add). It proceeds by calling next, possibly with other
+// commands, several times (e.g. to retry) or not at all (e.g. to reject a command). It may also
+// change the answer lines passed to yield. ctx is the request context of the api.
+// For a single command next takes a single command too, since a command list has no answer:
+// several ones fail with an error.
+//
+// For example, an allow-list:
+//
+//	func(ctx context.Context, cmds []mpdapi.Command, yield func(string) bool, next mpdapi.Invoker) error {
+//		for _, cmd := range cmds {
+//			if !allowed[cmd.Name] {
+//				return fmt.Errorf("command %s is not allowed", cmd.Name)
+//			}
+//		}
+//		return next(ctx, cmds, yield)
+//	}
+type Interceptor func(ctx context.Context, cmds []Command, yield func(line string) bool, next Invoker) error
+
+// WithInterceptors adds interceptors around sending the commands. The first one is the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *options) {
+		for _, interceptor := range interceptors {
+			o.clientOptions = append(o.clientOptions, mpdclient.WithInterceptors(clientInterceptor(interceptor)))
+		}
+	}
+}
+
+func clientInterceptor(interceptor Interceptor) mpdclient.Interceptor {
+	return func(ctx context.Context, cmds []commands.SingleCommand, yield func(line string) bool, next mpdclient.Invoker) error {
+		return interceptor(ctx, toCommands(cmds), yield, func(ctx context.Context, public []Command, yield func(line string) bool) error {
+			return next(ctx, fromCommands(public, cmds), yield)
+		})
+	}
+}
+
+func toCommands(cmds []commands.SingleCommand) []Command {
+	result := make([]Command, len(cmds))
+	for i, cmd := range cmds {
+		result[i] = Command{Name: cmd.Name(), Args: cmd.Args()}
+	}
+	return result
+}
+
+// fromCommands converts the commands back, keeping the original ones that were not changed,
+// so that they are sent exactly as without interceptors.
+func fromCommands(public []Command, original []commands.SingleCommand) []commands.SingleCommand {
+	result := make([]commands.SingleCommand, len(public))
+	for i, cmd := range public {
+		if i < len(original) && original[i].Name() == cmd.Name && slices.Equal(original[i].Args(), cmd.Args) {
+			result[i] = original[i]
+			continue
+		}
+		result[i] = commands.NewCustomCommand(cmd.Name, cmd.Args...)
+	}
+	return result
+}
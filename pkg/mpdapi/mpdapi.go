@@ -1,13 +1,18 @@
+//go:generate go run ../../internal/cmd/mpdgen -output decoders_gen.go
+
 package mpdapi
 
 import (
 	"context"
+	"iter"
 	"log/slog"
 	"time"
 
 	"github.com/anpotashev/go-observer/pkg/observer"
 	"github.com/anpotashev/mpdgo/internal/logger"
 	"github.com/anpotashev/mpdgo/internal/mpdclient"
+	"github.com/anpotashev/mpdgo/internal/notifier"
+	"github.com/anpotashev/mpdgo/pkg/tracing"
 )
 
 type MpdApi interface {
@@ -17,10 +22,15 @@ type MpdApi interface {
 	Settings
 	Outputs
 	Tree
+	TreeDiffs
+	StateEvents
 	observer.Subscriber[MpdEventType]
+	SubscribeEvents(opts ...SubscriptionOption) *Subscription
 	Connect() error
 	Disconnect() error
 	IsConnected() bool
+	PoolStats() (PoolStats, error)
+	SetIdleEvents(events ...MpdEventType) error
 	WithRequestContext(ctx context.Context) MpdApi
 }
 
@@ -29,11 +39,27 @@ type Impl struct {
 	observer.Observer[MpdEventType]
 	ctx            context.Context
 	requestContext context.Context
+	treeDiffs      *treeDiffFeed
+	subscriptions  *subscriptionHub
+	stateEvents    *stateFeed
+	// tracer starts the spans of the api calls, nil if tracing is off.
+	tracer tracing.Tracer
+	// requiredEvents are received whichever events are set with SetIdleEvents.
+	requiredEvents []MpdEventType
+	parsing        parsing
 }
 
-func NewMpdApi(ctx context.Context, host string, port uint16, password string, useCache bool, maxBatchCommandLength uint16, poolSize uint8, pingPeriod, pingTimeout time.Duration) (MpdApi, error) {
-	mpdClient := mpdclient.NewMpdClientImpl(ctx, host, port, password, maxBatchCommandLength, poolSize, pingPeriod, pingTimeout)
-	result := &Impl{mpdClient: mpdClient, ctx: ctx, Observer: observer.New[MpdEventType](), requestContext: context.Background()}
+func NewMpdApi(ctx context.Context, host string, port uint16, password string, useCache bool, maxBatchCommandLength uint16, poolSize uint8, pingPeriod, pingTimeout time.Duration, opts ...Option) (MpdApi, error) {
+	o := newOptions(opts)
+	var requiredEvents []MpdEventType
+	if useCache {
+		requiredEvents = cacheEvents
+	}
+	if subsystems := idleSubsystems(o.idleEvents, requiredEvents); len(subsystems) > 0 {
+		o.clientOptions = append(o.clientOptions, mpdclient.WithIdleSubsystems(subsystems...))
+	}
+	mpdClient := mpdclient.NewMpdClientImpl(ctx, host, port, password, maxBatchCommandLength, poolSize, pingPeriod, pingTimeout, o.clientOptions...)
+	result := &Impl{mpdClient: mpdClient, ctx: ctx, Observer: notifier.New[MpdEventType](), requestContext: context.Background(), treeDiffs: newTreeDiffFeed(), subscriptions: newSubscriptionHub(), stateEvents: newStateFeed(), tracer: o.tracer, requiredEvents: requiredEvents, parsing: o.parsing}
 	result.initObserver()
 	if useCache {
 		return newWithCache(result), nil
@@ -46,19 +72,65 @@ func SetLogger(l *slog.Logger) {
 }
 
 func (api *Impl) WithRequestContext(ctx context.Context) MpdApi {
-	return &Impl{
-		mpdClient:      api.mpdClient,
-		Observer:       api.Observer,
-		ctx:            api.ctx,
-		requestContext: ctx,
+	return api.withRequestContext(ctx)
+}
+
+func (api *Impl) withRequestContext(ctx context.Context) *Impl {
+	result := *api
+	result.requestContext = ctx
+	return &result
+}
+
+// root returns the api sending the commands with the background context. It is used by
+// the watchers shared by all the copies created with WithRequestContext, which outlive
+// the request starting them.
+func (api *Impl) root() *Impl {
+	return api.withRequestContext(context.Background())
+}
+
+// traced starts the span of the api call name. It returns the api sending the commands
+// within the span and the function ending the span with the result of the call.
+func (api *Impl) traced(name string) (*Impl, func(err error)) {
+	if api.tracer == nil {
+		return api, func(error) {}
+	}
+	ctx, span := api.tracer.Start(tracing.ContextWithTracer(api.requestContext, api.tracer), "mpdapi."+name)
+	traced := *api
+	traced.requestContext = ctx
+	return &traced, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
+
+// tracedSeq traces the iteration of the sequence returned by seq as the api call name.
+func tracedSeq[T any](api *Impl, name string, seq func(api *Impl) iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		api, end := api.traced(name)
+		var err error
+		defer func() { end(err) }()
+		for item, itemErr := range seq(api) {
+			if itemErr != nil {
+				err = itemErr
+			}
+			if !yield(item, itemErr) {
+				return
+			}
+		}
 	}
 }
 
-func (api *Impl) Connect() error {
+func (api *Impl) Connect() (err error) {
+	api, end := api.traced("Connect")
+	defer func() { end(err) }()
 	return wrapPkgError(api.mpdClient.Connect(api.requestContext))
 }
 
-func (api *Impl) Disconnect() error {
+func (api *Impl) Disconnect() (err error) {
+	api, end := api.traced("Disconnect")
+	defer func() { end(err) }()
 	return wrapPkgError(api.mpdClient.Disconnect(api.requestContext))
 }
 
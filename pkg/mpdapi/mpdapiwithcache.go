@@ -2,6 +2,7 @@ package mpdapi
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -11,6 +12,8 @@ const (
 	treeCN     = "tree"
 	playlistCN = "playlist"
 	statusCN   = "status"
+	// lsinfoCN is a prefix: lsinfo answers are cached per path as lsinfoCN + path.
+	lsinfoCN = "lsinfo:"
 )
 
 var clearCacheByEventMap = map[MpdEventType][]string{
@@ -30,6 +33,11 @@ var clearCacheByEventMap = map[MpdEventType][]string{
 	ON_MESSAGE_CHANGED:         {},
 }
 
+var clearCachePrefixesByEventMap = map[MpdEventType][]string{
+	ON_DISCONNECT:       {lsinfoCN},
+	ON_DATABASE_CHANGED: {lsinfoCN},
+}
+
 type ImplWithCache struct {
 	MpdApi
 	cache *cache.Cache
@@ -45,6 +53,9 @@ func newWithCache(api *Impl) MpdApi {
 					c.Delete(cacheName)
 				}
 			}
+			for _, prefix := range clearCachePrefixesByEventMap[event] {
+				deleteByPrefix(c, prefix)
+			}
 			//switch event {
 			//case ON_DISCONNECT:
 			//	onDisconnect(c)
@@ -77,3 +88,29 @@ func (api *ImplWithCache) Tree() (*DirectoryItem, error) {
 	api.cache.Set(treeCN, result, cache.NoExpiration)
 	return result, err
 }
+
+func (api *ImplWithCache) LsInfo(path string) ([]TreeItem, error) {
+	key := lsinfoCN + path
+	value, found := api.cache.Get(key)
+	if found {
+		return cloneTreeItems(value.([]TreeItem), api), nil
+	}
+	result, err := api.MpdApi.LsInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	api.cache.Set(key, result, cache.NoExpiration)
+	return cloneTreeItems(result, api), nil
+}
+
+func (api *ImplWithCache) Browse(path string) (*DirectoryItem, error) {
+	return browse(api, path)
+}
+
+func deleteByPrefix(c *cache.Cache, prefix string) {
+	for key := range c.Items() {
+		if strings.HasPrefix(key, prefix) {
+			c.Delete(key)
+		}
+	}
+}
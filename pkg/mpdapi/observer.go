@@ -43,6 +43,22 @@ var eventsMap = map[string]MpdEventType{
 	"message":              ON_MESSAGE_CHANGED,
 }
 
+// String returns the name of the event type: "connect", "disconnect", or the name of the idle subsystem, e.g. "player".
+func (t MpdEventType) String() string {
+	switch t {
+	case ON_CONNECT:
+		return "connect"
+	case ON_DISCONNECT:
+		return "disconnect"
+	}
+	for name, eventType := range eventsMap {
+		if eventType == t {
+			return name
+		}
+	}
+	return "unknown"
+}
+
 //func (api *Impl) Subscribe(timeout time.Duration) chan MpdEventType {
 //	return api.observer.Subscribe(timeout)
 //}
@@ -63,6 +79,7 @@ func (api *Impl) initObserver() {
 				if eventType != UNKNOWN {
 					logger.Info("Notifying about event", "eventType", eventType)
 					api.Notify(eventType)
+					api.subscriptions.publish(eventType)
 				}
 			case <-api.ctx.Done():
 				return
@@ -0,0 +1,106 @@
+package mpdapi
+
+import (
+	"io"
+	"net"
+
+	"github.com/anpotashev/mpdgo/internal/mpdclient"
+	"github.com/anpotashev/mpdgo/internal/mpdrw"
+	"github.com/anpotashev/mpdgo/internal/mpdrwpool"
+	"github.com/anpotashev/mpdgo/pkg/metrics"
+	"github.com/anpotashev/mpdgo/pkg/tracing"
+)
+
+// Option configures an MpdApi created with NewMpdApi.
+type Option func(*options)
+
+type options struct {
+	clientOptions []mpdclient.Option
+	tracer        tracing.Tracer
+	idleEvents    []MpdEventType
+	parsing       parsing
+}
+
+// WithDialer makes the api open its connections with dial instead of dialing host and port,
+// e.g. to connect through a unix socket or to a fake server (see package mpdtest).
+func WithDialer(dial func() (net.Conn, error)) Option {
+	return func(o *options) {
+		o.clientOptions = append(o.clientOptions, mpdclient.WithDialer(dial))
+	}
+}
+
+// WithRecorder writes every command and raw response of all connections, with timing, to w.
+// The recording is a JSON object per line:
+//
+//	{"session":1,"t":1377000,"dir":"send","line":"status"}
+//
+// session numbers the connections in dial order, t is the time since the api was created in nanoseconds,
+// dir is one of "dial", "send", "recv", "eof" (the connection was lost) and "close", and line is the line
+// without the line break, or the error for "dial" and "eof". The password is not recorded.
+// The recording can be served back with mpdtest.NewReplayer.
+func WithRecorder(w io.Writer) Option {
+	return func(o *options) {
+		o.clientOptions = append(o.clientOptions, mpdclient.WithRecorder(mpdrw.NewRecorder(w)))
+	}
+}
+
+// WithMetrics reports per-command counts and latencies, the pool wait time and usage,
+// the reconnections and the idle events to m, e.g. a prommetrics.Metrics.
+func WithMetrics(m metrics.Metrics) Option {
+	return func(o *options) {
+		o.clientOptions = append(o.clientOptions, mpdclient.WithMetrics(m))
+	}
+}
+
+// WithTracer starts a span with tracer for every api call, with child spans for the wait
+// for a pool connection and for writing the commands and reading the answers, see package tracing.
+func WithTracer(tracer tracing.Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// RetryPolicy defines how the commands only reading the state of the MPD server (status, playlistinfo,
+// outputs, listallinfo, ping, ...) are retried on a new connection after the connection was lost.
+// Commands changing the state, like add or delete, are never retried, as they may have been executed.
+// A streamed answer (e.g. ListAllInfoSeq) is only retried if none of it was received.
+type RetryPolicy = mpdrwpool.RetryPolicy
+
+// DefaultRetryPolicy retries twice, after 50ms and 100ms.
+var DefaultRetryPolicy = mpdrwpool.DefaultRetryPolicy
+
+// WithRetryPolicy replaces DefaultRetryPolicy, RetryPolicy{} disables retrying.
+// Either way a lost connection is replaced with a new one, and the api is only disconnected
+// if the MPD server can't be reached.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.clientOptions = append(o.clientOptions, mpdclient.WithRetryPolicy(policy))
+	}
+}
+
+// PoolSizing defines how many connections are kept open, besides the one watching the idle events.
+//
+// MinSize connections are opened on Connect and kept open. Up to MaxSize are opened when all
+// of them are in use; if a new one can't be opened, e.g. as the max_connections of the MPD server
+// are reached, the call waits until a connection is free. IdleTimeout closes the connections
+// above MinSize unused for that time, MaxLifetime closes the connections open for that time.
+// Zero durations keep the connections open. The connections are checked every ping period.
+type PoolSizing = mpdrwpool.Sizing
+
+// WithPoolSizing replaces the fixed poolSize of NewMpdApi, e.g. to share the max_connections
+// of the MPD server between several applications:
+//
+//	mpdapi.WithPoolSizing(mpdapi.PoolSizing{MinSize: 0, MaxSize: 4, IdleTimeout: time.Minute})
+func WithPoolSizing(sizing PoolSizing) Option {
+	return func(o *options) {
+		o.clientOptions = append(o.clientOptions, mpdclient.WithSizing(sizing))
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
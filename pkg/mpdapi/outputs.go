@@ -2,7 +2,6 @@ package mpdapi
 
 import (
 	"github.com/anpotashev/mpdgo/internal/commands"
-	"github.com/anpotashev/mpdgo/internal/parser"
 )
 
 type Outputs interface {
@@ -17,23 +16,29 @@ type Output struct {
 	Enabled bool   `mpd_prefix:"outputenabled"`
 }
 
-func (api *Impl) EnableOutput(id int) error {
+func (api *Impl) EnableOutput(id int) (err error) {
+	api, end := api.traced("EnableOutput")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.ENABLE_OUTPUT).AddParams(id)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) DisableOutput(id int) error {
+func (api *Impl) DisableOutput(id int) (err error) {
+	api, end := api.traced("DisableOutput")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.DISABLE_OUTPUT).AddParams(id)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) ListOutputs() ([]Output, error) {
+func (api *Impl) ListOutputs() (_ []Output, err error) {
+	api, end := api.traced("ListOutputs")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.OUTPUTS)
 	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
 	if err != nil {
 		return nil, wrapPkgError(err)
 	}
-	result, err := parser.ParseMultiValue[Output](list)
+	result, err := parseMultiValue[Output](api.parsing, cmd, list)
 	if err != nil {
 		return nil, wrapPkgError(err)
 	}
@@ -15,42 +15,58 @@ type Player interface {
 	Seek(songPos, seekPos int) error
 }
 
-func (api *Impl) Play() error {
+func (api *Impl) Play() (err error) {
+	api, end := api.traced("Play")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.PLAY)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Pause() error {
+func (api *Impl) Pause() (err error) {
+	api, end := api.traced("Pause")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.PAUSE)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Stop() error {
+func (api *Impl) Stop() (err error) {
+	api, end := api.traced("Stop")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.STOP)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Previous() error {
+func (api *Impl) Previous() (err error) {
+	api, end := api.traced("Previous")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.PREV)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Next() error {
+func (api *Impl) Next() (err error) {
+	api, end := api.traced("Next")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.NEXT)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) PlayId(id int) error {
+func (api *Impl) PlayId(id int) (err error) {
+	api, end := api.traced("PlayId")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.PLAY_ID).AddParams(id)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) PlayPos(pos int) error {
+func (api *Impl) PlayPos(pos int) (err error) {
+	api, end := api.traced("PlayPos")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.PLAY).AddParams(pos)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Seek(songPos, seekPos int) error {
+func (api *Impl) Seek(songPos, seekPos int) (err error) {
+	api, end := api.traced("Seek")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.SEEK).AddParams(songPos).AddParams(seekPos)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
@@ -0,0 +1,21 @@
+package mpdapi
+
+import "github.com/anpotashev/mpdgo/internal/mpdrwpool"
+
+// PoolStats is a snapshot of the connections of the api: the counts of the open and busy ones,
+// the percentiles of the time the calls waited for a connection, the age, last use and last error
+// of every connection and the state of the connection watching the idle events.
+type PoolStats = mpdrwpool.Stats
+
+// ConnectionStats is the state of a connection in PoolStats.
+type ConnectionStats = mpdrwpool.ConnectionStats
+
+// IdleWatcherStats is the state of the connection watching the idle events in PoolStats.
+type IdleWatcherStats = mpdrwpool.IdleWatcherStats
+
+// PoolStats returns a snapshot of the connections, e.g. for a diagnostics page.
+// It does not send any command.
+func (api *Impl) PoolStats() (PoolStats, error) {
+	stats, err := api.mpdClient.Stats(api.requestContext)
+	return stats, wrapPkgError(err)
+}
@@ -3,9 +3,9 @@ package mpdapi
 import (
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/anpotashev/mpdgo/internal/commands"
-	"github.com/anpotashev/mpdgo/internal/parser"
 )
 
 type Settings interface {
@@ -21,22 +21,25 @@ type SongTime struct {
 	Full    int
 }
 type status struct {
-	Volume         *int    `mpd_prefix:"volume"`
-	Repeat         *bool   `mpd_prefix:"repeat"`
-	Random         *bool   `mpd_prefix:"random"`
-	Single         *bool   `mpd_prefix:"single"`
-	Consume        *bool   `mpd_prefix:"consume"`
-	Playlist       *string `mpd_prefix:"playlist"`
-	PlaylistLength *int    `mpd_prefix:"playlistlength"`
-	Xfade          *int    `mpd_prefix:"xfade"`
-	State          *string `mpd_prefix:"state"`
-	Song           *int    `mpd_prefix:"song"`
-	SongId         *int    `mpd_prefix:"songid"`
-	Time           *string `mpd_prefix:"time"`
-	Bitrate        *int    `mpd_prefix:"bitrate"`
-	Audio          *string `mpd_prefix:"audio"`
-	NextSong       *int    `mpd_prefix:"nextsong"`
-	NextSongId     *int    `mpd_prefix:"nextsongid"`
+	Volume         *int           `mpd_prefix:"volume"`
+	Repeat         *bool          `mpd_prefix:"repeat"`
+	Random         *bool          `mpd_prefix:"random"`
+	Single         *bool          `mpd_prefix:"single"`
+	Consume        *bool          `mpd_prefix:"consume"`
+	Playlist       *string        `mpd_prefix:"playlist"`
+	PlaylistLength *int           `mpd_prefix:"playlistlength"`
+	Xfade          *int           `mpd_prefix:"xfade"`
+	State          *string        `mpd_prefix:"state"`
+	Song           *int           `mpd_prefix:"song"`
+	SongId         *int           `mpd_prefix:"songid"`
+	Time           *string        `mpd_prefix:"time"`
+	Elapsed        *time.Duration `mpd_prefix:"elapsed"`
+	Duration       *time.Duration `mpd_prefix:"duration"`
+	Bitrate        *int           `mpd_prefix:"bitrate"`
+	Audio          *string        `mpd_prefix:"audio"`
+	NextSong       *int           `mpd_prefix:"nextsong"`
+	NextSongId     *int           `mpd_prefix:"nextsongid"`
+	UpdatingDb     *int           `mpd_prefix:"updating_db"`
 }
 
 type Status struct {
@@ -52,38 +55,51 @@ type Status struct {
 	Song           *int
 	SongId         *int
 	Time           *SongTime
+	Elapsed        *time.Duration
+	Duration       *time.Duration
 	Bitrate        *int
 	Audio          *string
 	NextSong       *int
 	NextSongId     *int
+	UpdatingDb     *int
 }
 
-func (api *Impl) Random(value bool) error {
+func (api *Impl) Random(value bool) (err error) {
+	api, end := api.traced("Random")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.RANDOM).AddParams(value)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Repeat(value bool) error {
+func (api *Impl) Repeat(value bool) (err error) {
+	api, end := api.traced("Repeat")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.REPEAT).AddParams(value)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Single(value bool) error {
+func (api *Impl) Single(value bool) (err error) {
+	api, end := api.traced("Single")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.SINGLE).AddParams(value)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
-func (api *Impl) Consume(value bool) error {
+func (api *Impl) Consume(value bool) (err error) {
+	api, end := api.traced("Consume")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.CONSUME).AddParams(value)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) Status() (Status, error) {
+func (api *Impl) Status() (_ Status, err error) {
+	api, end := api.traced("Status")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.STATUS)
 	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
 	if err != nil {
 		return Status{}, wrapPkgError(err)
 	}
-	status, err := parser.ParseSingleValue[status](list)
+	status, err := parseSingleValue[status](api.parsing, cmd, list)
 	if err != nil {
 		return Status{}, wrapPkgError(err)
 	}
@@ -110,10 +126,13 @@ func (api *Impl) Status() (Status, error) {
 		Song:           status.Song,
 		SongId:         status.SongId,
 		Time:           songTime,
+		Elapsed:        status.Elapsed,
+		Duration:       status.Duration,
 		Bitrate:        status.Bitrate,
 		Audio:          status.Audio,
 		NextSong:       status.NextSong,
 		NextSongId:     status.NextSongId,
+		UpdatingDb:     status.UpdatingDb,
 	}
 	return result, nil
 }
@@ -0,0 +1,261 @@
+package mpdapi
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/anpotashev/mpdgo/internal/notifier"
+)
+
+type StateEvents interface {
+	// SubscribeStateEvents returns a channel receiving the changes of the player, the mixer, the options,
+	// the playlist and the outputs with the state before and after the change.
+	//
+	// The state is fetched once per change for all the subscribers, while there are any. The first
+	// subscription loads the state which the next change is compared with.
+	SubscribeStateEvents(timeout time.Duration) chan StateEvent
+	UnsubscribeStateEvents(ch chan StateEvent)
+}
+
+// StateEvent is a change of the state of the MPD server.
+//
+// Type is one of ON_PLAYER_CHANGED, ON_MIXER_CHANGED, ON_OPTIONS_CHANGED, ON_PLAYLIST_CHANGED
+// and ON_OUTPUT_CHANGED. After a reconnection, an event is published for every type with changes.
+type StateEvent struct {
+	Type MpdEventType
+	// Before and After are the statuses before and after the change, not set for ON_OUTPUT_CHANGED.
+	// Before is nil if the status was not loaded before.
+	Before, After *Status
+	// OutputsBefore and OutputsAfter are the outputs before and after the change of ON_OUTPUT_CHANGED.
+	OutputsBefore, OutputsAfter []Output
+	// Changes are the changed values of the type, e.g. volume 30 -> 45 for ON_MIXER_CHANGED.
+	// They are empty if the state before the change is unknown.
+	Changes []StateChange
+}
+
+// StateChange is a changed value of a StateEvent.
+//
+// Field is the name of the status field (e.g. "volume", "state", "random", "playlist"), or
+// "output:" followed by the output name for ON_OUTPUT_CHANGED. From and To are the values
+// before and after the change, e.g. "play" and "pause", "enabled" and "disabled" for an output.
+// A value which is not set is "".
+type StateChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Change returns the change of field, if it changed.
+func (e StateEvent) Change(field string) (StateChange, bool) {
+	for _, change := range e.Changes {
+		if change.Field == field {
+			return change, true
+		}
+	}
+	return StateChange{}, false
+}
+
+// stateField is a status field reported by the StateEvent of a type.
+type stateField struct {
+	name  string
+	value func(s *Status) string
+}
+
+// statusGroup are the status fields reported by the StateEvent of eventType.
+type statusGroup struct {
+	eventType MpdEventType
+	fields    []stateField
+}
+
+// statusGroups are the event types with the status as their state.
+var statusGroups = []statusGroup{
+	{ON_PLAYER_CHANGED, []stateField{
+		{"state", func(s *Status) string { return formatValue(s.State) }},
+		{"song", func(s *Status) string { return formatValue(s.Song) }},
+		{"songid", func(s *Status) string { return formatValue(s.SongId) }},
+		{"elapsed", func(s *Status) string { return formatValue(s.Elapsed) }},
+		{"duration", func(s *Status) string { return formatValue(s.Duration) }},
+		{"nextsong", func(s *Status) string { return formatValue(s.NextSong) }},
+		{"nextsongid", func(s *Status) string { return formatValue(s.NextSongId) }},
+	}},
+	{ON_MIXER_CHANGED, []stateField{
+		{"volume", func(s *Status) string { return formatValue(s.Volume) }},
+	}},
+	{ON_OPTIONS_CHANGED, []stateField{
+		{"repeat", func(s *Status) string { return formatValue(s.Repeat) }},
+		{"random", func(s *Status) string { return formatValue(s.Random) }},
+		{"single", func(s *Status) string { return formatValue(s.Single) }},
+		{"consume", func(s *Status) string { return formatValue(s.Consume) }},
+		{"xfade", func(s *Status) string { return formatValue(s.Xfade) }},
+	}},
+	{ON_PLAYLIST_CHANGED, []stateField{
+		{"playlist", func(s *Status) string { return formatValue(s.Playlist) }},
+		{"playlistlength", func(s *Status) string { return formatValue(s.PlaylistLength) }},
+	}},
+}
+
+func formatValue[T any](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(*value)
+}
+
+// statusChanges returns the changes of fields from before to after.
+func statusChanges(fields []stateField, before, after *Status) []StateChange {
+	if before == nil {
+		return nil
+	}
+	var result []StateChange
+	for _, field := range fields {
+		if from, to := field.value(before), field.value(after); from != to {
+			result = append(result, StateChange{Field: field.name, From: from, To: to})
+		}
+	}
+	return result
+}
+
+// outputChanges returns the outputs enabled, disabled, added or removed from before to after.
+// before is nil if the outputs were not loaded before.
+func outputChanges(before, after []Output) []StateChange {
+	if before == nil {
+		return nil
+	}
+	state := func(outputs []Output, id int) string {
+		i := slices.IndexFunc(outputs, func(o Output) bool { return o.Id == id })
+		switch {
+		case i < 0:
+			return ""
+		case outputs[i].Enabled:
+			return "enabled"
+		default:
+			return "disabled"
+		}
+	}
+	var result []StateChange
+	for _, output := range after {
+		if from, to := state(before, output.Id), state(after, output.Id); from != to {
+			result = append(result, StateChange{Field: "output:" + output.Name, From: from, To: to})
+		}
+	}
+	for _, output := range before {
+		if state(after, output.Id) == "" {
+			result = append(result, StateChange{Field: "output:" + output.Name, From: state(before, output.Id)})
+		}
+	}
+	return result
+}
+
+// stateFeed is shared by all the copies of Impl created with WithRequestContext.
+// The state is watched while there are subscribers.
+type stateFeed struct {
+	events *notifier.Notifier[StateEvent]
+
+	mu          sync.Mutex
+	subscribers int
+	// subscription drives the watcher, it is closed when the last subscriber leaves.
+	subscription *Subscription
+}
+
+func newStateFeed() *stateFeed {
+	return &stateFeed{events: notifier.New[StateEvent]()}
+}
+
+// serverState is the state the next change is compared with.
+type serverState struct {
+	status *Status
+	// outputs are nil if they were not loaded.
+	outputs []Output
+}
+
+func (api *Impl) SubscribeStateEvents(timeout time.Duration) chan StateEvent {
+	feed := api.stateEvents
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	if feed.subscribers == 0 {
+		root := api.root()
+		feed.subscription = root.SubscribeEvents(WithEventTypes(
+			ON_CONNECT, ON_PLAYER_CHANGED, ON_MIXER_CHANGED, ON_OPTIONS_CHANGED, ON_PLAYLIST_CHANGED, ON_OUTPUT_CHANGED))
+		go root.watchStateEvents(feed.subscription)
+	}
+	feed.subscribers++
+	return feed.events.Subscribe(timeout)
+}
+
+func (api *Impl) UnsubscribeStateEvents(ch chan StateEvent) {
+	feed := api.stateEvents
+	if !feed.events.Remove(ch) {
+		return
+	}
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	feed.subscribers--
+	if feed.subscribers == 0 {
+		feed.subscription.Close()
+		feed.subscription = nil
+	}
+}
+
+// watchStateEvents publishes the state events until the subscription is closed.
+func (api *Impl) watchStateEvents(subscription *Subscription) {
+	// Loading the state as on a connection makes it the state the next change is compared with.
+	state := api.publishStateEvents(EventBatch{Types: []MpdEventType{ON_CONNECT}}, serverState{})
+	for batch := range subscription.Events() {
+		state = api.publishStateEvents(batch, state)
+	}
+}
+
+// publishStateEvents fetches the state changed by the events of batch and publishes the changes since previous.
+// On ON_CONNECT, the whole state is fetched and the types with changes are published.
+// It returns the state the next change is compared with.
+func (api *Impl) publishStateEvents(batch EventBatch, previous serverState) serverState {
+	if !api.IsConnected() {
+		return previous
+	}
+	connected := batch.Has(ON_CONNECT)
+	var status *Status
+	if connected || slices.ContainsFunc(statusGroups, func(g statusGroup) bool { return batch.Has(g.eventType) }) {
+		result, err := api.Status()
+		if err != nil {
+			logger.Warn("Error loading the status for the state events", "err", err)
+			return previous
+		}
+		status = &result
+	}
+	var outputs []Output
+	if connected || batch.Has(ON_OUTPUT_CHANGED) {
+		result, err := api.ListOutputs()
+		if err != nil {
+			logger.Warn("Error loading the outputs for the state events", "err", err)
+			return previous
+		}
+		// nil outputs are the outputs not loaded yet.
+		outputs = append([]Output{}, result...)
+	}
+	current := previous
+	if status != nil {
+		current.status = status
+	}
+	if outputs != nil {
+		current.outputs = outputs
+	}
+	for _, g := range statusGroups {
+		if status == nil {
+			break
+		}
+		event := StateEvent{Type: g.eventType, Before: previous.status, After: status, Changes: statusChanges(g.fields, previous.status, status)}
+		if batch.Has(g.eventType) || connected && len(event.Changes) > 0 {
+			api.stateEvents.events.Notify(event)
+		}
+	}
+	if outputs != nil {
+		event := StateEvent{Type: ON_OUTPUT_CHANGED, OutputsBefore: previous.outputs, OutputsAfter: outputs, Changes: outputChanges(previous.outputs, outputs)}
+		if batch.Has(ON_OUTPUT_CHANGED) || connected && len(event.Changes) > 0 {
+			api.stateEvents.events.Notify(event)
+		}
+	}
+	return current
+}
@@ -0,0 +1,38 @@
+package mpdapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputChanges(t *testing.T) {
+	before := []Output{{Name: "a", Id: 0, Enabled: true}, {Name: "b", Id: 1, Enabled: true}}
+	after := []Output{{Name: "a", Id: 0, Enabled: false}, {Name: "c", Id: 2, Enabled: true}}
+	assert.Equal(t, []StateChange{
+		{Field: "output:a", From: "enabled", To: "disabled"},
+		{Field: "output:c", From: "", To: "enabled"},
+		{Field: "output:b", From: "enabled", To: ""},
+	}, outputChanges(before, after))
+	assert.Nil(t, outputChanges(nil, after))
+	assert.Nil(t, outputChanges(after, after))
+}
+
+func TestStatusChanges(t *testing.T) {
+	play, pause := "play", "pause"
+	volume := 30
+	before := &Status{State: &play, Volume: &volume}
+	after := &Status{State: &pause}
+	var player, mixer []stateField
+	for _, g := range statusGroups {
+		switch g.eventType {
+		case ON_PLAYER_CHANGED:
+			player = g.fields
+		case ON_MIXER_CHANGED:
+			mixer = g.fields
+		}
+	}
+	assert.Equal(t, []StateChange{{Field: "state", From: "play", To: "pause"}}, statusChanges(player, before, after))
+	assert.Equal(t, []StateChange{{Field: "volume", From: "30", To: ""}}, statusChanges(mixer, before, after))
+	assert.Nil(t, statusChanges(mixer, nil, after))
+}
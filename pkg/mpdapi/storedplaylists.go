@@ -2,7 +2,6 @@ package mpdapi
 
 import (
 	"github.com/anpotashev/mpdgo/internal/commands"
-	"github.com/anpotashev/mpdgo/internal/parser"
 )
 
 type StoredPlaylists interface {
@@ -12,30 +11,38 @@ type StoredPlaylists interface {
 	SaveCurrentPlaylistAsStored(string) error
 }
 
-func (api *Impl) GetPlaylists() ([]Playlist, error) {
+func (api *Impl) GetPlaylists() (_ []Playlist, err error) {
+	api, end := api.traced("GetPlaylists")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.LISTPLAYLISTS)
 	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
 	if err != nil {
 		return nil, wrapPkgError(err)
 	}
-	playlists, err := parser.ParseMultiValue[Playlist](list)
+	playlists, err := parseMultiValue[Playlist](api.parsing, cmd, list)
 	if err != nil {
 		return nil, err
 	}
 	return playlists, nil
 }
 
-func (api *Impl) DeleteStoredPlaylist(name string) error {
+func (api *Impl) DeleteStoredPlaylist(name string) (err error) {
+	api, end := api.traced("DeleteStoredPlaylist")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.RM).AddParams(name)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) RenameStoredPlaylist(oldName, newName string) error {
+func (api *Impl) RenameStoredPlaylist(oldName, newName string) (err error) {
+	api, end := api.traced("RenameStoredPlaylist")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.RENAME).AddParams(oldName, newName)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
 
-func (api *Impl) SaveCurrentPlaylistAsStored(name string) error {
+func (api *Impl) SaveCurrentPlaylistAsStored(name string) (err error) {
+	api, end := api.traced("SaveCurrentPlaylistAsStored")
+	defer func() { end(err) }()
 	cmd := commands.NewSingleCommand(commands.SAVE).AddParams(name)
 	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
 }
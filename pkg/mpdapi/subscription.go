@@ -0,0 +1,210 @@
+package mpdapi
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EventBatch is the events delivered at once by a Subscription.
+type EventBatch struct {
+	// Types are the distinct types of the events, in the order they first occurred.
+	Types []MpdEventType
+	// Dropped is the number of events merged into an earlier event of the same type of the batch.
+	Dropped int
+}
+
+// Has reports whether the batch contains an event of type eventType.
+func (b EventBatch) Has(eventType MpdEventType) bool {
+	return slices.Contains(b.Types, eventType)
+}
+
+// merge adds the events of other to the batch.
+func (b *EventBatch) merge(other EventBatch) {
+	b.Dropped += other.Dropped
+	for _, eventType := range other.Types {
+		b.add(eventType)
+	}
+}
+
+func (b *EventBatch) add(eventType MpdEventType) {
+	if b.Has(eventType) {
+		b.Dropped++
+		return
+	}
+	b.Types = append(b.Types, eventType)
+}
+
+// SubscriptionOption configures a Subscription created with SubscribeEvents.
+type SubscriptionOption func(*Subscription)
+
+// WithEventTypes makes the subscription receive the events of the given types only.
+func WithEventTypes(eventTypes ...MpdEventType) SubscriptionOption {
+	return func(s *Subscription) {
+		s.eventTypes = eventTypes
+	}
+}
+
+// WithDebounce delays the delivery of an event by window, the events occurring in the meantime
+// are delivered in the same batch. E.g. adding a large directory changes the playlist many times,
+// but is reported once.
+func WithDebounce(window time.Duration) SubscriptionOption {
+	return func(s *Subscription) {
+		s.debounce = window
+	}
+}
+
+// Subscription delivers the events of the api in batches, see SubscribeEvents.
+type Subscription struct {
+	eventTypes []MpdEventType
+	debounce   time.Duration
+	events     chan EventBatch
+	// signal is sent to when an event is added to pending.
+	signal chan struct{}
+	done   chan struct{}
+	close  sync.Once
+	hub    *subscriptionHub
+
+	mu      sync.Mutex
+	pending EventBatch
+	dropped uint64
+}
+
+// SubscribeEvents returns a subscription to the events of the api.
+//
+// Unlike Subscribe, an event is never lost when the consumer is slow: the events occurring
+// until the consumer receives the batch are merged into it, so the latest change of every type
+// is delivered. The subscription is closed with Close or when the ctx of the api is done.
+func (api *Impl) SubscribeEvents(opts ...SubscriptionOption) *Subscription {
+	s := &Subscription{
+		events: make(chan EventBatch),
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		hub:    api.subscriptions,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	api.subscriptions.add(s)
+	go s.run(api.ctx)
+	return s
+}
+
+// Events returns the channel the batches are delivered to. It is closed when the subscription is closed.
+func (s *Subscription) Events() <-chan EventBatch {
+	return s.events
+}
+
+// Dropped returns the number of events merged into an earlier event of the same type, in all batches
+// delivered so far.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops the delivery of the events and closes the Events channel.
+func (s *Subscription) Close() {
+	s.close.Do(func() {
+		s.hub.remove(s)
+		close(s.done)
+	})
+}
+
+// add adds the event to the pending batch unless it is filtered out.
+func (s *Subscription) add(eventType MpdEventType) {
+	if len(s.eventTypes) > 0 && !slices.Contains(s.eventTypes, eventType) {
+		return
+	}
+	s.mu.Lock()
+	s.pending.add(eventType)
+	s.mu.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// take removes and returns the pending batch.
+func (s *Subscription) take() EventBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.pending
+	s.pending = EventBatch{}
+	return batch
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.events)
+	defer s.Close()
+	for {
+		select {
+		case <-s.signal:
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+		if s.debounce > 0 {
+			select {
+			case <-time.After(s.debounce):
+			case <-s.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+		batch := s.take()
+		if len(batch.Types) == 0 {
+			continue
+		}
+		// The events occurring until the consumer is ready are merged into the batch.
+		for sent := false; !sent; {
+			select {
+			case s.events <- batch:
+				sent = true
+			case <-s.signal:
+				batch.merge(s.take())
+			case <-s.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+		s.mu.Lock()
+		s.dropped += uint64(batch.Dropped)
+		s.mu.Unlock()
+	}
+}
+
+// subscriptionHub is shared by all the copies of Impl created with WithRequestContext.
+type subscriptionHub struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+}
+
+func newSubscriptionHub() *subscriptionHub {
+	return &subscriptionHub{subscriptions: make(map[*Subscription]struct{})}
+}
+
+func (h *subscriptionHub) add(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriptions[s] = struct{}{}
+}
+
+func (h *subscriptionHub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscriptions, s)
+}
+
+// publish adds the event to the pending batch of every subscription. It does not block.
+func (h *subscriptionHub) publish(eventType MpdEventType) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscriptions {
+		s.add(eventType)
+	}
+}
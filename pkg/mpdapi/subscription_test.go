@@ -0,0 +1,105 @@
+package mpdapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSubscriptionTestApi(t *testing.T) (*Impl, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Impl{ctx: ctx, subscriptions: newSubscriptionHub()}, cancel
+}
+
+func receiveBatch(t *testing.T, s *Subscription) EventBatch {
+	t.Helper()
+	select {
+	case batch, ok := <-s.Events():
+		require.True(t, ok, "events channel closed")
+		return batch
+	case <-time.After(time.Second):
+		t.Fatal("no batch received")
+		return EventBatch{}
+	}
+}
+
+func assertNoBatch(t *testing.T, s *Subscription) {
+	t.Helper()
+	select {
+	case batch := <-s.Events():
+		t.Errorf("unexpected batch %v", batch)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	t.Run("events are merged while the consumer is busy", func(t *testing.T) {
+		api, _ := newSubscriptionTestApi(t)
+		s := api.SubscribeEvents()
+		defer s.Close()
+		api.subscriptions.publish(ON_PLAYER_CHANGED)
+		api.subscriptions.publish(ON_MIXER_CHANGED)
+		api.subscriptions.publish(ON_PLAYER_CHANGED)
+		time.Sleep(20 * time.Millisecond)
+		batch := receiveBatch(t, s)
+		assert.Equal(t, EventBatch{Types: []MpdEventType{ON_PLAYER_CHANGED, ON_MIXER_CHANGED}, Dropped: 1}, batch)
+		assert.True(t, batch.Has(ON_MIXER_CHANGED))
+		assert.False(t, batch.Has(ON_PLAYLIST_CHANGED))
+		assertNoBatch(t, s)
+		assert.Equal(t, uint64(1), s.Dropped())
+
+		api.subscriptions.publish(ON_PLAYLIST_CHANGED)
+		assert.Equal(t, EventBatch{Types: []MpdEventType{ON_PLAYLIST_CHANGED}}, receiveBatch(t, s))
+	})
+	t.Run("event types filter", func(t *testing.T) {
+		api, _ := newSubscriptionTestApi(t)
+		s := api.SubscribeEvents(WithEventTypes(ON_MIXER_CHANGED, ON_CONNECT))
+		defer s.Close()
+		api.subscriptions.publish(ON_PLAYER_CHANGED)
+		api.subscriptions.publish(ON_MIXER_CHANGED)
+		assert.Equal(t, EventBatch{Types: []MpdEventType{ON_MIXER_CHANGED}}, receiveBatch(t, s))
+		api.subscriptions.publish(ON_PLAYER_CHANGED)
+		assertNoBatch(t, s)
+	})
+	t.Run("debounce", func(t *testing.T) {
+		api, _ := newSubscriptionTestApi(t)
+		s := api.SubscribeEvents(WithDebounce(50 * time.Millisecond))
+		defer s.Close()
+		start := time.Now()
+		for range 5 {
+			api.subscriptions.publish(ON_PLAYLIST_CHANGED)
+			time.Sleep(time.Millisecond)
+		}
+		batch := receiveBatch(t, s)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, EventBatch{Types: []MpdEventType{ON_PLAYLIST_CHANGED}, Dropped: 4}, batch)
+		assertNoBatch(t, s)
+		assert.Equal(t, uint64(4), s.Dropped())
+	})
+	t.Run("close", func(t *testing.T) {
+		api, _ := newSubscriptionTestApi(t)
+		s := api.SubscribeEvents()
+		s.Close()
+		s.Close()
+		_, ok := <-s.Events()
+		assert.False(t, ok)
+		api.subscriptions.publish(ON_PLAYER_CHANGED)
+		assert.Empty(t, api.subscriptions.subscriptions)
+	})
+	t.Run("closed with the api context", func(t *testing.T) {
+		api, cancel := newSubscriptionTestApi(t)
+		s := api.SubscribeEvents()
+		cancel()
+		select {
+		case _, ok := <-s.Events():
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("events channel not closed")
+		}
+		assert.Empty(t, api.subscriptions.subscriptions)
+	})
+}
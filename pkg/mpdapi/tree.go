@@ -1,107 +1,205 @@
 package mpdapi
 
 import (
+	"context"
+	"iter"
 	"strings"
+	"time"
 
 	"github.com/anpotashev/mpdgo/internal/commands"
-	"github.com/anpotashev/mpdgo/internal/parser"
 )
 
 type Tree interface {
+	// Tree returns the whole database as a fully loaded tree (listallinfo).
 	Tree() (*DirectoryItem, error)
-	UpdateDB(path string) error
+	// ListAllInfoSeq returns an iterator over the database entries located under path,
+	// parsed as they are received from MPD. An error is yielded as the last element.
+	// The loop holds a connection, but a connection is always left to the commands sent from
+	// the loop body: the stream opens a connection of its own if it would hold the last one of the pool.
+	ListAllInfoSeq(path string) iter.Seq2[ParsedItem, error]
+	// LsInfo returns the directories, files and playlists located directly in path.
+	// The returned items have no parent.
+	LsInfo(path string) ([]TreeItem, error)
+	// Browse returns the directory at path with its first level loaded.
+	// Subdirectories are not loaded until DirectoryItem.Expand is called.
+	Browse(path string) (*DirectoryItem, error)
+	// UpdateDB starts updating the database at path and returns the update job id.
+	UpdateDB(path string) (int, error)
+	// RescanDB works like UpdateDB, but also rescans unmodified files.
+	RescanDB(path string) (int, error)
+	// WaitForUpdate blocks until the update job finishes or ctx is done.
+	// onProgress, if not nil, is called every time the update state is checked.
+	WaitForUpdate(ctx context.Context, jobId int, onProgress func(UpdateProgress)) error
+}
+
+type directoryLister interface {
+	LsInfo(path string) ([]TreeItem, error)
 }
 
 type TreeItem interface {
-	getParent() *DirectoryItem
-	getName() string
-	isLeaf() bool
+	// GetParent returns the directory containing the item, or nil for a root
+	// or for an item returned by LsInfo.
+	GetParent() *DirectoryItem
+	// GetName returns the last element of the item path.
+	GetName() string
+	// GetPath returns the full path of the item relative to the music directory.
+	GetPath() string
+	// IsLeaf reports whether the item cannot have children.
+	IsLeaf() bool
 }
 
 type DirectoryItem struct {
-	parent   *DirectoryItem
-	Path     string
-	Name     string
-	Children []TreeItem
+	parent       *DirectoryItem
+	lister       directoryLister
+	expanded     bool
+	Path         string
+	Name         string
+	LastModified *time.Time
+	Children     []TreeItem
 }
 
 type FileItem struct {
-	parent      *DirectoryItem
-	Path        string
-	Name        string
-	Time        *string
-	Artist      *string
-	AlbumArtist *string
-	Title       *string
-	Album       *string
-	Track       *string
-	Date        *string
-}
-
-func (d *DirectoryItem) getParent() *DirectoryItem {
+	parent       *DirectoryItem
+	Path         string
+	Name         string
+	LastModified *time.Time
+	Time         *string
+	Artist       []string
+	AlbumArtist  []string
+	Title        *string
+	Album        *string
+	Track        *string
+	Date         *string
+	Genre        []string
+	Composer     []string
+	Performer    []string
+	Format       *string
+	Duration     *time.Duration
+}
+
+// PlaylistFileItem is a playlist file stored in the music directory.
+type PlaylistFileItem struct {
+	parent       *DirectoryItem
+	Path         string
+	Name         string
+	LastModified *time.Time
+}
+
+func (d *DirectoryItem) GetParent() *DirectoryItem {
 	return d.parent
 }
 
-func (d *DirectoryItem) getName() string {
+func (d *DirectoryItem) GetName() string {
 	return d.Name
 }
 
-func (d *DirectoryItem) isLeaf() bool {
+func (d *DirectoryItem) GetPath() string {
+	return d.Path
+}
+
+func (d *DirectoryItem) IsLeaf() bool {
 	return false
 }
 
-func (f *FileItem) getParent() *DirectoryItem {
+func (f *FileItem) GetParent() *DirectoryItem {
 	return f.parent
 }
 
-func (f *FileItem) getName() string {
+func (f *FileItem) GetName() string {
 	return f.Name
 }
 
-func (f *FileItem) isLeaf() bool {
+func (f *FileItem) GetPath() string {
+	return f.Path
+}
+
+func (f *FileItem) IsLeaf() bool {
+	return true
+}
+
+func (p *PlaylistFileItem) GetParent() *DirectoryItem {
+	return p.parent
+}
+
+func (p *PlaylistFileItem) GetName() string {
+	return p.Name
+}
+
+func (p *PlaylistFileItem) GetPath() string {
+	return p.Path
+}
+
+func (p *PlaylistFileItem) IsLeaf() bool {
 	return true
 }
 
 type ParsedItem struct {
-	File        *string `mpd_prefix:"file" is_new_element_prefix:"true"`
-	Directory   *string `mpd_prefix:"directory" is_new_element_prefix:"true"`
-	Time        *string `mpd_prefix:"Time"`
-	Artist      *string `mpd_prefix:"Artist"`
-	AlbumArtist *string `mpd_prefix:"AlbumArtist"`
-	Title       *string `mpd_prefix:"Title"`
-	Album       *string `mpd_prefix:"Album"`
-	Track       *string `mpd_prefix:"Track"`
-	Date        *string `mpd_prefix:"Date"`
-	Genre       *string `mpd_prefix:"Genre"`
-}
-
-func (api *Impl) Tree() (*DirectoryItem, error) {
-	cmd := commands.NewSingleCommand(commands.LISTALLINFO)
-	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
-	if err != nil {
-		return nil, wrapPkgError(err)
-	}
-	mpdParsedItems, err := parser.ParseMultiValue[ParsedItem](list)
-	if err != nil {
-		return nil, err
-	}
+	File         *string        `mpd_prefix:"file" is_new_element_prefix:"true"`
+	Directory    *string        `mpd_prefix:"directory" is_new_element_prefix:"true"`
+	Playlist     *string        `mpd_prefix:"playlist" is_new_element_prefix:"true"`
+	LastModified *time.Time     `mpd_prefix:"Last-Modified"`
+	Time         *string        `mpd_prefix:"Time"`
+	Duration     *time.Duration `mpd_prefix:"duration"`
+	Format       *string        `mpd_prefix:"Format"`
+	Artist       []string       `mpd_prefix:"Artist"`
+	AlbumArtist  []string       `mpd_prefix:"AlbumArtist"`
+	Title        *string        `mpd_prefix:"Title"`
+	Album        *string        `mpd_prefix:"Album"`
+	Track        *string        `mpd_prefix:"Track"`
+	Date         *string        `mpd_prefix:"Date"`
+	Genre        []string       `mpd_prefix:"Genre"`
+	Composer     []string       `mpd_prefix:"Composer"`
+	Performer    []string       `mpd_prefix:"Performer"`
+}
+
+func (api *Impl) ListAllInfoSeq(path string) iter.Seq2[ParsedItem, error] {
+	return tracedSeq(api, "ListAllInfoSeq", func(api *Impl) iter.Seq2[ParsedItem, error] {
+		cmd := commands.NewSingleCommand(commands.LISTALLINFO)
+		if path != "" {
+			cmd = cmd.AddParams(path)
+		}
+		return wrapPkgErrorSeq(parseMultiValueSeq[ParsedItem](api.parsing, cmd, api.mpdClient.SendSingleCommandSeq(api.requestContext, cmd)))
+	})
+}
+
+func (api *Impl) Tree() (_ *DirectoryItem, err error) {
+	api, end := api.traced("Tree")
+	defer func() { end(err) }()
 	rootItem := &DirectoryItem{
 		parent:   nil,
+		expanded: true,
 		Name:     "/",
 		Path:     "",
 		Children: make([]TreeItem, 0),
 	}
 	currentDir := rootItem
-	for _, item := range mpdParsedItems {
-		if item.Directory != nil {
+	for item, err := range api.ListAllInfoSeq("") {
+		if err != nil {
+			return nil, err
+		}
+		if item.Playlist != nil {
+			parentDirItem := findParentDirItem(*item.Playlist, currentDir)
+			name := strings.TrimPrefix(*item.Playlist, parentDirItem.Path)
+			name = strings.TrimPrefix(name, "/")
+			playlistItem := &PlaylistFileItem{
+				parent:       parentDirItem,
+				Name:         name,
+				Path:         *item.Playlist,
+				LastModified: item.LastModified,
+			}
+			parentDirItem.Children = append(parentDirItem.Children, playlistItem)
+			currentDir = parentDirItem
+		} else if item.Directory != nil {
 			parentDirItem := findParentDirItem(*item.Directory, currentDir)
 			name := strings.TrimPrefix(*item.Directory, parentDirItem.Path)
 			name = strings.TrimPrefix(name, "/")
 			dirItem := &DirectoryItem{
-				parent:   parentDirItem,
-				Name:     name,
-				Path:     *item.Directory,
-				Children: make([]TreeItem, 0),
+				parent:       parentDirItem,
+				expanded:     true,
+				Name:         name,
+				Path:         *item.Directory,
+				LastModified: item.LastModified,
+				Children:     make([]TreeItem, 0),
 			}
 			parentDirItem.Children = append(parentDirItem.Children, dirItem)
 			currentDir = dirItem
@@ -110,16 +208,22 @@ func (api *Impl) Tree() (*DirectoryItem, error) {
 			name := strings.TrimPrefix(*item.File, parentDirItem.Path)
 			name = strings.TrimPrefix(name, "/")
 			fileItem := &FileItem{
-				parent:      parentDirItem,
-				Name:        name,
-				Path:        *item.File,
-				Time:        item.Time,
-				Artist:      item.Artist,
-				AlbumArtist: item.AlbumArtist,
-				Title:       item.Title,
-				Album:       item.Album,
-				Track:       item.Track,
-				Date:        item.Date,
+				parent:       parentDirItem,
+				Name:         name,
+				Path:         *item.File,
+				LastModified: item.LastModified,
+				Time:         item.Time,
+				Artist:       item.Artist,
+				AlbumArtist:  item.AlbumArtist,
+				Title:        item.Title,
+				Album:        item.Album,
+				Track:        item.Track,
+				Date:         item.Date,
+				Genre:        item.Genre,
+				Composer:     item.Composer,
+				Performer:    item.Performer,
+				Format:       item.Format,
+				Duration:     item.Duration,
 			}
 			parentDirItem.Children = append(parentDirItem.Children, fileItem)
 			currentDir = parentDirItem
@@ -138,7 +242,168 @@ func findParentDirItem(path string, currentActiveDir *DirectoryItem) *DirectoryI
 	return findParentDirItem(path, currentActiveDir.parent)
 }
 
-func (api *Impl) UpdateDB(path string) error {
-	cmd := commands.NewSingleCommand(commands.UPDATE).AddParams(path)
-	return wrapPkgErrorIgnoringAnswer(api.mpdClient.SendSingleCommand(api.requestContext, cmd))
+func (api *Impl) UpdateDB(path string) (_ int, err error) {
+	api, end := api.traced("UpdateDB")
+	defer func() { end(err) }()
+	return api.startUpdate(commands.UPDATE, path)
+}
+
+func (api *Impl) RescanDB(path string) (_ int, err error) {
+	api, end := api.traced("RescanDB")
+	defer func() { end(err) }()
+	return api.startUpdate(commands.RESCAN, path)
+}
+
+func (api *Impl) LsInfo(path string) (_ []TreeItem, err error) {
+	api, end := api.traced("LsInfo")
+	defer func() { end(err) }()
+	cmd := commands.NewSingleCommand(commands.LSINFO).AddParams(path)
+	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
+	if err != nil {
+		return nil, wrapPkgError(err)
+	}
+	mpdParsedItems, err := parseMultiValue[ParsedItem](api.parsing, cmd, list)
+	if err != nil {
+		return nil, wrapPkgError(err)
+	}
+	result := make([]TreeItem, 0, len(mpdParsedItems))
+	for _, item := range mpdParsedItems {
+		if treeItem := newTreeItem(item, api); treeItem != nil {
+			result = append(result, treeItem)
+		}
+	}
+	return result, nil
+}
+
+func (api *Impl) Browse(path string) (_ *DirectoryItem, err error) {
+	api, end := api.traced("Browse")
+	defer func() { end(err) }()
+	return browse(api, path)
+}
+
+func browse(lister directoryLister, path string) (*DirectoryItem, error) {
+	name := baseName(path)
+	if path == "" {
+		name = "/"
+	}
+	result := &DirectoryItem{
+		lister:   lister,
+		Path:     path,
+		Name:     name,
+		Children: make([]TreeItem, 0),
+	}
+	if err := result.Expand(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// IsExpanded reports whether the children of the directory have been loaded.
+func (d *DirectoryItem) IsExpanded() bool {
+	return d.expanded
+}
+
+// Expand loads the children of a directory obtained with Browse.
+// It does nothing if the children are already loaded.
+func (d *DirectoryItem) Expand() error {
+	if d.expanded || d.lister == nil {
+		return nil
+	}
+	return d.Reload()
+}
+
+// Reload loads the children of a directory obtained with Browse again,
+// replacing the previously loaded ones.
+func (d *DirectoryItem) Reload() error {
+	if d.lister == nil {
+		return nil
+	}
+	items, err := d.lister.LsInfo(d.Path)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		switch v := item.(type) {
+		case *DirectoryItem:
+			v.parent = d
+		case *FileItem:
+			v.parent = d
+		case *PlaylistFileItem:
+			v.parent = d
+		}
+	}
+	d.Children = items
+	d.expanded = true
+	return nil
+}
+
+// newTreeItem converts a single lsinfo entry to a parentless tree item.
+// Directories are created collapsed and are loaded with lister.
+func newTreeItem(item ParsedItem, lister directoryLister) TreeItem {
+	switch {
+	case item.Directory != nil:
+		return &DirectoryItem{
+			lister:       lister,
+			Name:         baseName(*item.Directory),
+			Path:         *item.Directory,
+			LastModified: item.LastModified,
+			Children:     make([]TreeItem, 0),
+		}
+	case item.File != nil:
+		return &FileItem{
+			Name:         baseName(*item.File),
+			Path:         *item.File,
+			LastModified: item.LastModified,
+			Time:         item.Time,
+			Artist:       item.Artist,
+			AlbumArtist:  item.AlbumArtist,
+			Title:        item.Title,
+			Album:        item.Album,
+			Track:        item.Track,
+			Date:         item.Date,
+			Genre:        item.Genre,
+			Composer:     item.Composer,
+			Performer:    item.Performer,
+			Format:       item.Format,
+			Duration:     item.Duration,
+		}
+	case item.Playlist != nil:
+		return &PlaylistFileItem{
+			Name:         baseName(*item.Playlist),
+			Path:         *item.Playlist,
+			LastModified: item.LastModified,
+		}
+	}
+	return nil
+}
+
+// cloneTreeItems returns parentless copies of items, so cached lsinfo answers
+// are never linked into a caller's tree.
+func cloneTreeItems(items []TreeItem, lister directoryLister) []TreeItem {
+	result := make([]TreeItem, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case *DirectoryItem:
+			result = append(result, &DirectoryItem{
+				lister:       lister,
+				Name:         v.Name,
+				Path:         v.Path,
+				LastModified: v.LastModified,
+				Children:     make([]TreeItem, 0),
+			})
+		case *FileItem:
+			c := *v
+			c.parent = nil
+			result = append(result, &c)
+		case *PlaylistFileItem:
+			c := *v
+			c.parent = nil
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+func baseName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
 }
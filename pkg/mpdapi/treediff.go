@@ -0,0 +1,210 @@
+package mpdapi
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/anpotashev/mpdgo/internal/notifier"
+)
+
+type TreeDiffs interface {
+	// SubscribeTreeDiffs returns a channel receiving the changes of the database tree
+	// after every database update. Empty diffs are not published.
+	//
+	// The first subscription loads the tree snapshot which the next update is compared with.
+	// The tree is watched while there are subscribers, the snapshot is dropped when the last one
+	// unsubscribes.
+	SubscribeTreeDiffs(timeout time.Duration) chan TreeDiff
+	UnsubscribeTreeDiffs(ch chan TreeDiff)
+}
+
+// TreeDiff describes changes between two database trees.
+//
+// Removed items belong to the old tree, added and modified items belong to the new one.
+// When a directory is added or removed, all the items of its subtree are reported as well.
+type TreeDiff struct {
+	AddedFiles          []*FileItem
+	RemovedFiles        []*FileItem
+	ModifiedFiles       []*FileItem
+	AddedDirectories    []*DirectoryItem
+	RemovedDirectories  []*DirectoryItem
+	ModifiedDirectories []*DirectoryItem
+}
+
+// IsEmpty reports whether the diff contains no changes.
+func (d TreeDiff) IsEmpty() bool {
+	return len(d.AddedFiles) == 0 &&
+		len(d.RemovedFiles) == 0 &&
+		len(d.ModifiedFiles) == 0 &&
+		len(d.AddedDirectories) == 0 &&
+		len(d.RemovedDirectories) == 0 &&
+		len(d.ModifiedDirectories) == 0
+}
+
+// DiffTrees compares the loaded parts of two trees.
+//
+// Files are considered modified when their Last-Modified time or any of their tags differ,
+// directories when their Last-Modified time differs. A nil tree is treated as an empty one.
+func DiffTrees(oldTree, newTree *DirectoryItem) TreeDiff {
+	var result TreeDiff
+	oldItems := indexTree(oldTree)
+	newItems := indexTree(newTree)
+	for _, item := range treeItems(newTree) {
+		oldItem, found := oldItems[item.GetPath()]
+		switch v := item.(type) {
+		case *DirectoryItem:
+			oldDir, isDir := oldItem.(*DirectoryItem)
+			switch {
+			case !found || !isDir:
+				result.AddedDirectories = append(result.AddedDirectories, v)
+			case !timeEqual(oldDir.LastModified, v.LastModified):
+				result.ModifiedDirectories = append(result.ModifiedDirectories, v)
+			}
+		case *FileItem:
+			oldFile, isFile := oldItem.(*FileItem)
+			switch {
+			case !found || !isFile:
+				result.AddedFiles = append(result.AddedFiles, v)
+			case !fileEqual(oldFile, v):
+				result.ModifiedFiles = append(result.ModifiedFiles, v)
+			}
+		}
+	}
+	for _, item := range treeItems(oldTree) {
+		newItem, found := newItems[item.GetPath()]
+		switch v := item.(type) {
+		case *DirectoryItem:
+			if _, isDir := newItem.(*DirectoryItem); !found || !isDir {
+				result.RemovedDirectories = append(result.RemovedDirectories, v)
+			}
+		case *FileItem:
+			if _, isFile := newItem.(*FileItem); !found || !isFile {
+				result.RemovedFiles = append(result.RemovedFiles, v)
+			}
+		}
+	}
+	return result
+}
+
+// treeItems returns all the items of the tree except its root.
+func treeItems(tree *DirectoryItem) []TreeItem {
+	if tree == nil {
+		return nil
+	}
+	var result []TreeItem
+	for item := range tree.All() {
+		if item != tree {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func indexTree(tree *DirectoryItem) map[string]TreeItem {
+	result := make(map[string]TreeItem)
+	for _, item := range treeItems(tree) {
+		result[item.GetPath()] = item
+	}
+	return result
+}
+
+func fileEqual(a, b *FileItem) bool {
+	if !timeEqual(a.LastModified, b.LastModified) || !stringPtrEqual(a.Time, b.Time) {
+		return false
+	}
+	for _, tag := range []Tag{TagArtist, TagAlbumArtist, TagTitle, TagAlbum, TagTrack, TagDate, TagGenre, TagComposer, TagPerformer} {
+		if !slices.Equal(a.Tag(tag), b.Tag(tag)) {
+			return false
+		}
+	}
+	return true
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func stringPtrEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// treeDiffFeed is shared by all the copies of Impl created with WithRequestContext.
+// The tree is watched while there are subscribers.
+type treeDiffFeed struct {
+	diffs *notifier.Notifier[TreeDiff]
+
+	mu          sync.Mutex
+	subscribers int
+	// subscription drives the watcher, it is closed when the last subscriber leaves.
+	subscription *Subscription
+}
+
+func newTreeDiffFeed() *treeDiffFeed {
+	return &treeDiffFeed{diffs: notifier.New[TreeDiff]()}
+}
+
+func (api *Impl) SubscribeTreeDiffs(timeout time.Duration) chan TreeDiff {
+	feed := api.treeDiffs
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	if feed.subscribers == 0 {
+		root := api.root()
+		feed.subscription = root.SubscribeEvents(WithEventTypes(ON_CONNECT, ON_DATABASE_CHANGED))
+		go root.watchTreeDiffs(feed.subscription)
+	}
+	feed.subscribers++
+	return feed.diffs.Subscribe(timeout)
+}
+
+func (api *Impl) UnsubscribeTreeDiffs(ch chan TreeDiff) {
+	feed := api.treeDiffs
+	if !feed.diffs.Remove(ch) {
+		return
+	}
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	feed.subscribers--
+	if feed.subscribers == 0 {
+		feed.subscription.Close()
+		feed.subscription = nil
+	}
+}
+
+// watchTreeDiffs publishes the diffs until the subscription is closed. The snapshot of the tree
+// is dropped with it.
+func (api *Impl) watchTreeDiffs(subscription *Subscription) {
+	// Loading the tree may take a long time, the events occurring meanwhile are merged
+	// into a single batch by the subscription.
+	snapshot := api.publishTreeDiff(nil)
+	for range subscription.Events() {
+		snapshot = api.publishTreeDiff(snapshot)
+	}
+}
+
+// publishTreeDiff loads the tree and publishes its changes since previous.
+// It returns the tree the next update is compared with.
+func (api *Impl) publishTreeDiff(previous *DirectoryItem) *DirectoryItem {
+	if !api.IsConnected() {
+		return previous
+	}
+	tree, err := api.Tree()
+	if err != nil {
+		logger.Warn("Error loading the tree for diffing", "err", err)
+		return previous
+	}
+	if previous == nil {
+		return tree
+	}
+	if diff := DiffTrees(previous, tree); !diff.IsEmpty() {
+		api.treeDiffs.diffs.Notify(diff)
+	}
+	return tree
+}
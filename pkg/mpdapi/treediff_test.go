@@ -0,0 +1,42 @@
+package mpdapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTrees(t *testing.T) {
+	t.Run("equal trees", func(t *testing.T) {
+		diff := DiffTrees(newTestTree(), newTestTree())
+		assert.True(t, diff.IsEmpty())
+	})
+	t.Run("nil old tree", func(t *testing.T) {
+		diff := DiffTrees(nil, newTestTree())
+		assert.Equal(t, []string{"a/b/1.mp3", "a/2.mp3", "3.mp3"}, paths(diff.AddedFiles))
+		assert.Equal(t, []string{"a", "a/b"}, paths(diff.AddedDirectories))
+		assert.Empty(t, diff.RemovedFiles)
+	})
+	t.Run("added, removed and modified items", func(t *testing.T) {
+		oldTree := newTestTree()
+		newTree := newTestTree()
+		a, _ := newTree.FindDirectory("a")
+		modified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		a.LastModified = &modified
+		c := &DirectoryItem{parent: newTree, Name: "c", Path: "c", expanded: true}
+		c.Children = []TreeItem{&FileItem{parent: c, Name: "4.mp3", Path: "c/4.mp3"}}
+		newTree.Children = append(newTree.Children, c)
+		file, _ := newTree.FindFile("a/2.mp3")
+		file.Artist = []string{"Other"}
+		a.Children = a.Children[1:]
+
+		diff := DiffTrees(oldTree, newTree)
+		assert.Equal(t, []string{"c/4.mp3"}, paths(diff.AddedFiles))
+		assert.Equal(t, []string{"c"}, paths(diff.AddedDirectories))
+		assert.Equal(t, []string{"a/b/1.mp3"}, paths(diff.RemovedFiles))
+		assert.Equal(t, []string{"a/b"}, paths(diff.RemovedDirectories))
+		assert.Equal(t, []string{"a/2.mp3"}, paths(diff.ModifiedFiles))
+		assert.Equal(t, []string{"a"}, paths(diff.ModifiedDirectories))
+	})
+}
@@ -0,0 +1,317 @@
+package mpdapi
+
+import (
+	"io/fs"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// SkipDir can be returned by a WalkFunc to skip the children of the visited directory.
+	// When returned for a file it skips the remaining items of the file's directory.
+	SkipDir = fs.SkipDir
+	// SkipAll can be returned by a WalkFunc to stop walking without an error.
+	SkipAll = fs.SkipAll
+)
+
+// WalkFunc is called by DirectoryItem.Walk for every visited item.
+type WalkFunc func(item TreeItem) error
+
+// Tag is a name of an MPD song tag.
+type Tag string
+
+const (
+	TagArtist      Tag = "Artist"
+	TagAlbumArtist Tag = "AlbumArtist"
+	TagTitle       Tag = "Title"
+	TagAlbum       Tag = "Album"
+	TagTrack       Tag = "Track"
+	TagDate        Tag = "Date"
+	TagGenre       Tag = "Genre"
+	TagComposer    Tag = "Composer"
+	TagPerformer   Tag = "Performer"
+)
+
+// FilePredicate reports whether a file matches a filter.
+type FilePredicate func(f *FileItem) bool
+
+// Find returns the item with the given full path located in the subtree of d.
+// Only the loaded part of the tree is searched.
+func (d *DirectoryItem) Find(path string) (TreeItem, bool) {
+	path = strings.Trim(path, "/")
+	if path == d.Path {
+		return d, true
+	}
+	rel := path
+	if d.Path != "" {
+		if !strings.HasPrefix(path, d.Path+"/") {
+			return nil, false
+		}
+		rel = strings.TrimPrefix(path, d.Path+"/")
+	}
+	current := d
+	for {
+		name, rest, hasRest := strings.Cut(rel, "/")
+		var next TreeItem
+		for _, child := range current.Children {
+			if child.GetName() == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, false
+		}
+		if !hasRest {
+			return next, true
+		}
+		dir, ok := next.(*DirectoryItem)
+		if !ok {
+			return nil, false
+		}
+		current = dir
+		rel = rest
+	}
+}
+
+// FindDirectory returns the directory with the given full path located in the subtree of d.
+func (d *DirectoryItem) FindDirectory(path string) (*DirectoryItem, bool) {
+	item, ok := d.Find(path)
+	if !ok {
+		return nil, false
+	}
+	dir, ok := item.(*DirectoryItem)
+	return dir, ok
+}
+
+// FindFile returns the file with the given full path located in the subtree of d.
+func (d *DirectoryItem) FindFile(path string) (*FileItem, bool) {
+	item, ok := d.Find(path)
+	if !ok {
+		return nil, false
+	}
+	file, ok := item.(*FileItem)
+	return file, ok
+}
+
+// Walk visits d and all the items of its subtree in depth-first order, a directory
+// before its children. Directories that are not expanded are visited but not descended into.
+//
+// If fn returns SkipDir for a directory, its children are skipped. If fn returns SkipAll,
+// walking stops and Walk returns nil. Any other error stops walking and is returned.
+func (d *DirectoryItem) Walk(fn WalkFunc) error {
+	err := d.walk(fn)
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (d *DirectoryItem) walk(fn WalkFunc) error {
+	if err := fn(d); err != nil {
+		return err
+	}
+	for _, child := range d.Children {
+		var err error
+		if dir, ok := child.(*DirectoryItem); ok {
+			err = dir.walk(fn)
+			if err == SkipDir {
+				continue
+			}
+		} else {
+			err = fn(child)
+		}
+		if err != nil {
+			if err == SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// All returns an iterator over d and all the items of its loaded subtree in Walk order.
+func (d *DirectoryItem) All() iter.Seq[TreeItem] {
+	return func(yield func(TreeItem) bool) {
+		_ = d.Walk(func(item TreeItem) error {
+			if !yield(item) {
+				return SkipAll
+			}
+			return nil
+		})
+	}
+}
+
+// Directories returns an iterator over d and all the directories of its loaded subtree.
+func (d *DirectoryItem) Directories() iter.Seq[*DirectoryItem] {
+	return func(yield func(*DirectoryItem) bool) {
+		for item := range d.All() {
+			if dir, ok := item.(*DirectoryItem); ok && !yield(dir) {
+				return
+			}
+		}
+	}
+}
+
+// Files returns an iterator over all the files of the loaded subtree of d.
+func (d *DirectoryItem) Files() iter.Seq[*FileItem] {
+	return func(yield func(*FileItem) bool) {
+		for item := range d.All() {
+			if file, ok := item.(*FileItem); ok && !yield(file) {
+				return
+			}
+		}
+	}
+}
+
+// FilterFiles returns an iterator over the files of the loaded subtree of d matching predicate.
+func (d *DirectoryItem) FilterFiles(predicate FilePredicate) iter.Seq[*FileItem] {
+	return func(yield func(*FileItem) bool) {
+		for file := range d.Files() {
+			if predicate(file) && !yield(file) {
+				return
+			}
+		}
+	}
+}
+
+// TrackCount returns the number of files in the loaded subtree of d.
+func (d *DirectoryItem) TrackCount() int {
+	count := 0
+	for range d.Files() {
+		count++
+	}
+	return count
+}
+
+// Duration returns the total duration of the files in the loaded subtree of d.
+func (d *DirectoryItem) Duration() time.Duration {
+	var result time.Duration
+	for file := range d.Files() {
+		result += file.Length()
+	}
+	return result
+}
+
+// Length returns the duration of the file, or zero if it is unknown.
+// The precise Duration is used when MPD reports it, the rounded Time otherwise.
+func (f *FileItem) Length() time.Duration {
+	if f.Duration != nil {
+		return *f.Duration
+	}
+	if f.Time == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(*f.Time)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Tag returns the values of the tag, or nil if the file does not have it.
+func (f *FileItem) Tag(tag Tag) []string {
+	var value *string
+	switch tag {
+	case TagArtist:
+		return f.Artist
+	case TagAlbumArtist:
+		return f.AlbumArtist
+	case TagGenre:
+		return f.Genre
+	case TagComposer:
+		return f.Composer
+	case TagPerformer:
+		return f.Performer
+	case TagTitle:
+		value = f.Title
+	case TagAlbum:
+		value = f.Album
+	case TagTrack:
+		value = f.Track
+	case TagDate:
+		value = f.Date
+	}
+	if value == nil {
+		return nil
+	}
+	return []string{*value}
+}
+
+// Ancestors returns an iterator over the directories containing item,
+// starting with its parent and ending with the root.
+func Ancestors(item TreeItem) iter.Seq[*DirectoryItem] {
+	return func(yield func(*DirectoryItem) bool) {
+		for parent := item.GetParent(); parent != nil; parent = parent.GetParent() {
+			if !yield(parent) {
+				return
+			}
+		}
+	}
+}
+
+// TagEquals matches files having the tag with exactly the given value.
+func TagEquals(tag Tag, value string) FilePredicate {
+	return func(f *FileItem) bool {
+		for _, v := range f.Tag(tag) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TagContains matches files having the tag with a value containing substr, ignoring case.
+func TagContains(tag Tag, substr string) FilePredicate {
+	substr = strings.ToLower(substr)
+	return func(f *FileItem) bool {
+		for _, v := range f.Tag(tag) {
+			if strings.Contains(strings.ToLower(v), substr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasTag matches files having the tag set.
+func HasTag(tag Tag) FilePredicate {
+	return func(f *FileItem) bool {
+		return len(f.Tag(tag)) > 0
+	}
+}
+
+// And matches files matching all the predicates.
+func And(predicates ...FilePredicate) FilePredicate {
+	return func(f *FileItem) bool {
+		for _, p := range predicates {
+			if !p(f) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches files matching at least one of the predicates.
+func Or(predicates ...FilePredicate) FilePredicate {
+	return func(f *FileItem) bool {
+		for _, p := range predicates {
+			if p(f) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches files not matching the predicate.
+func Not(predicate FilePredicate) FilePredicate {
+	return func(f *FileItem) bool {
+		return !predicate(f)
+	}
+}
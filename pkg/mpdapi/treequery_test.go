@@ -0,0 +1,162 @@
+package mpdapi
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// newTestTree builds the following tree:
+//
+//	/
+//	├── a
+//	│   ├── a/b
+//	│   │   └── a/b/1.mp3 (Artist1, 100s)
+//	│   └── a/2.mp3 (Artist2, 200s)
+//	└── 3.mp3 (Artist1, 300s)
+func newTestTree() *DirectoryItem {
+	root := &DirectoryItem{Name: "/", expanded: true}
+	a := &DirectoryItem{parent: root, Name: "a", Path: "a", expanded: true}
+	b := &DirectoryItem{parent: a, Name: "b", Path: "a/b", expanded: true}
+	b.Children = []TreeItem{
+		&FileItem{parent: b, Name: "1.mp3", Path: "a/b/1.mp3", Artist: []string{"Artist1"}, Time: strPtr("100")},
+	}
+	a.Children = []TreeItem{
+		b,
+		&FileItem{parent: a, Name: "2.mp3", Path: "a/2.mp3", Artist: []string{"Artist2"}, Time: strPtr("200")},
+	}
+	root.Children = []TreeItem{
+		a,
+		&FileItem{parent: root, Name: "3.mp3", Path: "3.mp3", Artist: []string{"Artist1"}, Time: strPtr("300")},
+	}
+	return root
+}
+
+func paths[T TreeItem](items []T) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.GetPath())
+	}
+	return result
+}
+
+func TestDirectoryItem_Find(t *testing.T) {
+	root := newTestTree()
+	t.Run("finds file and directory by full path", func(t *testing.T) {
+		item, ok := root.Find("a/b/1.mp3")
+		assert.True(t, ok)
+		assert.Equal(t, "a/b/1.mp3", item.GetPath())
+		dir, ok := root.FindDirectory("a/b")
+		assert.True(t, ok)
+		assert.Equal(t, "b", dir.Name)
+	})
+	t.Run("finds relative to a subdirectory", func(t *testing.T) {
+		a, _ := root.FindDirectory("a")
+		file, ok := a.FindFile("a/2.mp3")
+		assert.True(t, ok)
+		assert.Equal(t, "2.mp3", file.Name)
+		_, ok = a.Find("3.mp3")
+		assert.False(t, ok)
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, ok := root.Find("a/c")
+		assert.False(t, ok)
+		_, ok = root.FindDirectory("3.mp3")
+		assert.False(t, ok)
+		_, ok = root.Find("3.mp3/x")
+		assert.False(t, ok)
+	})
+}
+
+func TestDirectoryItem_Walk(t *testing.T) {
+	root := newTestTree()
+	t.Run("visits all items in depth-first order", func(t *testing.T) {
+		var visited []TreeItem
+		err := root.Walk(func(item TreeItem) error {
+			visited = append(visited, item)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"", "a", "a/b", "a/b/1.mp3", "a/2.mp3", "3.mp3"}, paths(visited))
+	})
+	t.Run("skips directory", func(t *testing.T) {
+		var visited []TreeItem
+		err := root.Walk(func(item TreeItem) error {
+			visited = append(visited, item)
+			if item.GetPath() == "a/b" {
+				return SkipDir
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"", "a", "a/b", "a/2.mp3", "3.mp3"}, paths(visited))
+	})
+	t.Run("stops on SkipAll and on error", func(t *testing.T) {
+		var visited []TreeItem
+		err := root.Walk(func(item TreeItem) error {
+			visited = append(visited, item)
+			if item.GetPath() == "a/b" {
+				return SkipAll
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"", "a", "a/b"}, paths(visited))
+		expectedErr := errors.New("error")
+		err = root.Walk(func(item TreeItem) error {
+			return expectedErr
+		})
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestDirectoryItem_Iterators(t *testing.T) {
+	root := newTestTree()
+	t.Run("files and directories", func(t *testing.T) {
+		assert.Equal(t, []string{"a/b/1.mp3", "a/2.mp3", "3.mp3"}, paths(slices.Collect(root.Files())))
+		assert.Equal(t, []string{"", "a", "a/b"}, paths(slices.Collect(root.Directories())))
+	})
+	t.Run("early stop", func(t *testing.T) {
+		var files []*FileItem
+		for file := range root.Files() {
+			files = append(files, file)
+			break
+		}
+		assert.Len(t, files, 1)
+	})
+	t.Run("filter files by tag", func(t *testing.T) {
+		assert.Equal(t, []string{"a/b/1.mp3", "3.mp3"}, paths(slices.Collect(root.FilterFiles(TagEquals(TagArtist, "Artist1")))))
+		assert.Equal(t, []string{"a/2.mp3"}, paths(slices.Collect(root.FilterFiles(And(TagContains(TagArtist, "artist"), Not(TagEquals(TagArtist, "Artist1")))))))
+		assert.Empty(t, slices.Collect(root.FilterFiles(HasTag(TagAlbum))))
+	})
+}
+
+func TestDirectoryItem_Aggregates(t *testing.T) {
+	root := newTestTree()
+	assert.Equal(t, 3, root.TrackCount())
+	assert.Equal(t, 600*time.Second, root.Duration())
+	a, _ := root.FindDirectory("a")
+	assert.Equal(t, 2, a.TrackCount())
+	assert.Equal(t, 300*time.Second, a.Duration())
+
+	// The precise duration is preferred to the rounded time.
+	file, _ := root.FindFile("3.mp3")
+	duration := 299500 * time.Millisecond
+	file.Duration = &duration
+	assert.Equal(t, duration, file.Length())
+	assert.Equal(t, 599500*time.Millisecond, root.Duration())
+}
+
+func TestAncestors(t *testing.T) {
+	root := newTestTree()
+	file, _ := root.FindFile("a/b/1.mp3")
+	assert.Equal(t, []string{"a/b", "a", ""}, paths(slices.Collect(Ancestors(file))))
+	assert.Empty(t, slices.Collect(Ancestors(root)))
+}
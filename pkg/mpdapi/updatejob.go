@@ -0,0 +1,92 @@
+package mpdapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/commands"
+)
+
+// updatePollInterval is how often WaitForUpdate checks the status
+// in case an ON_UPDATE_CHANGED event is missed.
+const updatePollInterval = time.Second
+
+// maxUpdateJobId is the largest update job id of MPD, the id of the next job is 1 again.
+const maxUpdateJobId = 1 << 15
+
+// UpdateProgress describes the state of the database update tracked by WaitForUpdate.
+type UpdateProgress struct {
+	// JobId is the id of the tracked job.
+	JobId int
+	// CurrentJobId is the id of the job MPD is running now, nil if no update is running.
+	// It is less than JobId while the tracked job is queued.
+	CurrentJobId *int
+	// Done reports whether the tracked job has finished.
+	Done bool
+}
+
+type updateAnswer struct {
+	JobId int `mpd_prefix:"updating_db"`
+}
+
+func (api *Impl) startUpdate(commandType commands.CommandType, path string) (int, error) {
+	cmd := commands.NewSingleCommand(commandType)
+	if path != "" {
+		cmd = cmd.AddParams(path)
+	}
+	list, err := api.mpdClient.SendSingleCommand(api.requestContext, cmd)
+	if err != nil {
+		return 0, wrapPkgError(err)
+	}
+	answer, err := parseSingleValue[updateAnswer](api.parsing, cmd, list)
+	if err != nil {
+		return 0, wrapPkgError(err)
+	}
+	return answer.JobId, nil
+}
+
+func (api *Impl) WaitForUpdate(ctx context.Context, jobId int, onProgress func(UpdateProgress)) (err error) {
+	// The status is requested with ctx, so a request in progress is interrupted when it is done.
+	api, end := api.withRequestContext(ctx).traced("WaitForUpdate")
+	defer func() { end(err) }()
+	subscription := api.SubscribeEvents(WithEventTypes(ON_UPDATE_CHANGED, ON_DATABASE_CHANGED))
+	defer subscription.Close()
+	events := subscription.Events()
+	ticker := time.NewTicker(updatePollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := api.Status()
+		if err != nil {
+			return err
+		}
+		progress := UpdateProgress{
+			JobId:        jobId,
+			CurrentJobId: status.UpdatingDb,
+			Done:         status.UpdatingDb == nil || jobFinished(*status.UpdatingDb, jobId),
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+		if progress.Done {
+			return nil
+		}
+		select {
+		case _, ok := <-events:
+			if !ok {
+				// The api is closed, the status is polled until it fails.
+				events = nil
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// jobFinished reports whether the job jobId has finished while the job current is running.
+// The ids grow by one and wrap around after maxUpdateJobId, so current runs after jobId
+// if it is less than half of the ids ahead of it.
+func jobFinished(current, jobId int) bool {
+	ahead := ((current-jobId)%maxUpdateJobId + maxUpdateJobId) % maxUpdateJobId
+	return ahead > 0 && ahead < maxUpdateJobId/2
+}
@@ -0,0 +1,18 @@
+package mpdapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobFinished(t *testing.T) {
+	assert.False(t, jobFinished(5, 5))
+	assert.False(t, jobFinished(4, 5))
+	assert.True(t, jobFinished(6, 5))
+	// The ids wrap around after maxUpdateJobId.
+	assert.True(t, jobFinished(1, maxUpdateJobId))
+	assert.True(t, jobFinished(2, maxUpdateJobId-1))
+	assert.False(t, jobFinished(maxUpdateJobId, 1))
+	assert.False(t, jobFinished(maxUpdateJobId-1, 2))
+}
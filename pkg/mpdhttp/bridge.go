@@ -0,0 +1,252 @@
+// Package mpdhttp serves the events of an mpdapi.MpdApi to browsers as Server-Sent Events
+// and over WebSocket, as JSON messages:
+//
+//	bridge := mpdhttp.New(ctx, api, mpdhttp.WithStateEvents())
+//	http.Handle("/events", bridge.SSEHandler())
+//	http.Handle("/ws", bridge.WebSocketHandler())
+//
+// A connection receives the events listed in the events query parameter, e.g.
+// /events?events=player,mixer, all of them without it. The names are the ones of
+// mpdapi.MpdEventType.String. A message with the event "heartbeat" is sent every heartbeat
+// interval, so the front-end can detect a stale connection.
+package mpdhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+)
+
+// HeartbeatEvent is the event of the heartbeat messages.
+const HeartbeatEvent = "heartbeat"
+
+// Message is the JSON payload sent for an event.
+type Message struct {
+	// Event is the name of the event type, e.g. "player" or "connect", or HeartbeatEvent.
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// Dropped is the number of messages not sent to the connection before this one, as it was too slow.
+	Dropped int `json:"dropped,omitempty"`
+	// Changes, Status and Outputs are the state of the event, see WithStateEvents.
+	Changes []Change        `json:"changes,omitempty"`
+	Status  *mpdapi.Status  `json:"status,omitempty"`
+	Outputs []mpdapi.Output `json:"outputs,omitempty"`
+}
+
+// Change is a changed value of the state, see mpdapi.StateChange.
+type Change struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Option configures a Bridge created with New.
+type Option func(*Bridge)
+
+// WithStateEvents sends the player, mixer, options, playlist and output events with the state
+// after the change and the changed values, see mpdapi.StateEvents. The state is fetched once per
+// event for all the connections.
+func WithStateEvents() Option {
+	return func(b *Bridge) {
+		b.stateEvents = true
+	}
+}
+
+// WithHeartbeat sets the interval of the heartbeat messages, 15 seconds by default. 0 disables them.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(b *Bridge) {
+		b.heartbeat = interval
+	}
+}
+
+// WithBufferSize sets the number of messages kept for a slow connection, 64 by default.
+// The messages above are dropped, the next message sent reports their number.
+func WithBufferSize(size int) Option {
+	return func(b *Bridge) {
+		b.bufferSize = max(size, 1)
+	}
+}
+
+// WithOriginPatterns allows the WebSocket connections from the hosts matching the patterns,
+// e.g. "*.example.com", besides the host of the handler.
+func WithOriginPatterns(patterns ...string) Option {
+	return func(b *Bridge) {
+		b.originPatterns = append(b.originPatterns, patterns...)
+	}
+}
+
+// stateEventTypes are the event types sent with their state by WithStateEvents.
+var stateEventTypes = []mpdapi.MpdEventType{
+	mpdapi.ON_PLAYER_CHANGED,
+	mpdapi.ON_MIXER_CHANGED,
+	mpdapi.ON_OPTIONS_CHANGED,
+	mpdapi.ON_PLAYLIST_CHANGED,
+	mpdapi.ON_OUTPUT_CHANGED,
+}
+
+// Bridge sends the events of an api to the HTTP connections of its handlers.
+type Bridge struct {
+	stateEvents    bool
+	heartbeat      time.Duration
+	bufferSize     int
+	originPatterns []string
+	// done is closed when the ctx of the bridge is done.
+	done chan struct{}
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+// client is a connection of a handler.
+type client struct {
+	// events are the names of the events sent to the connection, all if empty.
+	events   []string
+	messages chan Message
+	// dropped is the number of messages dropped since the last one queued. Guarded by Bridge.mu.
+	dropped int
+}
+
+// New creates a bridge of the events of api, subscribed to them until ctx is done.
+func New(ctx context.Context, api mpdapi.MpdApi, opts ...Option) *Bridge {
+	b := &Bridge{
+		heartbeat:  15 * time.Second,
+		bufferSize: 64,
+		done:       make(chan struct{}),
+		clients:    make(map[*client]struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	// The events occurring while the previous ones are published are merged by the subscription
+	// instead of being dropped.
+	events := api.SubscribeEvents()
+	var states chan mpdapi.StateEvent
+	if b.stateEvents {
+		states = api.SubscribeStateEvents(time.Second)
+	}
+	go func() {
+		defer close(b.done)
+		defer events.Close()
+		if states != nil {
+			defer api.UnsubscribeStateEvents(states)
+		}
+		for {
+			select {
+			case batch, ok := <-events.Events():
+				if !ok {
+					return
+				}
+				for _, event := range batch.Types {
+					if b.stateEvents && slices.Contains(stateEventTypes, event) {
+						// Sent with the state when it is loaded.
+						continue
+					}
+					b.publish(Message{Event: event.String(), Time: time.Now()})
+				}
+			case event := <-states:
+				b.publish(stateMessage(event))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return b
+}
+
+func stateMessage(event mpdapi.StateEvent) Message {
+	message := Message{Event: event.Type.String(), Time: time.Now(), Status: event.After, Outputs: event.OutputsAfter}
+	for _, change := range event.Changes {
+		message.Changes = append(message.Changes, Change{Field: change.Field, From: change.From, To: change.To})
+	}
+	return message
+}
+
+// publish queues the message to the connections receiving its event.
+func (b *Bridge) publish(message Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		if len(c.events) > 0 && !slices.Contains(c.events, message.Event) {
+			continue
+		}
+		queued := message
+		queued.Dropped = c.dropped
+		select {
+		case c.messages <- queued:
+			c.dropped = 0
+		default:
+			c.dropped++
+		}
+	}
+}
+
+// register adds a connection receiving the events listed in the events query parameter of r.
+func (b *Bridge) register(r *http.Request) (*client, error) {
+	c := &client{messages: make(chan Message, b.bufferSize)}
+	if events := r.URL.Query().Get("events"); events != "" {
+		for _, name := range strings.Split(events, ",") {
+			name = strings.TrimSpace(name)
+			if !isEventName(name) {
+				return nil, fmt.Errorf("unknown event %q", name)
+			}
+			c.events = append(c.events, name)
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clients[c] = struct{}{}
+	return c, nil
+}
+
+func (b *Bridge) unregister(c *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, c)
+}
+
+// isEventName reports whether name is the name of an mpdapi.MpdEventType.
+func isEventName(name string) bool {
+	for eventType := mpdapi.ON_CONNECT; eventType <= mpdapi.ON_MESSAGE_CHANGED; eventType++ {
+		if eventType.String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// serve sends the messages of c with send until the request is done, the bridge is done or send fails.
+func (b *Bridge) serve(ctx context.Context, c *client, send func(message Message) error) {
+	var heartbeat <-chan time.Time
+	if b.heartbeat > 0 {
+		ticker := time.NewTicker(b.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case message := <-c.messages:
+			err = send(message)
+		case <-heartbeat:
+			err = send(Message{Event: HeartbeatEvent, Time: time.Now()})
+		case <-ctx.Done():
+			return
+		case <-b.done:
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Warn("Error sending an event", "err", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package mpdhttp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdhttp"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBridge(t *testing.T, opts ...mpdhttp.Option) (*mpdtest.Server, mpdapi.MpdApi, *httptest.Server) {
+	t.Helper()
+	server := mpdtest.NewServer()
+	t.Cleanup(func() { _ = server.Close() })
+	server.AddSongs(mpdtest.Song{File: "1.mp3", Duration: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 1, time.Second, time.Second, mpdapi.WithDialer(server.Dial))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	t.Cleanup(func() { _ = api.Disconnect() })
+	bridge := mpdhttp.New(ctx, api, opts...)
+	mux := http.NewServeMux()
+	mux.Handle("/events", bridge.SSEHandler())
+	mux.Handle("/ws", bridge.WebSocketHandler())
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return server, api, httpServer
+}
+
+// sseMessages returns the messages received from the SSE handler at path.
+func sseMessages(t *testing.T, httpServer *httptest.Server, path string) chan mpdhttp.Message {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+path, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	messages := make(chan mpdhttp.Message, 16)
+	go func() {
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var message mpdhttp.Message
+			if json.Unmarshal([]byte(data), &message) == nil {
+				messages <- message
+			}
+		}
+	}()
+	return messages
+}
+
+func nextMessage(t *testing.T, messages chan mpdhttp.Message) mpdhttp.Message {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return mpdhttp.Message{}
+	}
+}
+
+func TestSSEHandler(t *testing.T) {
+	t.Run("filtered events", func(t *testing.T) {
+		server, _, httpServer := newBridge(t, mpdhttp.WithHeartbeat(0))
+		messages := sseMessages(t, httpServer, "/events?events=mixer,player")
+		server.Notify("playlist")
+		server.Notify("mixer")
+		message := nextMessage(t, messages)
+		assert.Equal(t, "mixer", message.Event)
+		assert.False(t, message.Time.IsZero())
+		server.Notify("playlist")
+		server.Notify("player")
+		assert.Equal(t, "player", nextMessage(t, messages).Event)
+	})
+	t.Run("heartbeat", func(t *testing.T) {
+		_, _, httpServer := newBridge(t, mpdhttp.WithHeartbeat(10*time.Millisecond))
+		messages := sseMessages(t, httpServer, "/events?events=database")
+		assert.Equal(t, mpdhttp.HeartbeatEvent, nextMessage(t, messages).Event)
+	})
+	t.Run("unknown event", func(t *testing.T) {
+		_, _, httpServer := newBridge(t)
+		resp, err := http.Get(httpServer.URL + "/events?events=player,unknown")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("state events", func(t *testing.T) {
+		server, api, httpServer := newBridge(t, mpdhttp.WithHeartbeat(0), mpdhttp.WithStateEvents())
+		messages := sseMessages(t, httpServer, "/events?events=mixer,output")
+		// The state the changes are compared with is loaded with the outputs.
+		assert.Eventually(t, func() bool {
+			for _, command := range server.Commands() {
+				if command == "outputs" {
+					return true
+				}
+			}
+			return false
+		}, time.Second, time.Millisecond)
+		server.SetVolume(45)
+		message := nextMessage(t, messages)
+		assert.Equal(t, "mixer", message.Event)
+		assert.Equal(t, []mpdhttp.Change{{Field: "volume", From: "100", To: "45"}}, message.Changes)
+		require.NotNil(t, message.Status)
+		assert.Equal(t, 45, *message.Status.Volume)
+
+		require.NoError(t, api.DisableOutput(0))
+		message = nextMessage(t, messages)
+		assert.Equal(t, "output", message.Event)
+		assert.Equal(t, []mpdapi.Output{{Name: "default", Id: 0, Enabled: false}}, message.Outputs)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("stops with events in flight", func(t *testing.T) {
+		server, api, _ := newBridge(t, mpdhttp.WithHeartbeat(0))
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		mpdhttp.New(ctx, api, mpdhttp.WithStateEvents())
+		for i := range 50 {
+			server.SetVolume(i)
+			if i == 25 {
+				cancel()
+			}
+		}
+		// The events sent after the bridge stopped don't reach its closed subscriptions.
+		_, err := api.Status()
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+	})
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	server, _, httpServer := newBridge(t, mpdhttp.WithHeartbeat(0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws?events=player", nil)
+	require.NoError(t, err)
+	defer conn.CloseNow()
+	// The connection is registered before the handshake is answered.
+	server.Notify("mixer")
+	server.Notify("player")
+	var message mpdhttp.Message
+	require.NoError(t, wsjson.Read(ctx, conn, &message))
+	assert.Equal(t, "player", message.Event)
+	require.NoError(t, conn.Close(websocket.StatusNormalClosure, ""))
+}
@@ -0,0 +1,27 @@
+module github.com/anpotashev/mpdgo/pkg/mpdhttp
+
+go 1.24.4
+
+require (
+	github.com/anpotashev/mpdgo v0.0.0-20261019045550-30c8831004d0
+	github.com/coder/websocket v1.8.13
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/anpotashev/go-observer v0.0.0-20250930195727-c65407428856 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rs/zerolog v1.33.0 // indirect
+	github.com/stretchr/objx v0.5.2 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The bridge is developed with the library next to it. The replace applies to this module
+// only, the modules requiring the bridge use the version of the library required above.
+replace github.com/anpotashev/mpdgo => ../..
@@ -0,0 +1,84 @@
+package mpdhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// writeTimeout limits sending a message to a WebSocket connection.
+const writeTimeout = 10 * time.Second
+
+// SSEHandler returns the handler sending the events as Server-Sent Events,
+// every message is the data of an event:
+//
+//	data: {"event":"player","time":"2025-10-19T10:00:00Z"}
+func (b *Bridge) SSEHandler() http.Handler {
+	return http.HandlerFunc(b.serveSSE)
+}
+
+func (b *Bridge) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	c, err := b.register(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer b.unregister(c)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	b.serve(r.Context(), c, func(message Message) error {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
+
+// WebSocketHandler returns the handler sending the events over WebSocket, a JSON text message per event.
+// The messages received from the connection are ignored.
+// Only the connections from the host of the handler are accepted, see WithOriginPatterns.
+func (b *Bridge) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(b.serveWebSocket)
+}
+
+func (b *Bridge) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	c, err := b.register(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer b.unregister(c)
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: b.originPatterns})
+	if err != nil {
+		logger.Warn("Error accepting a WebSocket connection", "err", err)
+		return
+	}
+	defer conn.CloseNow()
+	// The connection is closed by the client, the context is done then.
+	ctx := conn.CloseRead(r.Context())
+	b.serve(ctx, c, func(message Message) error {
+		ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+		defer cancel()
+		return wsjson.Write(ctx, conn, message)
+	})
+	_ = conn.Close(websocket.StatusNormalClosure, "")
+}
@@ -0,0 +1,105 @@
+package mpdplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+// newClockTracker returns a tracker without an api, whose time is advanced by the returned function.
+func newClockTracker(nearEnd time.Duration) (*Tracker, func(d time.Duration)) {
+	now := time.Unix(0, 0)
+	t := &Tracker{
+		nearEnd:  nearEnd,
+		now:      func() time.Time { return now },
+		Observer: notifier.New[Event](),
+		ticks:    notifier.New[State](),
+		state:    State{SongPos: -1, SongId: -1},
+	}
+	return t, func(d time.Duration) { now = now.Add(d) }
+}
+
+// receive returns the types of the next n events.
+func receive(t *testing.T, events chan Event, n int) []EventType {
+	t.Helper()
+	var result []EventType
+	for range n {
+		select {
+		case event := <-events:
+			result = append(result, event.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("%d events received instead of %d: %v", len(result), n, result)
+		}
+	}
+	return result
+}
+
+func assertNoEvent(t *testing.T, events chan Event) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %v", event.Type)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTracker_Interpolation(t *testing.T) {
+	tracker, advance := newClockTracker(0)
+	tracker.update(State{State: StatePlay, SongPos: 0, SongId: 1, Elapsed: 10 * time.Second, Duration: time.Minute})
+	advance(5 * time.Second)
+	assert.Equal(t, 15*time.Second, tracker.State().Elapsed)
+	advance(time.Minute)
+	assert.Equal(t, time.Minute, tracker.State().Elapsed)
+
+	tracker.update(State{State: StatePause, SongPos: 0, SongId: 1, Elapsed: 20 * time.Second, Duration: time.Minute})
+	advance(5 * time.Second)
+	assert.Equal(t, 20*time.Second, tracker.State().Elapsed)
+}
+
+func TestTracker_NearEnd(t *testing.T) {
+	tracker, advance := newClockTracker(10 * time.Second)
+	tracker.update(State{State: StatePlay, SongPos: 0, SongId: 1, Elapsed: 40 * time.Second, Duration: time.Minute})
+	events := tracker.Subscribe(time.Second)
+	defer tracker.Unsubscribe(events)
+
+	advance(9 * time.Second)
+	tracker.tick()
+	assertNoEvent(t, events)
+	advance(time.Second)
+	tracker.tick()
+	select {
+	case event := <-events:
+		assert.Equal(t, NearEnd, event.Type)
+		assert.Equal(t, 50*time.Second, event.State.Elapsed)
+		assert.Equal(t, 10*time.Second, event.State.Remaining())
+	case <-time.After(time.Second):
+		t.Fatal("NearEnd not received")
+	}
+	advance(time.Second)
+	tracker.tick()
+	assertNoEvent(t, events)
+}
+
+func TestTracker_Restart(t *testing.T) {
+	tracker, advance := newClockTracker(10 * time.Second)
+	tracker.update(State{State: StatePlay, SongPos: 0, SongId: 1, Duration: time.Minute})
+	events := tracker.Subscribe(time.Second)
+	defer tracker.Unsubscribe(events)
+
+	// With repeat and single, the song plays again with the same id.
+	advance(55 * time.Second)
+	tracker.tick()
+	assert.Equal(t, []EventType{NearEnd}, receive(t, events, 1))
+	advance(5 * time.Second)
+	tracker.update(State{State: StatePlay, SongPos: 0, SongId: 1, Elapsed: 200 * time.Millisecond, Duration: time.Minute})
+	assert.Equal(t, []EventType{SongEnded, SongStarted}, receive(t, events, 2))
+	assert.False(t, tracker.nearEndFired)
+
+	// A seek back in the middle of the song is not a restart.
+	advance(30 * time.Second)
+	tracker.update(State{State: StatePlay, SongPos: 0, SongId: 1, Duration: time.Minute})
+	assert.Equal(t, []EventType{Seeked}, receive(t, events, 1))
+	assertNoEvent(t, events)
+}
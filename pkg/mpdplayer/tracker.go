@@ -0,0 +1,305 @@
+// Package mpdplayer tracks the player of an MPD server for front-ends: the current song,
+// the play state and the elapsed time, interpolated between the events of the server,
+// so a progress bar doesn't need to poll the status:
+//
+//	tracker := mpdplayer.New(ctx, api, mpdplayer.WithNearEnd(10*time.Second))
+//	ticks := tracker.SubscribeTicks(time.Second)
+//	events := tracker.Subscribe(time.Second)
+//	for {
+//		select {
+//		case state := <-ticks:
+//			progress.Set(state.Elapsed, state.Duration)
+//		case event := <-events:
+//			if event.Type == mpdplayer.NearEnd {
+//				prefetchNextCover()
+//			}
+//		}
+//	}
+package mpdplayer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anpotashev/go-observer/pkg/observer"
+	"github.com/anpotashev/mpdgo/internal/logger"
+	"github.com/anpotashev/mpdgo/internal/notifier"
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+)
+
+// Play states of State.
+const (
+	StatePlay  = "play"
+	StatePause = "pause"
+	StateStop  = "stop"
+)
+
+// State is the state of the player.
+type State struct {
+	// State is StatePlay, StatePause or StateStop, "" if not connected.
+	State string
+	// SongPos and SongId are the position and the id of the current song in the playlist, -1 if there is none.
+	SongPos int
+	SongId  int
+	// Elapsed is the elapsed time of the current song. It is interpolated while playing.
+	Elapsed  time.Duration
+	Duration time.Duration
+}
+
+// Remaining returns the time until the end of the current song, 0 if its duration is unknown.
+func (s State) Remaining() time.Duration {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return max(s.Duration-s.Elapsed, 0)
+}
+
+// HasSong reports whether there is a current song.
+func (s State) HasSong() bool {
+	return s.SongId >= 0
+}
+
+// EventType is the type of an Event.
+type EventType uint8
+
+const (
+	// StateChanged is fired when the play state changes, e.g. from play to pause.
+	StateChanged EventType = iota + 1
+	// SongStarted is fired when a song becomes the current one while playing or paused,
+	// or when the playing song starts again, e.g. with repeat and single.
+	SongStarted
+	// SongEnded is fired when the current song stops being current, as the next one started,
+	// the player stopped or the song starts again. The State of the event is the last state of the song.
+	SongEnded
+	// NearEnd is fired once per song when the remaining time of the playing song reaches
+	// the time set with WithNearEnd.
+	NearEnd
+	// Seeked is fired when the elapsed time differs from the interpolated one, e.g. after a seek.
+	// A seek to the start within the last seconds of the song is taken for the song starting again.
+	Seeked
+)
+
+func (t EventType) String() string {
+	switch t {
+	case StateChanged:
+		return "state_changed"
+	case SongStarted:
+		return "song_started"
+	case SongEnded:
+		return "song_ended"
+	case NearEnd:
+		return "near_end"
+	case Seeked:
+		return "seeked"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of the player.
+type Event struct {
+	Type  EventType
+	State State
+}
+
+// Option configures a Tracker created with New.
+type Option func(*Tracker)
+
+// WithTickInterval sets how often the ticks are sent while playing, 1 second by default.
+func WithTickInterval(interval time.Duration) Option {
+	return func(t *Tracker) {
+		t.tickInterval = interval
+	}
+}
+
+// WithNearEnd fires NearEnd when the playing song has remaining time left. It is checked every tick.
+func WithNearEnd(remaining time.Duration) Option {
+	return func(t *Tracker) {
+		t.nearEnd = remaining
+	}
+}
+
+// seekTolerance is the difference between the reported and the interpolated elapsed time
+// which is not considered a seek.
+const seekTolerance = 1500 * time.Millisecond
+
+// Tracker tracks the player of an mpdapi.MpdApi. The events are received with Subscribe,
+// in the order they are fired, e.g. SongEnded before SongStarted.
+type Tracker struct {
+	api          mpdapi.MpdApi
+	tickInterval time.Duration
+	nearEnd      time.Duration
+	// now returns the current time, replaced in the tests of the interpolation.
+	now func() time.Time
+	observer.Observer[Event]
+	ticks observer.Observer[State]
+
+	mu    sync.Mutex
+	state State
+	// updated is the time state.Elapsed was reported by the server.
+	updated time.Time
+	// nearEndFired reports whether NearEnd was fired for the current song.
+	nearEndFired bool
+}
+
+// New creates a tracker of the player of api. It runs until ctx is done.
+// The state is loaded when api is connected.
+func New(ctx context.Context, api mpdapi.MpdApi, opts ...Option) *Tracker {
+	t := &Tracker{
+		api:          api,
+		tickInterval: time.Second,
+		now:          time.Now,
+		Observer:     notifier.New[Event](),
+		ticks:        notifier.New[State](),
+		state:        State{SongPos: -1, SongId: -1},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	subscription := api.SubscribeEvents(mpdapi.WithEventTypes(mpdapi.ON_CONNECT, mpdapi.ON_DISCONNECT, mpdapi.ON_PLAYER_CHANGED))
+	go t.run(ctx, subscription)
+	return t
+}
+
+// State returns the current state of the player.
+func (t *Tracker) State() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current()
+}
+
+// SubscribeTicks returns a channel receiving the state every tick interval while playing,
+// and on every change of the player.
+func (t *Tracker) SubscribeTicks(timeout time.Duration) chan State {
+	return t.ticks.Subscribe(timeout)
+}
+
+func (t *Tracker) UnsubscribeTicks(ch chan State) {
+	t.ticks.Unsubscribe(ch)
+}
+
+// current returns the state with the interpolated elapsed time. t.mu must be held.
+func (t *Tracker) current() State {
+	state := t.state
+	if state.State == StatePlay {
+		state.Elapsed += t.now().Sub(t.updated)
+		if state.Duration > 0 {
+			state.Elapsed = min(state.Elapsed, state.Duration)
+		}
+	}
+	return state
+}
+
+func (t *Tracker) run(ctx context.Context, subscription *mpdapi.Subscription) {
+	defer subscription.Close()
+	if t.api.IsConnected() {
+		t.refresh()
+	}
+	ticker := time.NewTicker(t.tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case batch, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+			if batch.Has(mpdapi.ON_DISCONNECT) && !t.api.IsConnected() {
+				t.update(State{SongPos: -1, SongId: -1})
+				continue
+			}
+			t.refresh()
+		case <-ticker.C:
+			t.tick()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh loads the status of the player.
+func (t *Tracker) refresh() {
+	status, err := t.api.Status()
+	if err != nil {
+		logger.Warn("Error loading the status of the player", "err", err)
+		return
+	}
+	t.update(stateOf(status))
+}
+
+// stateOf returns the state of the player in status.
+func stateOf(status mpdapi.Status) State {
+	state := State{SongPos: -1, SongId: -1}
+	if status.State != nil {
+		state.State = *status.State
+	}
+	if status.Song != nil && status.SongId != nil && state.State != StateStop {
+		state.SongPos, state.SongId = *status.Song, *status.SongId
+	}
+	switch {
+	case status.Elapsed != nil:
+		state.Elapsed = *status.Elapsed
+	case status.Time != nil:
+		state.Elapsed = time.Duration(status.Time.Current) * time.Second
+	}
+	switch {
+	case status.Duration != nil:
+		state.Duration = *status.Duration
+	case status.Time != nil:
+		state.Duration = time.Duration(status.Time.Full) * time.Second
+	}
+	return state
+}
+
+// update replaces the state and fires the events of the change.
+func (t *Tracker) update(state State) {
+	t.mu.Lock()
+	previous := t.current()
+	t.state, t.updated = state, t.now()
+	// A song playing again from the start, e.g. with repeat and single, keeps its id.
+	restarted := previous.SongId == state.SongId && state.HasSong() && previous.State == StatePlay &&
+		previous.Duration > 0 && previous.Remaining() <= seekTolerance && state.Elapsed <= seekTolerance
+	songChanged := previous.SongId != state.SongId || restarted
+	if songChanged {
+		t.nearEndFired = false
+	}
+	var events []Event
+	if songChanged && previous.HasSong() {
+		events = append(events, Event{Type: SongEnded, State: previous})
+	}
+	if previous.State != state.State {
+		events = append(events, Event{Type: StateChanged, State: state})
+	}
+	if songChanged && state.HasSong() {
+		events = append(events, Event{Type: SongStarted, State: state})
+	}
+	if !songChanged && state.HasSong() && previous.State == state.State &&
+		(state.Elapsed-previous.Elapsed).Abs() > seekTolerance {
+		t.nearEndFired = t.nearEndFired && state.Remaining() <= t.nearEnd
+		events = append(events, Event{Type: Seeked, State: state})
+	}
+	t.mu.Unlock()
+	for _, event := range events {
+		t.Notify(event)
+	}
+	t.ticks.Notify(state)
+}
+
+// tick sends the interpolated state while playing, and fires NearEnd.
+func (t *Tracker) tick() {
+	t.mu.Lock()
+	state := t.current()
+	if state.State != StatePlay {
+		t.mu.Unlock()
+		return
+	}
+	nearEnd := t.nearEnd > 0 && !t.nearEndFired && state.Duration > 0 && state.Remaining() <= t.nearEnd
+	if nearEnd {
+		t.nearEndFired = true
+	}
+	t.mu.Unlock()
+	if nearEnd {
+		t.Notify(Event{Type: NearEnd, State: state})
+	}
+	t.ticks.Notify(state)
+}
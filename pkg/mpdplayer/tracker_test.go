@@ -0,0 +1,129 @@
+package mpdplayer_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdplayer"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForEvents returns the events of the types, which must be received in the given order.
+// The events of other types are skipped.
+func waitForEvents(t *testing.T, events chan mpdplayer.Event, types ...mpdplayer.EventType) []mpdplayer.Event {
+	t.Helper()
+	var result []mpdplayer.Event
+	var received []mpdplayer.EventType
+	timeout := time.After(time.Second)
+	for len(result) < len(types) {
+		select {
+		case event := <-events:
+			if slices.Contains(types, event.Type) {
+				result = append(result, event)
+				received = append(received, event.Type)
+			}
+		case <-timeout:
+			t.Fatalf("events %v not received, received %v", types, received)
+		}
+	}
+	require.Equal(t, types, received)
+	return result
+}
+
+func TestTracker(t *testing.T) {
+	server := mpdtest.NewServer()
+	t.Cleanup(func() { _ = server.Close() })
+	server.AddSongs(
+		mpdtest.Song{File: "1.mp3", Duration: time.Minute},
+		mpdtest.Song{File: "2.mp3", Duration: 2 * time.Minute},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 2, time.Second, time.Second, mpdapi.WithDialer(server.Dial))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	t.Cleanup(func() { _ = api.Disconnect() })
+	require.NoError(t, api.Add("1.mp3"))
+	require.NoError(t, api.Add("2.mp3"))
+
+	tracker := mpdplayer.New(ctx, api, mpdplayer.WithTickInterval(10*time.Millisecond), mpdplayer.WithNearEnd(2*time.Second))
+	assert.Eventually(t, func() bool { return tracker.State().State == mpdplayer.StateStop }, time.Second, time.Millisecond)
+	assert.False(t, tracker.State().HasSong())
+	events := tracker.Subscribe(time.Second)
+	ticks := tracker.SubscribeTicks(time.Second)
+
+	t.Run("song started", func(t *testing.T) {
+		require.NoError(t, api.Play())
+		received := waitForEvents(t, events, mpdplayer.StateChanged, mpdplayer.SongStarted)
+		assert.Equal(t, mpdplayer.StatePlay, received[0].State.State)
+		assert.Equal(t, 0, received[1].State.SongPos)
+		assert.Equal(t, time.Minute, received[1].State.Duration)
+	})
+	t.Run("ticks while playing", func(t *testing.T) {
+		// The interpolation is tested with a clock in interpolation_test.go.
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case state := <-ticks:
+				if state.State == mpdplayer.StatePlay {
+					assert.Equal(t, 0, state.SongPos)
+					return
+				}
+			case <-timeout:
+				t.Fatal("no tick received while playing")
+			}
+		}
+	})
+	t.Run("seek and near end", func(t *testing.T) {
+		require.NoError(t, api.Seek(0, 59))
+		received := waitForEvents(t, events, mpdplayer.Seeked, mpdplayer.NearEnd)
+		assert.GreaterOrEqual(t, received[1].State.Elapsed, 59*time.Second)
+		assert.LessOrEqual(t, received[1].State.Remaining(), time.Second)
+	})
+	t.Run("pause", func(t *testing.T) {
+		require.NoError(t, api.Pause())
+		received := waitForEvents(t, events, mpdplayer.StateChanged)
+		assert.Equal(t, mpdplayer.StatePause, received[0].State.State)
+		require.NoError(t, api.Play())
+		waitForEvents(t, events, mpdplayer.StateChanged)
+	})
+	t.Run("next song", func(t *testing.T) {
+		require.NoError(t, api.Next())
+		received := waitForEvents(t, events, mpdplayer.SongEnded, mpdplayer.SongStarted)
+		assert.Equal(t, 0, received[0].State.SongPos)
+		assert.Equal(t, 1, received[1].State.SongPos)
+		assert.Equal(t, 2*time.Minute, tracker.State().Duration)
+	})
+	t.Run("unsubscribe while playing", func(t *testing.T) {
+		// The events are sent while the subscribers unsubscribe, run with -race.
+		for i := range 10 {
+			events := tracker.Subscribe(time.Second)
+			ticks := tracker.SubscribeTicks(time.Second)
+			select {
+			case <-ticks:
+			case <-time.After(time.Second):
+				t.Fatal("no tick received while playing")
+			}
+			require.NoError(t, api.Seek(1, 10*(i+1)))
+			tracker.Unsubscribe(events)
+			tracker.UnsubscribeTicks(ticks)
+		}
+		waitForEvents(t, events, mpdplayer.Seeked)
+	})
+	t.Run("stop", func(t *testing.T) {
+		require.NoError(t, api.Stop())
+		received := waitForEvents(t, events, mpdplayer.SongEnded, mpdplayer.StateChanged)
+		assert.Equal(t, 1, received[0].State.SongPos)
+		assert.Equal(t, mpdplayer.StateStop, received[1].State.State)
+		assert.False(t, tracker.State().HasSong())
+	})
+	t.Run("disconnect", func(t *testing.T) {
+		require.NoError(t, api.Disconnect())
+		assert.Eventually(t, func() bool { return tracker.State().State == "" }, time.Second, time.Millisecond)
+	})
+}
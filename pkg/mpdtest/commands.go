@@ -0,0 +1,821 @@
+package mpdtest
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anpotashev/mpdgo/internal/parser"
+)
+
+type songInfo struct {
+	File         string              `mpd_prefix:"file" is_new_element_prefix:"true"`
+	LastModified *time.Time          `mpd_prefix:"Last-Modified"`
+	Time         *int                `mpd_prefix:"Time"`
+	Duration     *time.Duration      `mpd_prefix:"duration"`
+	Pos          *int                `mpd_prefix:"Pos"`
+	Id           *int                `mpd_prefix:"Id"`
+	Tags         map[string][]string `is_catch_all:"true"`
+}
+
+type directoryInfo struct {
+	Directory    string     `mpd_prefix:"directory" is_new_element_prefix:"true"`
+	LastModified *time.Time `mpd_prefix:"Last-Modified"`
+}
+
+type playlistInfo struct {
+	Playlist     string     `mpd_prefix:"playlist" is_new_element_prefix:"true"`
+	LastModified *time.Time `mpd_prefix:"Last-Modified"`
+}
+
+type outputInfo struct {
+	Id      int    `mpd_prefix:"outputid" is_new_element_prefix:"true"`
+	Name    string `mpd_prefix:"outputname"`
+	Plugin  string `mpd_prefix:"plugin"`
+	Enabled bool   `mpd_prefix:"outputenabled"`
+}
+
+type statusInfo struct {
+	Volume         int            `mpd_prefix:"volume"`
+	Repeat         bool           `mpd_prefix:"repeat"`
+	Random         bool           `mpd_prefix:"random"`
+	Single         bool           `mpd_prefix:"single"`
+	Consume        bool           `mpd_prefix:"consume"`
+	Playlist       int            `mpd_prefix:"playlist"`
+	PlaylistLength int            `mpd_prefix:"playlistlength"`
+	State          PlayerState    `mpd_prefix:"state"`
+	Song           *int           `mpd_prefix:"song"`
+	SongId         *int           `mpd_prefix:"songid"`
+	Time           *string        `mpd_prefix:"time"`
+	Elapsed        *time.Duration `mpd_prefix:"elapsed"`
+	Duration       *time.Duration `mpd_prefix:"duration"`
+	NextSong       *int           `mpd_prefix:"nextsong"`
+	NextSongId     *int           `mpd_prefix:"nextsongid"`
+}
+
+func newSongInfo(song Song) songInfo {
+	info := songInfo{File: song.File, Tags: song.Tags}
+	if !song.LastModified.IsZero() {
+		info.LastModified = &song.LastModified
+	}
+	if song.Duration > 0 {
+		seconds := int(song.Duration.Round(time.Second) / time.Second)
+		info.Time = &seconds
+		info.Duration = &song.Duration
+	}
+	return info
+}
+
+// marshal returns the lines of value. The marshaled types are defined above, so it only fails
+// for values with line breaks, e.g. in a tag of a song added by a test. The handler answers
+// with an ACK then, as such lines would not be valid MPD protocol.
+func marshal(value any) ([]string, error) {
+	lines, err := parser.MarshalSingleValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling the answer: %w", err)
+	}
+	return lines, nil
+}
+
+// answer collects the lines of an answer of several values.
+type answer struct {
+	lines []string
+	err   error
+}
+
+// add appends the lines of value, see marshal.
+func (a *answer) add(value any) {
+	if a.err != nil {
+		return
+	}
+	lines, err := marshal(value)
+	a.lines, a.err = append(a.lines, lines...), err
+}
+
+func (a *answer) addLine(line string) {
+	a.lines = append(a.lines, line)
+}
+
+func (a *answer) result() ([]string, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return a.lines, nil
+}
+
+func (s *Server) registerBuiltinHandlers() {
+	builtins := map[string]HandlerFunc{
+		"status":           s.status,
+		"currentsong":      s.currentSong,
+		"play":             s.play,
+		"playid":           s.playId,
+		"pause":            s.pause,
+		"stop":             s.stop,
+		"next":             s.next,
+		"previous":         s.previous,
+		"seek":             s.seek,
+		"setvol":           s.setVol,
+		"random":           s.option(func(st *state, v bool) { st.random = v }),
+		"repeat":           s.option(func(st *state, v bool) { st.repeat = v }),
+		"single":           s.option(func(st *state, v bool) { st.single = v }),
+		"consume":          s.option(func(st *state, v bool) { st.consume = v }),
+		"playlistinfo":     s.playlistInfo,
+		"clear":            s.clear,
+		"add":              s.add,
+		"addid":            s.addId,
+		"delete":           s.delete,
+		"move":             s.move,
+		"shuffle":          s.shuffle,
+		"lsinfo":           s.lsInfo,
+		"listall":          s.listAll(false),
+		"listallinfo":      s.listAll(true),
+		"update":           s.update,
+		"rescan":           s.update,
+		"outputs":          s.outputs,
+		"enableoutput":     s.setOutput(true),
+		"disableoutput":    s.setOutput(false),
+		"listplaylists":    s.listPlaylists,
+		"listplaylist":     s.listPlaylist(false),
+		"listplaylistinfo": s.listPlaylist(true),
+		"load":             s.load,
+		"save":             s.save,
+		"rm":               s.rm,
+		"rename":           s.rename,
+	}
+	maps.Copy(s.handlers, builtins)
+}
+
+func checkArgs(command string, args []string, minCount, maxCount int) error {
+	if len(args) < minCount || len(args) > maxCount {
+		return newAckError(AckArg, "wrong number of arguments for \"%s\"", command)
+	}
+	return nil
+}
+
+func parseInt(value string) (int, error) {
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, newAckError(AckArg, "Integer expected: %s", value)
+	}
+	return result, nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch value {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	}
+	return false, newAckError(AckArg, "Boolean (0/1) expected: %s", value)
+}
+
+// parseRange parses a position or a "start:end" range (end excluded, may be omitted)
+// and checks it against the queue length.
+func parseRange(value string, length int) (int, int, error) {
+	startValue, endValue, isRange := strings.Cut(value, ":")
+	start, err := parseInt(startValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	end := start + 1
+	if isRange {
+		end = length
+		if endValue != "" {
+			if end, err = parseInt(endValue); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if start < 0 || end > length || start >= end {
+		return 0, 0, newAckError(AckArg, "Bad song index")
+	}
+	return start, end, nil
+}
+
+func (s *Server) status(args []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &s.state
+	info := statusInfo{
+		Volume:         st.volume,
+		Repeat:         st.repeat,
+		Random:         st.random,
+		Single:         st.single,
+		Consume:        st.consume,
+		Playlist:       st.version,
+		PlaylistLength: len(st.queue),
+		State:          st.player,
+	}
+	if pos := st.currentPos(); pos >= 0 {
+		item := st.queue[pos]
+		info.Song, info.SongId = &pos, &item.Id
+		if st.player != StateStop {
+			elapsed, duration := st.elapsed, item.Song.Duration
+			songTime := fmt.Sprintf("%d:%d", int(elapsed.Seconds()), int(duration.Round(time.Second).Seconds()))
+			info.Time, info.Elapsed, info.Duration = &songTime, &elapsed, &duration
+		}
+		if next := pos + 1; next < len(st.queue) {
+			info.NextSong, info.NextSongId = &next, &st.queue[next].Id
+		}
+	}
+	return marshal(info)
+}
+
+func (s *Server) currentSong(args []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos := s.state.currentPos()
+	if pos < 0 {
+		return nil, nil
+	}
+	item := s.state.queue[pos]
+	info := newSongInfo(item.Song)
+	info.Pos, info.Id = &pos, &item.Id
+	return marshal(info)
+}
+
+// playPos starts playing the song at pos, or resumes the current one if pos is negative.
+func (s *Server) playPos(pos int) error {
+	s.mu.Lock()
+	st := &s.state
+	if pos < 0 {
+		pos = max(st.currentPos(), 0)
+		if st.player == StatePause {
+			st.player = StatePlay
+			s.mu.Unlock()
+			s.Notify("player")
+			return nil
+		}
+	}
+	if pos >= len(st.queue) {
+		s.mu.Unlock()
+		if len(st.queue) == 0 && pos == 0 {
+			return nil
+		}
+		return newAckError(AckArg, "Bad song index")
+	}
+	st.currentId = st.queue[pos].Id
+	st.player = StatePlay
+	st.elapsed = 0
+	s.mu.Unlock()
+	s.Notify("player")
+	return nil
+}
+
+func (s *Server) play(args []string) ([]string, error) {
+	if err := checkArgs("play", args, 0, 1); err != nil {
+		return nil, err
+	}
+	pos := -1
+	if len(args) == 1 {
+		var err error
+		if pos, err = parseInt(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	return nil, s.playPos(pos)
+}
+
+func (s *Server) playId(args []string) ([]string, error) {
+	if err := checkArgs("playid", args, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, s.playPos(-1)
+	}
+	id, err := parseInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	pos := s.state.posById(id)
+	s.mu.Unlock()
+	if pos < 0 {
+		return nil, newAckError(AckNoExist, "No such song")
+	}
+	return nil, s.playPos(pos)
+}
+
+func (s *Server) pause(args []string) ([]string, error) {
+	if err := checkArgs("pause", args, 0, 1); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	st := &s.state
+	pause := st.player == StatePlay
+	if len(args) == 1 {
+		var err error
+		if pause, err = parseBool(args[0]); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	switch {
+	case pause && st.player == StatePlay:
+		st.player = StatePause
+	case !pause && st.player == StatePause:
+		st.player = StatePlay
+	}
+	s.mu.Unlock()
+	s.Notify("player")
+	return nil, nil
+}
+
+func (s *Server) stop(args []string) ([]string, error) {
+	s.mu.Lock()
+	s.state.player = StateStop
+	s.state.elapsed = 0
+	s.mu.Unlock()
+	s.Notify("player")
+	return nil, nil
+}
+
+func (s *Server) step(delta int) ([]string, error) {
+	s.mu.Lock()
+	st := &s.state
+	if st.player == StateStop {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	pos := st.currentPos() + delta
+	if pos >= len(st.queue) && st.repeat {
+		pos = 0
+	}
+	if pos < 0 || pos >= len(st.queue) {
+		st.player = StateStop
+		st.elapsed = 0
+		if pos >= len(st.queue) {
+			st.currentId = -1
+		}
+	} else {
+		st.currentId = st.queue[pos].Id
+		st.elapsed = 0
+	}
+	s.mu.Unlock()
+	s.Notify("player")
+	return nil, nil
+}
+
+func (s *Server) next(args []string) ([]string, error) {
+	return s.step(1)
+}
+
+func (s *Server) previous(args []string) ([]string, error) {
+	return s.step(-1)
+}
+
+func (s *Server) seek(args []string) ([]string, error) {
+	if err := checkArgs("seek", args, 2, 2); err != nil {
+		return nil, err
+	}
+	pos, err := parseInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return nil, newAckError(AckArg, "Number expected: %s", args[1])
+	}
+	if err := s.playPos(pos); err != nil {
+		return nil, err
+	}
+	s.SetElapsed(time.Duration(seconds * float64(time.Second)))
+	return nil, nil
+}
+
+func (s *Server) setVol(args []string) ([]string, error) {
+	if err := checkArgs("setvol", args, 1, 1); err != nil {
+		return nil, err
+	}
+	volume, err := parseInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if volume < 0 || volume > 100 {
+		return nil, newAckError(AckArg, "Invalid volume value")
+	}
+	s.SetVolume(volume)
+	return nil, nil
+}
+
+func (s *Server) option(set func(st *state, value bool)) HandlerFunc {
+	return func(args []string) ([]string, error) {
+		if err := checkArgs("option", args, 1, 1); err != nil {
+			return nil, err
+		}
+		value, err := parseBool(args[0])
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		set(&s.state, value)
+		s.mu.Unlock()
+		s.Notify("options")
+		return nil, nil
+	}
+}
+
+func (s *Server) playlistInfo(args []string) ([]string, error) {
+	if err := checkArgs("playlistinfo", args, 0, 1); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start, end := 0, len(s.state.queue)
+	if len(args) == 1 {
+		var err error
+		if start, end, err = parseRange(args[0], len(s.state.queue)); err != nil {
+			return nil, err
+		}
+	}
+	var out answer
+	for pos := start; pos < end; pos++ {
+		item := s.state.queue[pos]
+		info := newSongInfo(item.Song)
+		info.Pos, info.Id = &pos, &item.Id
+		out.add(info)
+	}
+	return out.result()
+}
+
+// changeQueue runs change on the state and notifies about the queue change if it succeeds.
+func (s *Server) changeQueue(change func(st *state) ([]string, error)) ([]string, error) {
+	s.mu.Lock()
+	out, err := change(&s.state)
+	if err == nil {
+		s.state.queueChanged()
+	}
+	s.mu.Unlock()
+	if err == nil {
+		s.Notify("playlist")
+	}
+	return out, err
+}
+
+func (s *Server) clear(args []string) ([]string, error) {
+	return s.changeQueue(func(st *state) ([]string, error) {
+		st.queue = nil
+		return nil, nil
+	})
+}
+
+func (s *Server) add(args []string) ([]string, error) {
+	if err := checkArgs("add", args, 1, 2); err != nil {
+		return nil, err
+	}
+	return s.changeQueue(func(st *state) ([]string, error) {
+		songs, ok := st.songsUnder(args[0])
+		if !ok {
+			return nil, newAckError(AckNoExist, "No such directory")
+		}
+		pos := len(st.queue)
+		if len(args) == 2 {
+			var err error
+			if pos, err = parseInt(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		for i, song := range songs {
+			st.addToQueue(song, pos+i)
+		}
+		return nil, nil
+	})
+}
+
+func (s *Server) addId(args []string) ([]string, error) {
+	if err := checkArgs("addid", args, 1, 2); err != nil {
+		return nil, err
+	}
+	return s.changeQueue(func(st *state) ([]string, error) {
+		song, ok := st.songs[args[0]]
+		if !ok {
+			return nil, newAckError(AckNoExist, "No such song")
+		}
+		pos := -1
+		if len(args) == 2 {
+			var err error
+			if pos, err = parseInt(args[1]); err != nil {
+				return nil, err
+			}
+			if pos < 0 || pos > len(st.queue) {
+				return nil, newAckError(AckArg, "Bad song index")
+			}
+		}
+		return []string{fmt.Sprintf("Id: %d", st.addToQueue(song, pos))}, nil
+	})
+}
+
+func (s *Server) delete(args []string) ([]string, error) {
+	if err := checkArgs("delete", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return s.changeQueue(func(st *state) ([]string, error) {
+		start, end, err := parseRange(args[0], len(st.queue))
+		if err != nil {
+			return nil, err
+		}
+		st.queue = slices.Delete(st.queue, start, end)
+		return nil, nil
+	})
+}
+
+func (s *Server) move(args []string) ([]string, error) {
+	if err := checkArgs("move", args, 2, 2); err != nil {
+		return nil, err
+	}
+	return s.changeQueue(func(st *state) ([]string, error) {
+		start, end, err := parseRange(args[0], len(st.queue))
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		moved := slices.Clone(st.queue[start:end])
+		rest := slices.Delete(slices.Clone(st.queue), start, end)
+		if to < 0 || to > len(rest) {
+			return nil, newAckError(AckArg, "Bad song index")
+		}
+		st.queue = slices.Insert(rest, to, moved...)
+		return nil, nil
+	})
+}
+
+func (s *Server) shuffle(args []string) ([]string, error) {
+	if err := checkArgs("shuffle", args, 0, 1); err != nil {
+		return nil, err
+	}
+	return s.changeQueue(func(st *state) ([]string, error) {
+		start, end := 0, len(st.queue)
+		if len(args) == 1 {
+			var err error
+			if start, end, err = parseRange(args[0], len(st.queue)); err != nil {
+				return nil, err
+			}
+		}
+		part := st.queue[start:end]
+		s.rand.Shuffle(len(part), func(i, j int) {
+			part[i], part[j] = part[j], part[i]
+		})
+		return nil, nil
+	})
+}
+
+func (s *Server) lsInfo(args []string) ([]string, error) {
+	if err := checkArgs("lsinfo", args, 0, 1); err != nil {
+		return nil, err
+	}
+	dir := ""
+	if len(args) == 1 {
+		dir = strings.Trim(args[0], "/")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &s.state
+	if song, ok := st.songs[dir]; ok {
+		return marshal(newSongInfo(song))
+	}
+	if !st.isDirectory(dir) {
+		return nil, newAckError(AckNoExist, "No such directory")
+	}
+	dirs, songs := st.children(dir)
+	var out answer
+	for _, d := range dirs {
+		out.add(directoryInfo{Directory: d})
+	}
+	for _, song := range songs {
+		out.add(newSongInfo(song))
+	}
+	if dir == "" {
+		for _, name := range slices.Sorted(maps.Keys(st.playlists)) {
+			p := st.playlists[name]
+			out.add(playlistInfo{Playlist: name, LastModified: &p.LastModified})
+		}
+	}
+	return out.result()
+}
+
+func (s *Server) listAll(withInfo bool) HandlerFunc {
+	return func(args []string) ([]string, error) {
+		if err := checkArgs("listall", args, 0, 1); err != nil {
+			return nil, err
+		}
+		dir := ""
+		if len(args) == 1 {
+			dir = strings.Trim(args[0], "/")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		st := &s.state
+		var out answer
+		addSong := func(song Song) {
+			if withInfo {
+				out.add(newSongInfo(song))
+				return
+			}
+			out.addLine("file: " + song.File)
+		}
+		if song, ok := st.songs[dir]; ok {
+			addSong(song)
+			return out.result()
+		}
+		if !st.isDirectory(dir) {
+			return nil, newAckError(AckNoExist, "No such directory")
+		}
+		var walk func(dir string)
+		walk = func(dir string) {
+			// Like MPD, the songs of a directory go before its subdirectories.
+			dirs, songs := st.children(dir)
+			for _, song := range songs {
+				addSong(song)
+			}
+			for _, d := range dirs {
+				out.addLine("directory: " + d)
+				walk(d)
+			}
+		}
+		walk(dir)
+		return out.result()
+	}
+}
+
+// update runs a database update job. The job finishes immediately,
+// unless the update command is overridden with Handle.
+func (s *Server) update(args []string) ([]string, error) {
+	if err := checkArgs("update", args, 0, 1); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if len(args) == 1 && !s.state.isDirectory(args[0]) {
+		if _, ok := s.state.songs[args[0]]; !ok {
+			s.mu.Unlock()
+			return nil, newAckError(AckNoExist, "Malformed path")
+		}
+	}
+	s.state.lastJob++
+	job := s.state.lastJob
+	s.mu.Unlock()
+	s.Notify("update", "database")
+	return []string{fmt.Sprintf("updating_db: %d", job)}, nil
+}
+
+func (s *Server) outputs(args []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out answer
+	for _, o := range s.state.outputs {
+		out.add(outputInfo{Id: o.Id, Name: o.Name, Plugin: "null", Enabled: o.Enabled})
+	}
+	return out.result()
+}
+
+func (s *Server) setOutput(enabled bool) HandlerFunc {
+	return func(args []string) ([]string, error) {
+		if err := checkArgs("enableoutput", args, 1, 1); err != nil {
+			return nil, err
+		}
+		id, err := parseInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if id < 0 || id >= len(s.state.outputs) {
+			s.mu.Unlock()
+			return nil, newAckError(AckNoExist, "No such audio output")
+		}
+		s.state.outputs[id].Enabled = enabled
+		s.mu.Unlock()
+		s.Notify("output")
+		return nil, nil
+	}
+}
+
+func (s *Server) listPlaylists(args []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out answer
+	for _, name := range slices.Sorted(maps.Keys(s.state.playlists)) {
+		p := s.state.playlists[name]
+		out.add(playlistInfo{Playlist: name, LastModified: &p.LastModified})
+	}
+	return out.result()
+}
+
+func (s *Server) listPlaylist(withInfo bool) HandlerFunc {
+	return func(args []string) ([]string, error) {
+		if err := checkArgs("listplaylist", args, 1, 1); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		p, ok := s.state.playlists[args[0]]
+		if !ok {
+			return nil, newAckError(AckNoExist, "No such playlist")
+		}
+		var out answer
+		for _, file := range p.Files {
+			song, ok := s.state.songs[file]
+			if !withInfo || !ok {
+				out.addLine("file: " + file)
+				continue
+			}
+			out.add(newSongInfo(song))
+		}
+		return out.result()
+	}
+}
+
+func (s *Server) load(args []string) ([]string, error) {
+	if err := checkArgs("load", args, 1, 2); err != nil {
+		return nil, err
+	}
+	return s.changeQueue(func(st *state) ([]string, error) {
+		p, ok := st.playlists[args[0]]
+		if !ok {
+			return nil, newAckError(AckNoExist, "No such playlist")
+		}
+		files := p.Files
+		if len(args) == 2 {
+			start, end, err := parseRange(args[1], len(files))
+			if err != nil {
+				return nil, err
+			}
+			files = files[start:end]
+		}
+		for _, file := range files {
+			song, ok := st.songs[file]
+			if !ok {
+				song = Song{File: file}
+			}
+			st.addToQueue(song, -1)
+		}
+		return nil, nil
+	})
+}
+
+// changePlaylists runs change on the state and notifies about the stored playlists change if it succeeds.
+func (s *Server) changePlaylists(change func(st *state) error) ([]string, error) {
+	s.mu.Lock()
+	err := change(&s.state)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	s.Notify("stored_playlist")
+	return nil, nil
+}
+
+func (s *Server) save(args []string) ([]string, error) {
+	if err := checkArgs("save", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return s.changePlaylists(func(st *state) error {
+		if _, ok := st.playlists[args[0]]; ok {
+			return newAckError(AckExist, "Playlist already exists")
+		}
+		files := make([]string, len(st.queue))
+		for i, item := range st.queue {
+			files[i] = item.Song.File
+		}
+		st.playlists[args[0]] = &StoredPlaylist{Name: args[0], LastModified: now(), Files: files}
+		return nil
+	})
+}
+
+func (s *Server) rm(args []string) ([]string, error) {
+	if err := checkArgs("rm", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return s.changePlaylists(func(st *state) error {
+		if _, ok := st.playlists[args[0]]; !ok {
+			return newAckError(AckNoExist, "No such playlist")
+		}
+		delete(st.playlists, args[0])
+		return nil
+	})
+}
+
+func (s *Server) rename(args []string) ([]string, error) {
+	if err := checkArgs("rename", args, 2, 2); err != nil {
+		return nil, err
+	}
+	return s.changePlaylists(func(st *state) error {
+		p, ok := st.playlists[args[0]]
+		if !ok {
+			return newAckError(AckNoExist, "No such playlist")
+		}
+		if _, ok := st.playlists[args[1]]; ok {
+			return newAckError(AckExist, "Playlist already exists")
+		}
+		delete(st.playlists, args[0])
+		p.Name = args[1]
+		p.LastModified = now()
+		st.playlists[args[1]] = p
+		return nil
+	})
+}
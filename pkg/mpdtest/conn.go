@@ -0,0 +1,274 @@
+package mpdtest
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// subsystems are the idle subsystems in the order they are reported.
+var subsystems = []string{
+	"database", "update", "stored_playlist", "playlist", "player", "mixer",
+	"output", "options", "partition", "sticker", "subscription", "message",
+}
+
+// conn serves a single client connection.
+type conn struct {
+	server  *Server
+	netConn net.Conn
+	w       *bufio.Writer
+	lines   chan string
+	done    chan struct{}
+	// authenticated reports whether the client sent the correct password.
+	authenticated bool
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+	events  chan struct{}
+}
+
+func newConn(server *Server, netConn net.Conn) *conn {
+	return &conn{
+		server:  server,
+		netConn: netConn,
+		w:       bufio.NewWriter(netConn),
+		lines:   make(chan string),
+		done:    make(chan struct{}),
+		pending: make(map[string]struct{}),
+		events:  make(chan struct{}, 1),
+	}
+}
+
+// notify stores the changed subsystems until the client asks for them with idle.
+func (c *conn) notify(changed []string) {
+	c.mu.Lock()
+	for _, subsystem := range changed {
+		c.pending[subsystem] = struct{}{}
+	}
+	c.mu.Unlock()
+	select {
+	case c.events <- struct{}{}:
+	default:
+	}
+}
+
+// takePending removes and returns the pending subsystems matching filter (all if filter is empty).
+func (c *conn) takePending(filter []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var result []string
+	for _, subsystem := range subsystems {
+		if _, ok := c.pending[subsystem]; !ok {
+			continue
+		}
+		if len(filter) > 0 && !slices.Contains(filter, subsystem) {
+			continue
+		}
+		delete(c.pending, subsystem)
+		result = append(result, subsystem)
+	}
+	return result
+}
+
+func (c *conn) run() {
+	defer close(c.done)
+	defer c.netConn.Close()
+	go c.readLines()
+	if !c.write("OK MPD " + Version) {
+		return
+	}
+	for line := range c.lines {
+		name, args, err := splitCommand(line)
+		if err != nil {
+			if !c.write(ackLine(newAckError(AckArg, "%v", err), 0, "")) {
+				return
+			}
+			continue
+		}
+		switch name {
+		case "close":
+			return
+		case "idle":
+			c.server.record(line)
+			if !c.idle(args) {
+				return
+			}
+		case "noidle":
+			// MPD ignores noidle when the client is not idling.
+			c.server.record(line)
+		case "command_list_begin", "command_list_ok_begin":
+			if !c.commandList(name == "command_list_ok_begin") {
+				return
+			}
+		default:
+			out, err := c.execute(line, name, args)
+			if errors.Is(err, ErrCloseConnection) {
+				return
+			}
+			if err != nil {
+				out = append(out, ackLine(err, 0, name))
+			} else {
+				out = append(out, "OK")
+			}
+			if !c.write(out...) {
+				return
+			}
+		}
+	}
+}
+
+func (c *conn) readLines() {
+	defer close(c.lines)
+	scanner := bufio.NewScanner(c.netConn)
+	for scanner.Scan() {
+		select {
+		case c.lines <- strings.TrimSuffix(scanner.Text(), "\r"):
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *conn) write(lines ...string) bool {
+	for _, line := range lines {
+		if _, err := c.w.WriteString(line + "\n"); err != nil {
+			return false
+		}
+	}
+	return c.w.Flush() == nil
+}
+
+// execute runs a command and returns its answer lines without the final OK.
+func (c *conn) execute(line, name string, args []string) ([]string, error) {
+	c.server.record(line)
+	switch name {
+	case "password":
+		if len(args) != 1 {
+			return nil, newAckError(AckArg, "wrong number of arguments for \"password\"")
+		}
+		if _, ok := c.server.checkPassword(args[0]); !ok {
+			return nil, newAckError(AckPassword, "incorrect password")
+		}
+		c.authenticated = true
+		return nil, nil
+	case "ping":
+		return nil, nil
+	}
+	if required, _ := c.server.checkPassword(""); required && !c.authenticated {
+		return nil, newAckError(AckPermission, "you don't have permission for \"%s\"", name)
+	}
+	handler, ok := c.server.handler(name)
+	if !ok {
+		return nil, newAckError(AckUnknown, "unknown command \"%s\"", name)
+	}
+	return handler(args)
+}
+
+// idle answers the idle command once one of the subsystems in filter changes,
+// or immediately with no subsystem when the client sends noidle.
+func (c *conn) idle(filter []string) bool {
+	for {
+		if changed := c.takePending(filter); len(changed) > 0 {
+			out := make([]string, 0, len(changed)+1)
+			for _, subsystem := range changed {
+				out = append(out, "changed: "+subsystem)
+			}
+			return c.write(append(out, "OK")...)
+		}
+		select {
+		case <-c.events:
+		case line, ok := <-c.lines:
+			if !ok {
+				return false
+			}
+			c.server.record(line)
+			if strings.TrimSpace(line) != "noidle" {
+				// Only noidle is allowed while idling, MPD closes the connection otherwise.
+				return false
+			}
+			return c.write("OK")
+		case <-c.server.closed:
+			return false
+		}
+	}
+}
+
+// commandList reads the commands up to command_list_end and executes them in order.
+// Execution stops at the first failing command.
+func (c *conn) commandList(listOK bool) bool {
+	var lines []string
+	for line := range c.lines {
+		if strings.TrimSpace(line) == "command_list_end" {
+			out, ok := c.executeList(lines, listOK)
+			return ok && c.write(out...)
+		}
+		lines = append(lines, line)
+	}
+	return false
+}
+
+// executeList returns the answer to the commands of a command list,
+// or false if the connection must be closed.
+func (c *conn) executeList(lines []string, listOK bool) ([]string, bool) {
+	var out []string
+	for i, line := range lines {
+		name, args, err := splitCommand(line)
+		if err == nil {
+			var answer []string
+			answer, err = c.execute(line, name, args)
+			out = append(out, answer...)
+		}
+		if errors.Is(err, ErrCloseConnection) {
+			return nil, false
+		}
+		if err != nil {
+			return append(out, ackLine(err, i, name)), true
+		}
+		if listOK {
+			out = append(out, "list_OK")
+		}
+	}
+	return append(out, "OK"), true
+}
+
+// splitCommand splits a command line into the command name and its arguments,
+// unquoting the arguments enclosed in double quotes.
+func splitCommand(line string) (string, []string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, escaped, hasToken := false, false, false
+	for _, r := range strings.TrimSpace(line) {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case inQuotes && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			hasToken = true
+		case !inQuotes && (r == ' ' || r == '\t'):
+			if hasToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				hasToken = false
+			}
+		default:
+			current.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if inQuotes || escaped {
+		return "", nil, errors.New("missing closing '\"'")
+	}
+	if hasToken {
+		tokens = append(tokens, current.String())
+	}
+	if len(tokens) == 0 {
+		return "", nil, errors.New("no command given")
+	}
+	return tokens[0], tokens[1:], nil
+}
@@ -0,0 +1,40 @@
+package mpdtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line     string
+		name     string
+		args     []string
+		hasError bool
+	}{
+		{line: "status", name: "status"},
+		{line: "  play 1 ", name: "play", args: []string{"1"}},
+		{line: `add "a b/c.mp3"`, name: "add", args: []string{"a b/c.mp3"}},
+		{line: `rename "say \"hi\"" "back\\slash"`, name: "rename", args: []string{`say "hi"`, `back\slash`}},
+		{line: `save ""`, name: "save", args: []string{""}},
+		{line: `add "unclosed`, hasError: true},
+		{line: "", hasError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			name, args, err := splitCommand(tt.line)
+			if tt.hasError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.name, name)
+			if len(tt.args) == 0 {
+				assert.Empty(t, args)
+				return
+			}
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
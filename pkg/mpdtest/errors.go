@@ -0,0 +1,52 @@
+package mpdtest
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AckCode is an MPD protocol error code sent in ACK answers.
+type AckCode int
+
+const (
+	AckNotList       AckCode = 1
+	AckArg           AckCode = 2
+	AckPassword      AckCode = 3
+	AckPermission    AckCode = 4
+	AckUnknown       AckCode = 5
+	AckNoExist       AckCode = 50
+	AckPlaylistMax   AckCode = 51
+	AckSystem        AckCode = 52
+	AckPlaylistLoad  AckCode = 53
+	AckUpdateAlready AckCode = 54
+	AckPlayerSync    AckCode = 55
+	AckExist         AckCode = 56
+)
+
+// ErrCloseConnection is returned by a HandlerFunc to make the server close the connection
+// without answering, as if it was lost.
+var ErrCloseConnection = errors.New("close connection")
+
+// AckError is returned by a HandlerFunc to make the server answer with an ACK line.
+// Any other error is sent as an AckUnknown error.
+type AckError struct {
+	Code    AckCode
+	Message string
+}
+
+func (e *AckError) Error() string {
+	return fmt.Sprintf("ACK %d: %s", e.Code, e.Message)
+}
+
+func newAckError(code AckCode, format string, args ...any) *AckError {
+	return &AckError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ackLine formats the ACK answer to the command with the given index in a command list.
+func ackLine(err error, index int, command string) string {
+	ack, ok := err.(*AckError)
+	if !ok {
+		ack = &AckError{Code: AckUnknown, Message: err.Error()}
+	}
+	return fmt.Sprintf("ACK [%d@%d] {%s} %s", ack.Code, index, command, ack.Message)
+}
@@ -0,0 +1,90 @@
+package mpdtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleEvents(t *testing.T) {
+	t.Run("only the configured events are received", func(t *testing.T) {
+		server := newTestServer(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 1, time.Second, time.Second,
+			mpdapi.WithDialer(server.Dial), mpdapi.WithIdleEvents(mpdapi.ON_PLAYER_CHANGED))
+		require.NoError(t, err)
+		events := api.Subscribe(time.Second)
+		require.NoError(t, api.Connect())
+		t.Cleanup(func() { _ = api.Disconnect() })
+		waitForEvent(t, events, mpdapi.ON_CONNECT)
+
+		server.Notify("mixer")
+		server.Notify("player")
+		assert.Equal(t, mpdapi.ON_PLAYER_CHANGED, nextEvent(t, events))
+		assertNoEvent(t, events)
+		assert.Contains(t, server.Commands(), `idle "player"`)
+
+		require.NoError(t, api.SetIdleEvents(mpdapi.ON_MIXER_CHANGED))
+		assert.Equal(t, mpdapi.ON_MIXER_CHANGED, nextEvent(t, events))
+		server.Notify("player")
+		assertNoEvent(t, events)
+		assert.Contains(t, server.Commands(), "noidle")
+		assert.Contains(t, server.Commands(), `idle "mixer"`)
+	})
+	t.Run("the cache events are always received", func(t *testing.T) {
+		server := newTestServer(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		api, err := mpdapi.NewMpdApi(ctx, "", 0, "", true, 100, 1, time.Second, time.Second,
+			mpdapi.WithDialer(server.Dial), mpdapi.WithIdleEvents(mpdapi.ON_PLAYER_CHANGED))
+		require.NoError(t, err)
+		require.NoError(t, api.Connect())
+		t.Cleanup(func() { _ = api.Disconnect() })
+		assert.Eventually(t, func() bool {
+			for _, command := range server.Commands() {
+				if command == `idle "player" "database" "playlist"` {
+					return true
+				}
+			}
+			return false
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("disconnect interrupts idle with noidle", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+		assert.Eventually(t, func() bool {
+			stats, err := api.PoolStats()
+			return err == nil && stats.IdleWatcher.Waiting
+		}, time.Second, time.Millisecond)
+		require.NoError(t, api.Disconnect())
+		assert.Eventually(t, func() bool {
+			commands := server.Commands()
+			return len(commands) > 0 && commands[len(commands)-1] == "noidle"
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func nextEvent(t *testing.T, events chan mpdapi.MpdEventType) mpdapi.MpdEventType {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return mpdapi.UNKNOWN
+	}
+}
+
+func assertNoEvent(t *testing.T, events chan mpdapi.MpdEventType) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
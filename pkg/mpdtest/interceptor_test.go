@@ -0,0 +1,73 @@
+package mpdtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptors(t *testing.T) {
+	server := newTestServer(t)
+	errNotAllowed := errors.New("not allowed")
+	allowList := func(ctx context.Context, cmds []mpdapi.Command, yield func(string) bool, next mpdapi.Invoker) error {
+		for _, cmd := range cmds {
+			if cmd.Name == "clear" {
+				return fmt.Errorf("%w: %s", errNotAllowed, cmd.Name)
+			}
+		}
+		return next(ctx, cmds, yield)
+	}
+	var mu sync.Mutex
+	var logged []string
+	logging := func(ctx context.Context, cmds []mpdapi.Command, yield func(string) bool, next mpdapi.Invoker) error {
+		mu.Lock()
+		for _, cmd := range cmds {
+			logged = append(logged, cmd.String())
+		}
+		mu.Unlock()
+		return next(ctx, cmds, yield)
+	}
+	// rewriting lists a directory instead of the missing one.
+	rewriting := func(ctx context.Context, cmds []mpdapi.Command, yield func(string) bool, next mpdapi.Invoker) error {
+		for i, cmd := range cmds {
+			if cmd.Name == "listall" && len(cmd.Args) == 1 && cmd.Args[0] == "missing" {
+				cmds[i] = mpdapi.Command{Name: "listall", Args: []string{"a/b"}}
+			}
+		}
+		return next(ctx, cmds, yield)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 2, time.Second, time.Second,
+		mpdapi.WithDialer(server.Dial), mpdapi.WithInterceptors(allowList, logging, rewriting))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	defer func() { _ = api.Disconnect() }()
+
+	require.NoError(t, api.Add("missing"))
+	queue := server.Queue()
+	require.Len(t, queue, 1)
+	assert.Equal(t, "a/b/2.mp3", queue[0].Song.File)
+
+	assert.ErrorIs(t, api.Clear(), errNotAllowed)
+	assert.Len(t, server.Queue(), 1)
+	assert.NotContains(t, server.Commands(), "clear")
+
+	status, err := api.Status()
+	require.NoError(t, err)
+	assert.Equal(t, 1, *status.PlaylistLength)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, logged, `listall "missing"`)
+	assert.Contains(t, logged, `add "a/b/2.mp3"`)
+	assert.Contains(t, logged, "status")
+	assert.NotContains(t, logged, "clear")
+}
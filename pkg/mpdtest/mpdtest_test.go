@@ -0,0 +1,242 @@
+package mpdtest_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *mpdtest.Server {
+	t.Helper()
+	server := mpdtest.NewServer()
+	t.Cleanup(func() { _ = server.Close() })
+	server.AddSongs(
+		mpdtest.Song{File: "a/1.mp3", Duration: time.Minute, Tags: map[string][]string{"Artist": {"A"}, "Title": {"One"}}},
+		mpdtest.Song{File: "a/b/2.mp3", Duration: 2 * time.Minute},
+		mpdtest.Song{File: "3.mp3", Duration: 3 * time.Minute},
+	)
+	return server
+}
+
+func connect(t *testing.T, password string, dialer func() (net.Conn, error)) mpdapi.MpdApi {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, password, false, 100, 2, time.Second, time.Second, mpdapi.WithDialer(dialer))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	t.Cleanup(func() {
+		_ = api.Disconnect()
+		cancel()
+	})
+	return api
+}
+
+func TestServer(t *testing.T) {
+	t.Run("queue and player", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+
+		require.NoError(t, api.Add("a"))
+		require.NoError(t, api.Add("3.mp3"))
+		playlist, err := api.Playlist()
+		require.NoError(t, err)
+		require.Len(t, playlist.Items, 3)
+		assert.Equal(t, "a/1.mp3", playlist.Items[0].File)
+		assert.Equal(t, []string{"A"}, playlist.Items[0].Artist)
+		assert.Equal(t, "a/b/2.mp3", playlist.Items[1].File)
+		assert.Equal(t, "3.mp3", playlist.Items[2].File)
+		assert.Equal(t, 2, playlist.Items[2].Pos)
+
+		require.NoError(t, api.PlayPos(1))
+		status, err := api.Status()
+		require.NoError(t, err)
+		assert.Equal(t, "play", *status.State)
+		assert.Equal(t, 1, *status.Song)
+		assert.Equal(t, 3, *status.PlaylistLength)
+		assert.Equal(t, 2, *status.NextSong)
+
+		require.NoError(t, api.Pause())
+		state, pos := server.PlayerState()
+		assert.Equal(t, mpdtest.StatePause, state)
+		assert.Equal(t, 1, pos)
+
+		require.NoError(t, api.Move(0, 2))
+		queue := server.Queue()
+		assert.Equal(t, "a/1.mp3", queue[2].Song.File)
+
+		require.NoError(t, api.Clear())
+		status, err = api.Status()
+		require.NoError(t, err)
+		assert.Equal(t, "stop", *status.State)
+		assert.Nil(t, status.Song)
+	})
+	t.Run("settings and outputs", func(t *testing.T) {
+		server := newTestServer(t)
+		id := server.AddOutput("second", false)
+		api := connect(t, "", server.Dial)
+
+		require.NoError(t, api.Random(true))
+		require.NoError(t, api.EnableOutput(id))
+		require.NoError(t, api.DisableOutput(0))
+		status, err := api.Status()
+		require.NoError(t, err)
+		assert.True(t, *status.Random)
+		outputs, err := api.ListOutputs()
+		require.NoError(t, err)
+		require.Len(t, outputs, 2)
+		assert.False(t, outputs[0].Enabled)
+		assert.True(t, outputs[1].Enabled)
+	})
+	t.Run("stored playlists", func(t *testing.T) {
+		server := newTestServer(t)
+		server.AddStoredPlaylist("stored", "3.mp3", "a/1.mp3")
+		api := connect(t, "", server.Dial)
+
+		require.NoError(t, api.AddStoredToPos("stored", 0))
+		require.NoError(t, api.SaveCurrentPlaylistAsStored("copy"))
+		require.NoError(t, api.RenameStoredPlaylist("copy", "renamed"))
+		playlists, err := api.GetPlaylists()
+		require.NoError(t, err)
+		require.Len(t, playlists, 2)
+		assert.Equal(t, "renamed", *playlists[0].Name)
+		renamed, ok := server.StoredPlaylist("renamed")
+		require.True(t, ok)
+		assert.Equal(t, []string{"3.mp3", "a/1.mp3"}, renamed.Files)
+
+		require.NoError(t, api.DeleteStoredPlaylist("stored"))
+		assert.Error(t, api.DeleteStoredPlaylist("stored"))
+	})
+	t.Run("database", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+
+		tree, err := api.Tree()
+		require.NoError(t, err)
+		file, ok := tree.FindFile("a/b/2.mp3")
+		require.True(t, ok)
+		assert.Equal(t, "2.mp3", file.Name)
+
+		items, err := api.LsInfo("a")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, "a/b", items[0].GetPath())
+		assert.False(t, items[0].IsLeaf())
+		assert.Equal(t, "a/1.mp3", items[1].GetPath())
+
+		_, err = api.LsInfo("missing")
+		assert.Error(t, err)
+
+		// An answer which can't be written as MPD protocol is an ACK.
+		server.AddSongs(mpdtest.Song{File: "a/4.mp3", Tags: map[string][]string{"Title": {"two\nlines"}}})
+		_, err = api.LsInfo("a")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line break")
+		server.RemoveSongs("a/4.mp3")
+
+		jobId, err := api.UpdateDB("")
+		require.NoError(t, err)
+		assert.Equal(t, 1, jobId)
+		status, err := api.Status()
+		require.NoError(t, err)
+		assert.Nil(t, status.UpdatingDb)
+	})
+	t.Run("idle events", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+		events := api.Subscribe(time.Second)
+		defer api.Unsubscribe(events)
+
+		require.NoError(t, api.Repeat(true))
+		waitForEvent(t, events, mpdapi.ON_OPTIONS_CHANGED)
+		server.AddSongs(mpdtest.Song{File: "4.mp3"})
+		waitForEvent(t, events, mpdapi.ON_DATABASE_CHANGED)
+		server.Notify("sticker")
+		waitForEvent(t, events, mpdapi.ON_STICKER_CHANGED)
+	})
+	t.Run("password", func(t *testing.T) {
+		server := newTestServer(t)
+		server.SetPassword("secret")
+		api := connect(t, "secret", server.Dial)
+
+		_, err := api.Status()
+		assert.NoError(t, err)
+		assert.Contains(t, server.Commands(), `password "secret"`)
+	})
+	t.Run("custom handler", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Handle("stop", func(args []string) ([]string, error) {
+			return nil, &mpdtest.AckError{Code: mpdtest.AckSystem, Message: "output failed"}
+		})
+		api := connect(t, "", server.Dial)
+
+		err := api.Stop()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "output failed")
+	})
+	t.Run("tcp listener", func(t *testing.T) {
+		server := newTestServer(t)
+		address, err := server.Listen()
+		require.NoError(t, err)
+		api := connect(t, "", func() (net.Conn, error) {
+			return net.Dial("tcp", address)
+		})
+
+		require.NoError(t, api.Add("3.mp3"))
+		assert.Len(t, server.Queue(), 1)
+		assert.Positive(t, server.ConnectionCount())
+	})
+}
+
+func TestServer_protocol(t *testing.T) {
+	server := newTestServer(t)
+	c, err := server.Dial()
+	require.NoError(t, err)
+	defer c.Close()
+	require.NoError(t, c.SetDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 4096)
+	exchange := func(request string) string {
+		if request != "" {
+			_, err := c.Write([]byte(request))
+			require.NoError(t, err)
+		}
+		var answer strings.Builder
+		for !strings.HasSuffix(answer.String(), "OK\n") && !strings.Contains(answer.String(), "ACK ") &&
+			!strings.HasPrefix(answer.String(), "OK MPD") {
+			n, err := c.Read(buf)
+			require.NoError(t, err)
+			answer.Write(buf[:n])
+		}
+		return answer.String()
+	}
+
+	assert.Equal(t, "OK MPD "+mpdtest.Version+"\n", exchange(""))
+	assert.Equal(t, "Id: 1\nOK\n", exchange("addid \"a/1.mp3\"\n"))
+	assert.Equal(t, "list_OK\nlist_OK\nOK\n",
+		exchange("command_list_ok_begin\nrepeat 1\nsetvol 50\ncommand_list_end\n"))
+	assert.Equal(t, "ACK [50@1] {playid} No such song\n",
+		exchange("command_list_begin\nplayid 1\nplayid 7\ncommand_list_end\n"))
+	assert.Equal(t, "ACK [5@0] {foo} unknown command \"foo\"\n", exchange("foo\n"))
+	assert.Equal(t, "changed: playlist\nchanged: player\nchanged: mixer\nchanged: options\nOK\n", exchange("idle\n"))
+}
+
+func waitForEvent(t *testing.T, events chan mpdapi.MpdEventType, expected mpdapi.MpdEventType) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event == expected {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("event %v not received", expected)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package mpdtest_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSizing(t *testing.T) {
+	server := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 4, time.Second, 20*time.Millisecond,
+		mpdapi.WithDialer(server.Dial),
+		mpdapi.WithPoolSizing(mpdapi.PoolSizing{MinSize: 0, MaxSize: 2, IdleTimeout: 50 * time.Millisecond}))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	defer func() { _ = api.Disconnect() }()
+	// Only the connection watching the idle events is open.
+	assert.Equal(t, 1, server.ConnectionCount())
+
+	_, err = api.Status()
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.ConnectionCount())
+
+	assert.Eventually(t, func() bool { return server.ConnectionCount() == 1 }, time.Second, 5*time.Millisecond)
+	_, err = api.Status()
+	require.NoError(t, err)
+}
+
+func TestConcurrentCommands(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	server := newTestServer(t)
+	server.Handle("status", func(args []string) ([]string, error) {
+		time.Sleep(delay)
+		return []string{"volume: 100"}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 4, time.Second, time.Second,
+		mpdapi.WithDialer(server.Dial),
+		mpdapi.WithPoolSizing(mpdapi.PoolSizing{MinSize: 1, MaxSize: 4}))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	defer func() { _ = api.Disconnect() }()
+
+	// The commands are sent on as many connections, the pool grows to run them at once.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := api.Status()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Less(t, time.Since(start), 2*delay)
+	stats, err := api.PoolStats()
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Open)
+}
+
+func TestPoolStats(t *testing.T) {
+	server := newTestServer(t)
+	api := connect(t, "", server.Dial)
+
+	assert.Eventually(t, func() bool {
+		stats, err := api.PoolStats()
+		return err == nil && stats.IdleWatcher.Waiting
+	}, time.Second, time.Millisecond)
+	_, err := api.Status()
+	require.NoError(t, err)
+	server.Notify("mixer")
+	assert.Eventually(t, func() bool {
+		stats, err := api.PoolStats()
+		return err == nil && stats.IdleWatcher.Events == 1
+	}, time.Second, time.Millisecond)
+
+	stats, err := api.PoolStats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Open)
+	assert.Equal(t, 0, stats.InUse)
+	assert.Equal(t, uint64(1), stats.Waits)
+	assert.Len(t, stats.Connections, 2)
+
+	require.NoError(t, api.Disconnect())
+	_, err = api.PoolStats()
+	assert.Error(t, err)
+}
+
+func TestPoolWaits(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	server := newTestServer(t)
+	server.Handle("status", func(args []string) ([]string, error) {
+		time.Sleep(delay)
+		return []string{"volume: 100"}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 1, time.Second, time.Second, mpdapi.WithDialer(server.Dial))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	defer func() { _ = api.Disconnect() }()
+
+	// The commands wait for the single connection in turn.
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := api.Status()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	stats, err := api.PoolStats()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.Waits)
+	assert.GreaterOrEqual(t, stats.WaitP99, delay)
+	assert.GreaterOrEqual(t, stats.WaitMax, 2*delay-10*time.Millisecond)
+}
+
+func TestCommandsWhileStreaming(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		poolSize uint8
+	}{
+		{name: "single connection", poolSize: 1},
+		{name: "two connections", poolSize: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, tt.poolSize, time.Second, time.Second, mpdapi.WithDialer(server.Dial))
+			require.NoError(t, err)
+			require.NoError(t, api.Connect())
+			defer func() { _ = api.Disconnect() }()
+			require.NoError(t, api.Add("a"))
+
+			for _, err := range api.PlaylistSeq() {
+				require.NoError(t, err)
+				// The connection streaming the playlist is busy until the loop ends,
+				// a connection is left to the other commands.
+				requestCtx, requestCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				_, err = api.WithRequestContext(requestCtx).Status()
+				requestCancel()
+				assert.NoError(t, err)
+				break
+			}
+			for _, err := range api.ListAllInfoSeq("") {
+				require.NoError(t, err)
+				for _, err := range api.PlaylistSeq() {
+					require.NoError(t, err)
+					_, err = api.Status()
+					assert.NoError(t, err)
+					break
+				}
+				break
+			}
+			// The connection of a stream is closed at its end if it's not one of the pool.
+			assert.Eventually(t, func() bool {
+				stats, err := api.PoolStats()
+				return err == nil && server.ConnectionCount() == stats.Open+1
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package mpdtest
+
+import (
+	"io"
+	"net"
+
+	"github.com/anpotashev/mpdgo/internal/mpdrw"
+)
+
+// ErrReplayMismatch is reported by Replayer.Err when a command does not match the recording.
+var ErrReplayMismatch = mpdrw.ErrReplayMismatch
+
+// Replayer serves back a recording written with mpdapi.WithRecorder, to reproduce
+// a session deterministically:
+//
+//	replayer, err := mpdtest.NewReplayer(file)
+//	api, _ := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 2, time.Second, time.Second,
+//		mpdapi.WithDialer(replayer.Dial))
+//
+// Every dial gets the next recorded connection. A command is answered with the answer recorded
+// for the same command, first looked up on its own connection, then on the others, as the pool
+// may use its connections in a different order. Pings are answered even if not recorded and
+// an idle command with no recorded answer waits for noidle. The recorded timing is not reproduced.
+// A command that does not match the recording closes the connection and is reported by Err.
+type Replayer struct {
+	replayer *mpdrw.Replayer
+}
+
+// NewReplayer reads the recording from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	replayer, err := mpdrw.NewReplayer(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{replayer: replayer}, nil
+}
+
+// Dial opens a connection serving the next recorded connection.
+func (r *Replayer) Dial() (net.Conn, error) {
+	return r.replayer.Dial()
+}
+
+// Err returns the first command that did not match the recording, wrapping ErrReplayMismatch.
+func (r *Replayer) Err() error {
+	return r.replayer.Err()
+}
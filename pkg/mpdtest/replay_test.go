@@ -0,0 +1,67 @@
+package mpdtest_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func TestReplayer(t *testing.T) {
+	session := func(api mpdapi.MpdApi) (*mpdapi.Playlist, mpdapi.Status) {
+		require.NoError(t, api.Add("a"))
+		require.NoError(t, api.PlayPos(1))
+		playlist, err := api.Playlist()
+		require.NoError(t, err)
+		status, err := api.Status()
+		require.NoError(t, err)
+		return playlist, status
+	}
+	server := newTestServer(t)
+	server.SetPassword("secret")
+	var recording syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "secret", false, 100, 2, time.Second, time.Second,
+		mpdapi.WithDialer(server.Dial), mpdapi.WithRecorder(&recording))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	recordedPlaylist, recordedStatus := session(api)
+	cancel()
+	assert.NotContains(t, string(recording.Bytes()), "secret")
+
+	replayer, err := mpdtest.NewReplayer(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	api = connect(t, "another", replayer.Dial)
+	playlist, status := session(api)
+	assert.Equal(t, recordedPlaylist, playlist)
+	assert.Equal(t, recordedStatus, status)
+	assert.NoError(t, replayer.Err())
+
+	_, err = api.ListOutputs()
+	assert.Error(t, err)
+	assert.ErrorIs(t, replayer.Err(), mpdtest.ErrReplayMismatch)
+}
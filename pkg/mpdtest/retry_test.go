@@ -0,0 +1,130 @@
+package mpdtest_test
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	t.Run("read is retried on a new connection", func(t *testing.T) {
+		server := newTestServer(t)
+		var calls atomic.Int32
+		server.Handle("status", func(args []string) ([]string, error) {
+			if calls.Add(1) == 1 {
+				return nil, mpdtest.ErrCloseConnection
+			}
+			return []string{"volume: 50", "state: stop"}, nil
+		})
+		api := connect(t, "", server.Dial)
+		connections := server.ConnectionCount()
+
+		status, err := api.Status()
+		require.NoError(t, err)
+		assert.Equal(t, "stop", *status.State)
+		assert.Equal(t, int32(2), calls.Load())
+		assert.True(t, api.IsConnected())
+		assert.Eventually(t, func() bool { return server.ConnectionCount() == connections }, time.Second, time.Millisecond)
+	})
+	t.Run("mutation is not retried", func(t *testing.T) {
+		server := newTestServer(t)
+		var calls atomic.Int32
+		server.Handle("clear", func(args []string) ([]string, error) {
+			calls.Add(1)
+			return nil, mpdtest.ErrCloseConnection
+		})
+		api := connect(t, "", server.Dial)
+
+		assert.Error(t, api.Clear())
+		assert.Equal(t, int32(1), calls.Load())
+		// The lost connection was replaced.
+		assert.True(t, api.IsConnected())
+		_, err := api.Status()
+		assert.NoError(t, err)
+	})
+	t.Run("unreachable server disconnects", func(t *testing.T) {
+		server := newTestServer(t)
+		var unreachable atomic.Bool
+		dial := func() (net.Conn, error) {
+			if unreachable.Load() {
+				return nil, errors.New("connection refused")
+			}
+			return server.Dial()
+		}
+		server.Handle("clear", func(args []string) ([]string, error) {
+			unreachable.Store(true)
+			return nil, mpdtest.ErrCloseConnection
+		})
+		api := connect(t, "", dial)
+
+		assert.Error(t, api.Clear())
+		assert.Eventually(t, func() bool { return !api.IsConnected() }, time.Second, time.Millisecond)
+	})
+	t.Run("slow redial does not block the other connections", func(t *testing.T) {
+		server := newTestServer(t)
+		redialing, redial := make(chan struct{}), make(chan struct{})
+		var slow atomic.Bool
+		dial := func() (net.Conn, error) {
+			if slow.CompareAndSwap(true, false) {
+				close(redialing)
+				<-redial
+			}
+			return server.Dial()
+		}
+		server.Handle("clear", func(args []string) ([]string, error) {
+			slow.Store(true)
+			return nil, mpdtest.ErrCloseConnection
+		})
+		api := connect(t, "", dial)
+
+		cleared := make(chan error)
+		go func() { cleared <- api.Clear() }()
+		<-redialing
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := api.Status()
+			assert.NoError(t, err)
+			assert.True(t, api.IsConnected())
+			_, err = api.PoolStats()
+			assert.NoError(t, err)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("the other connection is blocked by the redial")
+		}
+		close(redial)
+		<-done
+		assert.Error(t, <-cleared)
+		_, err := api.Status()
+		assert.NoError(t, err)
+	})
+	t.Run("dropped connections are replaced", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+		events := api.Subscribe(time.Second)
+		connections := server.ConnectionCount()
+
+		server.DropConnections()
+		// The idle connection is replaced at once, reporting all subsystems as changed.
+		waitForEvent(t, events, mpdapi.ON_PLAYER_CHANGED)
+		// Both pooled connections are replaced when used.
+		for range 2 {
+			status, err := api.Status()
+			require.NoError(t, err)
+			assert.Equal(t, "stop", *status.State)
+		}
+		assert.True(t, api.IsConnected())
+		assert.Eventually(t, func() bool { return server.ConnectionCount() == connections }, time.Second, time.Millisecond)
+		server.Notify("mixer")
+		waitForEvent(t, events, mpdapi.ON_MIXER_CHANGED)
+	})
+}
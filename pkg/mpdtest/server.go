@@ -0,0 +1,207 @@
+// Package mpdtest provides an in-process fake MPD server for testing applications built on mpdapi.
+//
+// The server keeps an in-memory database, queue, stored playlists, outputs and player status,
+// implements the commands used by mpdapi, command lists and idle notifications, and can be
+// scripted with Handle to override a command or add a new one:
+//
+//	server := mpdtest.NewServer()
+//	defer server.Close()
+//	server.AddSongs(mpdtest.Song{File: "a/1.mp3", Duration: time.Minute})
+//	api, _ := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 2, time.Second, time.Second,
+//		mpdapi.WithDialer(server.Dial))
+package mpdtest
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Version is the protocol version sent in the greeting.
+const Version = "0.23.5"
+
+// HandlerFunc answers a command. args are the unquoted command arguments.
+// The returned lines are sent before OK; an error is sent as an ACK line (see AckError).
+type HandlerFunc func(args []string) ([]string, error)
+
+// Server is a fake MPD server. It is safe for concurrent use.
+type Server struct {
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	password string
+	commands []string
+	state    state
+	rand     *rand.Rand
+
+	connsMu  sync.Mutex
+	conns    map[*conn]struct{}
+	listener net.Listener
+	closed   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a server with an empty database and queue, and a single enabled output.
+func NewServer() *Server {
+	s := &Server{
+		handlers: make(map[string]HandlerFunc),
+		state:    newState(),
+		rand:     rand.New(rand.NewSource(1)),
+		conns:    make(map[*conn]struct{}),
+		closed:   make(chan struct{}),
+	}
+	s.registerBuiltinHandlers()
+	return s
+}
+
+// Listen starts accepting connections on a random local TCP port and returns its address.
+func (s *Server) Listen() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	s.connsMu.Lock()
+	s.listener = listener
+	s.connsMu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.serve(c)
+		}
+	}()
+	return listener.Addr().String(), nil
+}
+
+// Dial returns the client end of an in-memory connection to the server (see net.Pipe).
+// It can be passed to mpdapi.WithDialer.
+func (s *Server) Dial() (net.Conn, error) {
+	select {
+	case <-s.closed:
+		return nil, errors.New("mpdtest: server closed")
+	default:
+	}
+	client, server := net.Pipe()
+	s.serve(server)
+	return client, nil
+}
+
+// Close stops listening and closes all the connections.
+func (s *Server) Close() error {
+	s.connsMu.Lock()
+	select {
+	case <-s.closed:
+		s.connsMu.Unlock()
+		return nil
+	default:
+	}
+	close(s.closed)
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for c := range s.conns {
+		_ = c.netConn.Close()
+	}
+	s.connsMu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// DropConnections closes all the client connections, as if the network failed.
+// The server keeps accepting new ones.
+func (s *Server) DropConnections() {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for c := range s.conns {
+		_ = c.netConn.Close()
+	}
+}
+
+// SetPassword makes the server require the password command before any other one.
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.password = password
+}
+
+// Handle sets the handler of a command, replacing the built-in one, if any.
+func (s *Server) Handle(command string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+}
+
+// Commands returns the command lines received by the server so far, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Notify reports changes of the given subsystems (e.g. "player", "playlist")
+// to the idling clients.
+func (s *Server) Notify(subsystems ...string) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for c := range s.conns {
+		c.notify(subsystems)
+	}
+}
+
+// ConnectionCount returns the number of open client connections.
+func (s *Server) ConnectionCount() int {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	return len(s.conns)
+}
+
+func (s *Server) serve(netConn net.Conn) {
+	c := newConn(s, netConn)
+	s.connsMu.Lock()
+	select {
+	case <-s.closed:
+		s.connsMu.Unlock()
+		_ = netConn.Close()
+		return
+	default:
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	s.connsMu.Unlock()
+	go func() {
+		defer s.wg.Done()
+		c.run()
+		s.connsMu.Lock()
+		delete(s.conns, c)
+		s.connsMu.Unlock()
+	}()
+}
+
+func (s *Server) handler(command string) (HandlerFunc, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.handlers[command]
+	return h, ok
+}
+
+func (s *Server) record(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, line)
+}
+
+func (s *Server) checkPassword(password string) (required, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.password != "", s.password == password
+}
+
+// now is used for the modification times set by the server.
+var now = func() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
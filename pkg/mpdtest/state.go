@@ -0,0 +1,268 @@
+package mpdtest
+
+import (
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Song is a file of the fake database.
+type Song struct {
+	// File is the path of the song relative to the music directory, e.g. "artist/album/01.flac".
+	File         string
+	LastModified time.Time
+	Duration     time.Duration
+	// Tags holds the song tags by name, e.g. "Artist", "Title" or "Genre".
+	Tags map[string][]string
+}
+
+// QueueItem is a song of the queue (the current playlist). Its position is its index in Server.Queue.
+type QueueItem struct {
+	Id   int
+	Song Song
+}
+
+// Output is an audio output.
+type Output struct {
+	Id      int
+	Name    string
+	Enabled bool
+}
+
+// StoredPlaylist is a playlist saved on the server.
+type StoredPlaylist struct {
+	Name         string
+	LastModified time.Time
+	Files        []string
+}
+
+// PlayerState is the state of the player.
+type PlayerState string
+
+const (
+	StatePlay  PlayerState = "play"
+	StatePause PlayerState = "pause"
+	StateStop  PlayerState = "stop"
+)
+
+// state is the in-memory state of a Server, guarded by Server.mu.
+type state struct {
+	songs     map[string]Song
+	queue     []QueueItem
+	nextId    int
+	playlists map[string]*StoredPlaylist
+	outputs   []Output
+
+	player    PlayerState
+	currentId int
+	elapsed   time.Duration
+	volume    int
+	repeat    bool
+	random    bool
+	single    bool
+	consume   bool
+	version   int
+	lastJob   int
+}
+
+func newState() state {
+	return state{
+		songs:     make(map[string]Song),
+		playlists: make(map[string]*StoredPlaylist),
+		outputs:   []Output{{Id: 0, Name: "default", Enabled: true}},
+		player:    StateStop,
+		currentId: -1,
+		volume:    100,
+		nextId:    1,
+		version:   1,
+	}
+}
+
+// AddSongs adds songs to the database, replacing the ones with the same path.
+func (s *Server) AddSongs(songs ...Song) {
+	s.mu.Lock()
+	for _, song := range songs {
+		if song.LastModified.IsZero() {
+			song.LastModified = now()
+		}
+		s.state.songs[song.File] = song
+	}
+	s.mu.Unlock()
+	s.Notify("database")
+}
+
+// RemoveSongs removes songs from the database. The queue is not changed.
+func (s *Server) RemoveSongs(files ...string) {
+	s.mu.Lock()
+	for _, file := range files {
+		delete(s.state.songs, file)
+	}
+	s.mu.Unlock()
+	s.Notify("database")
+}
+
+// AddStoredPlaylist saves a playlist of the given files, replacing the one with the same name.
+func (s *Server) AddStoredPlaylist(name string, files ...string) {
+	s.mu.Lock()
+	s.state.playlists[name] = &StoredPlaylist{Name: name, LastModified: now(), Files: slices.Clone(files)}
+	s.mu.Unlock()
+	s.Notify("stored_playlist")
+}
+
+// StoredPlaylist returns the stored playlist with the given name.
+func (s *Server) StoredPlaylist(name string) (StoredPlaylist, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.state.playlists[name]
+	if !ok {
+		return StoredPlaylist{}, false
+	}
+	result := *p
+	result.Files = slices.Clone(p.Files)
+	return result, true
+}
+
+// AddOutput adds an audio output and returns its id.
+func (s *Server) AddOutput(name string, enabled bool) int {
+	s.mu.Lock()
+	id := len(s.state.outputs)
+	s.state.outputs = append(s.state.outputs, Output{Id: id, Name: name, Enabled: enabled})
+	s.mu.Unlock()
+	s.Notify("output")
+	return id
+}
+
+// Outputs returns the audio outputs.
+func (s *Server) Outputs() []Output {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.state.outputs)
+}
+
+// Queue returns the songs of the queue.
+func (s *Server) Queue() []QueueItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.state.queue)
+}
+
+// PlayerState returns the state of the player and the position of the current song, or -1.
+func (s *Server) PlayerState() (PlayerState, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.player, s.state.currentPos()
+}
+
+// SetElapsed sets the elapsed time of the current song.
+func (s *Server) SetElapsed(elapsed time.Duration) {
+	s.mu.Lock()
+	s.state.elapsed = elapsed
+	s.mu.Unlock()
+	s.Notify("player")
+}
+
+// SetVolume sets the volume.
+func (s *Server) SetVolume(volume int) {
+	s.mu.Lock()
+	s.state.volume = volume
+	s.mu.Unlock()
+	s.Notify("mixer")
+}
+
+// currentPos returns the queue position of the current song, or -1.
+func (st *state) currentPos() int {
+	return st.posById(st.currentId)
+}
+
+func (st *state) posById(id int) int {
+	return slices.IndexFunc(st.queue, func(item QueueItem) bool {
+		return item.Id == id
+	})
+}
+
+// directories returns all the directories of the database, without the root.
+func (st *state) directories() map[string]struct{} {
+	result := make(map[string]struct{})
+	for file := range st.songs {
+		for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			result[dir] = struct{}{}
+		}
+	}
+	return result
+}
+
+// children returns the sorted subdirectories and songs located directly in dir.
+func (st *state) children(dir string) ([]string, []Song) {
+	var dirs []string
+	for d := range st.directories() {
+		if parentDir(d) == dir {
+			dirs = append(dirs, d)
+		}
+	}
+	var songs []Song
+	for _, file := range slices.Sorted(maps.Keys(st.songs)) {
+		if parentDir(file) == dir {
+			songs = append(songs, st.songs[file])
+		}
+	}
+	slices.Sort(dirs)
+	return dirs, songs
+}
+
+// songsUnder returns the sorted songs located in dir and its subdirectories, or the song
+// with the given path. The root directory is "".
+func (st *state) songsUnder(p string) ([]Song, bool) {
+	p = strings.Trim(p, "/")
+	if song, ok := st.songs[p]; ok {
+		return []Song{song}, true
+	}
+	if _, ok := st.directories()[p]; !ok && p != "" {
+		return nil, false
+	}
+	var result []Song
+	for _, file := range slices.Sorted(maps.Keys(st.songs)) {
+		if p == "" || strings.HasPrefix(file, p+"/") {
+			result = append(result, st.songs[file])
+		}
+	}
+	return result, true
+}
+
+func (st *state) isDirectory(p string) bool {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return true
+	}
+	_, ok := st.directories()[p]
+	return ok
+}
+
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// queueChanged increases the playlist version after a queue change.
+func (st *state) queueChanged() {
+	st.version++
+	if st.currentPos() < 0 {
+		st.currentId = -1
+		st.player = StateStop
+		st.elapsed = 0
+	}
+}
+
+func (st *state) addToQueue(song Song, pos int) int {
+	item := QueueItem{Id: st.nextId, Song: song}
+	st.nextId++
+	if pos < 0 || pos > len(st.queue) {
+		pos = len(st.queue)
+	}
+	st.queue = slices.Insert(st.queue, pos, item)
+	return item.Id
+}
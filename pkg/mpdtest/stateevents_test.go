@@ -0,0 +1,93 @@
+package mpdtest_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateEvents(t *testing.T) {
+	server := newTestServer(t)
+	api := connect(t, "", server.Dial)
+	events := api.SubscribeStateEvents(time.Second)
+	defer api.UnsubscribeStateEvents(events)
+	// The state the changes are compared with is loaded with the outputs.
+	assert.Eventually(t, func() bool { return slices.Contains(server.Commands(), "outputs") }, time.Second, time.Millisecond)
+
+	server.SetVolume(30)
+	event := waitForStateEvent(t, events, mpdapi.ON_MIXER_CHANGED)
+	assert.Equal(t, []mpdapi.StateChange{{Field: "volume", From: "100", To: "30"}}, event.Changes)
+	require.NotNil(t, event.Before)
+	assert.Equal(t, 100, *event.Before.Volume)
+	assert.Equal(t, 30, *event.After.Volume)
+
+	require.NoError(t, api.Add("3.mp3"))
+	event = waitForStateEvent(t, events, mpdapi.ON_PLAYLIST_CHANGED)
+	change, ok := event.Change("playlistlength")
+	assert.True(t, ok)
+	assert.Equal(t, mpdapi.StateChange{Field: "playlistlength", From: "0", To: "1"}, change)
+
+	require.NoError(t, api.Play())
+	event = waitForStateEvent(t, events, mpdapi.ON_PLAYER_CHANGED)
+	change, ok = event.Change("state")
+	assert.True(t, ok)
+	assert.Equal(t, mpdapi.StateChange{Field: "state", From: "stop", To: "play"}, change)
+	require.NoError(t, api.Pause())
+	event = waitForStateEvent(t, events, mpdapi.ON_PLAYER_CHANGED)
+	change, _ = event.Change("state")
+	assert.Equal(t, mpdapi.StateChange{Field: "state", From: "play", To: "pause"}, change)
+
+	require.NoError(t, api.DisableOutput(0))
+	event = waitForStateEvent(t, events, mpdapi.ON_OUTPUT_CHANGED)
+	assert.Equal(t, []mpdapi.StateChange{{Field: "output:default", From: "enabled", To: "disabled"}}, event.Changes)
+	assert.Equal(t, []mpdapi.Output{{Name: "default", Id: 0, Enabled: true}}, event.OutputsBefore)
+	assert.Equal(t, []mpdapi.Output{{Name: "default", Id: 0, Enabled: false}}, event.OutputsAfter)
+
+	// The state is not watched without subscribers.
+	api.UnsubscribeStateEvents(events)
+	_, ok = <-events
+	assert.False(t, ok)
+	outputs := countCommand(server, "outputs")
+	require.NoError(t, api.EnableOutput(0))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, outputs, countCommand(server, "outputs"))
+
+	// A new subscription loads the state again.
+	events = api.WithRequestContext(t.Context()).SubscribeStateEvents(time.Second)
+	defer api.UnsubscribeStateEvents(events)
+	assert.Eventually(t, func() bool { return countCommand(server, "outputs") == outputs+1 }, time.Second, time.Millisecond)
+	server.SetVolume(45)
+	event = waitForStateEvent(t, events, mpdapi.ON_MIXER_CHANGED)
+	assert.Equal(t, []mpdapi.StateChange{{Field: "volume", From: "30", To: "45"}}, event.Changes)
+}
+
+func countCommand(server *mpdtest.Server, command string) int {
+	count := 0
+	for _, c := range server.Commands() {
+		if c == command {
+			count++
+		}
+	}
+	return count
+}
+
+func waitForStateEvent(t *testing.T, events chan mpdapi.StateEvent, expected mpdapi.MpdEventType) mpdapi.StateEvent {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == expected {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("state event %v not received", expected)
+			return mpdapi.StateEvent{}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package mpdtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	server := newTestServer(t)
+	api := connect(t, "", server.Dial)
+	subscription := api.SubscribeEvents(
+		mpdapi.WithEventTypes(mpdapi.ON_PLAYLIST_CHANGED),
+		mpdapi.WithDebounce(100*time.Millisecond))
+	defer subscription.Close()
+
+	require.NoError(t, api.Add("a"))
+	require.NoError(t, api.Add("3.mp3"))
+	require.NoError(t, api.Clear())
+	select {
+	case batch := <-subscription.Events():
+		assert.Equal(t, []mpdapi.MpdEventType{mpdapi.ON_PLAYLIST_CHANGED}, batch.Types)
+	case <-time.After(time.Second):
+		t.Fatal("no batch received")
+	}
+	select {
+	case batch := <-subscription.Events():
+		t.Errorf("unexpected batch %v", batch)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
@@ -0,0 +1,134 @@
+package mpdtest_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/anpotashev/mpdgo/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+// testSpan is a span remembering its parent, attributes and error.
+type testSpan struct {
+	name   string
+	parent *testSpan
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+func (s *testSpan) path() string {
+	if s.parent == nil {
+		return s.name
+	}
+	return s.parent.path() + "/" + s.name
+}
+
+// testTracer records the spans it starts.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, _ := ctx.Value(spanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attrs: make(map[string]any)}
+	t.spans = append(t.spans, span)
+	span.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, span), &lockedSpan{tracer: t, span: span}
+}
+
+func (t *testTracer) find(path string) *testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, span := range t.spans {
+		if span.path() == path {
+			return span
+		}
+	}
+	return nil
+}
+
+type lockedSpan struct {
+	tracer *testTracer
+	span   *testSpan
+}
+
+func (s *lockedSpan) SetAttributes(attrs ...tracing.Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.SetAttributes(attrs...)
+}
+
+func (s *lockedSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.err = err
+}
+
+func (s *lockedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.ended = true
+}
+
+func (s *testSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func TestTracing(t *testing.T) {
+	server := newTestServer(t)
+	tracer := &testTracer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", false, 100, 2, time.Second, time.Second,
+		mpdapi.WithDialer(server.Dial), mpdapi.WithTracer(tracer))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+
+	_, err = api.Status()
+	require.NoError(t, err)
+	status := tracer.find("mpdapi.Status")
+	require.NotNil(t, status)
+	assert.True(t, status.ended)
+	assert.NoError(t, status.err)
+	require.NotNil(t, tracer.find("mpdapi.Status/mpd.pool.acquire"))
+	write := tracer.find("mpdapi.Status/mpd.write")
+	require.NotNil(t, write)
+	assert.Equal(t, "status", write.attrs[tracing.AttrCommand])
+	assert.Equal(t, len("status\n"), write.attrs[tracing.AttrBytesWritten])
+	read := tracer.find("mpdapi.Status/mpd.read")
+	require.NotNil(t, read)
+	assert.Equal(t, "status", read.attrs[tracing.AttrCommand])
+	assert.Positive(t, read.attrs[tracing.AttrBytesRead])
+	assert.Positive(t, read.attrs[tracing.AttrLines])
+
+	err = api.PlayId(42)
+	var ackErr *mpdapi.AckError
+	require.True(t, errors.As(err, &ackErr))
+	assert.Equal(t, int(mpdtest.AckNoExist), ackErr.Code)
+	playId := tracer.find("mpdapi.PlayId")
+	require.NotNil(t, playId)
+	assert.Error(t, playId.err)
+	read = tracer.find("mpdapi.PlayId/mpd.read")
+	require.NotNil(t, read)
+	assert.Equal(t, int(mpdtest.AckNoExist), read.attrs[tracing.AttrAckCode])
+
+	for range api.PlaylistSeq() {
+	}
+	read = tracer.find("mpdapi.PlaylistSeq/mpd.read")
+	require.NotNil(t, read)
+	assert.Equal(t, "playlistinfo", read.attrs[tracing.AttrCommand])
+}
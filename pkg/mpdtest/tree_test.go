@@ -0,0 +1,168 @@
+package mpdtest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countLsInfo(server *mpdtest.Server) int {
+	count := 0
+	for _, command := range server.Commands() {
+		if strings.HasPrefix(command, "lsinfo") {
+			count++
+		}
+	}
+	return count
+}
+
+func paths(items []mpdapi.TreeItem) []string {
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.GetPath())
+	}
+	return result
+}
+
+func connectWithCache(t *testing.T, server *mpdtest.Server) mpdapi.MpdApi {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	api, err := mpdapi.NewMpdApi(ctx, "", 0, "", true, 100, 2, time.Second, time.Second, mpdapi.WithDialer(server.Dial))
+	require.NoError(t, err)
+	require.NoError(t, api.Connect())
+	t.Cleanup(func() {
+		_ = api.Disconnect()
+		cancel()
+	})
+	return api
+}
+
+func TestBrowse(t *testing.T) {
+	server := newTestServer(t)
+	api := connect(t, "", server.Dial)
+
+	root, err := api.Browse("")
+	require.NoError(t, err)
+	assert.Equal(t, "/", root.Name)
+	assert.True(t, root.IsExpanded())
+	assert.Equal(t, []string{"a", "3.mp3"}, paths(root.Children))
+	dir, ok := root.Children[0].(*mpdapi.DirectoryItem)
+	require.True(t, ok)
+	assert.Same(t, root, dir.GetParent())
+	assert.Same(t, root, root.Children[1].GetParent())
+	assert.False(t, dir.IsExpanded())
+	assert.Empty(t, dir.Children)
+
+	t.Run("expand", func(t *testing.T) {
+		loads := countLsInfo(server)
+		require.NoError(t, dir.Expand())
+		assert.True(t, dir.IsExpanded())
+		assert.Equal(t, []string{"a/b", "a/1.mp3"}, paths(dir.Children))
+		assert.Same(t, dir, dir.Children[1].GetParent())
+		assert.Equal(t, loads+1, countLsInfo(server))
+
+		// The loaded children are kept.
+		require.NoError(t, dir.Expand())
+		assert.Equal(t, loads+1, countLsInfo(server))
+	})
+	t.Run("reload", func(t *testing.T) {
+		server.AddSongs(mpdtest.Song{File: "a/4.mp3", Duration: time.Minute})
+		require.NoError(t, dir.Reload())
+		assert.Equal(t, []string{"a/b", "a/1.mp3", "a/4.mp3"}, paths(dir.Children))
+		assert.Same(t, dir, dir.Children[2].GetParent())
+	})
+	t.Run("subdirectory", func(t *testing.T) {
+		sub, err := api.Browse("a/b")
+		require.NoError(t, err)
+		assert.Equal(t, "b", sub.Name)
+		assert.Nil(t, sub.GetParent())
+		assert.Equal(t, []string{"a/b/2.mp3"}, paths(sub.Children))
+	})
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := api.Browse("missing")
+		assert.Error(t, err)
+	})
+}
+
+func TestLsInfoCache(t *testing.T) {
+	t.Run("cached answers are cloned", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connectWithCache(t, server)
+
+		items, err := api.LsInfo("a")
+		require.NoError(t, err)
+		file, ok := items[1].(*mpdapi.FileItem)
+		require.True(t, ok)
+		file.Name = "changed"
+		changed := "changed"
+		file.Title = &changed
+		dir, ok := items[0].(*mpdapi.DirectoryItem)
+		require.True(t, ok)
+		require.NoError(t, dir.Expand())
+		items[0] = nil
+		loads := countLsInfo(server)
+
+		cached, err := api.LsInfo("a")
+		require.NoError(t, err)
+		assert.Equal(t, loads, countLsInfo(server))
+		assert.Equal(t, []string{"a/b", "a/1.mp3"}, paths(cached))
+		assert.Equal(t, "1.mp3", cached[1].GetName())
+		require.NotNil(t, cached[1].(*mpdapi.FileItem).Title)
+		assert.Equal(t, "One", *cached[1].(*mpdapi.FileItem).Title)
+		cachedDir := cached[0].(*mpdapi.DirectoryItem)
+		assert.False(t, cachedDir.IsExpanded())
+		assert.Empty(t, cachedDir.Children)
+
+		// Browsing links the items to the browsed directory, not the cached ones.
+		browsed, err := api.Browse("a")
+		require.NoError(t, err)
+		assert.Same(t, browsed, browsed.Children[1].GetParent())
+		cached, err = api.LsInfo("a")
+		require.NoError(t, err)
+		assert.Nil(t, cached[1].GetParent())
+		assert.Equal(t, loads, countLsInfo(server))
+	})
+	t.Run("database change clears all paths", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connectWithCache(t, server)
+		_, err := api.LsInfo("")
+		require.NoError(t, err)
+		_, err = api.LsInfo("a")
+		require.NoError(t, err)
+
+		server.AddSongs(mpdtest.Song{File: "a/4.mp3", Duration: time.Minute}, mpdtest.Song{File: "5.mp3", Duration: time.Minute})
+		assert.Eventually(t, func() bool {
+			items, err := api.LsInfo("a")
+			return err == nil && len(items) == 3
+		}, time.Second, time.Millisecond)
+		items, err := api.LsInfo("")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "3.mp3", "5.mp3"}, paths(items))
+	})
+	t.Run("disconnect clears all paths", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connectWithCache(t, server)
+		_, err := api.LsInfo("")
+		require.NoError(t, err)
+		_, err = api.LsInfo("a")
+		require.NoError(t, err)
+
+		require.NoError(t, api.Disconnect())
+		// The change is not notified to the disconnected client.
+		server.AddSongs(mpdtest.Song{File: "a/4.mp3", Duration: time.Minute}, mpdtest.Song{File: "5.mp3", Duration: time.Minute})
+		require.NoError(t, api.Connect())
+		assert.Eventually(t, func() bool {
+			items, err := api.LsInfo("a")
+			return err == nil && len(items) == 3
+		}, time.Second, time.Millisecond)
+		items, err := api.LsInfo("")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "3.mp3", "5.mp3"}, paths(items))
+	})
+}
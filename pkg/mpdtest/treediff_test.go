@@ -0,0 +1,64 @@
+package mpdtest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countTreeLoads(server *mpdtest.Server) int {
+	count := 0
+	for _, command := range server.Commands() {
+		if strings.HasPrefix(command, "listallinfo") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestTreeDiffs(t *testing.T) {
+	server := newTestServer(t)
+	api := connect(t, "", server.Dial)
+	diffs := api.SubscribeTreeDiffs(time.Second)
+	// The snapshot the update is compared with is loaded first, and again on the connect event
+	// if it is published after the subscription.
+	assert.Eventually(t, func() bool { return countTreeLoads(server) > 0 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	server.AddSongs(mpdtest.Song{File: "a/4.mp3", Duration: time.Minute})
+	select {
+	case diff := <-diffs:
+		require.Len(t, diff.AddedFiles, 1)
+		assert.Equal(t, "a/4.mp3", diff.AddedFiles[0].Path)
+	case <-time.After(time.Second):
+		t.Fatal("no diff received")
+	}
+
+	// The tree is not watched without subscribers.
+	api.UnsubscribeTreeDiffs(diffs)
+	_, ok := <-diffs
+	assert.False(t, ok)
+	loads := countTreeLoads(server)
+	server.AddSongs(mpdtest.Song{File: "5.mp3", Duration: time.Minute})
+	_, err := api.Status()
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, loads, countTreeLoads(server))
+
+	// A new subscription loads a new snapshot.
+	diffs = api.WithRequestContext(t.Context()).SubscribeTreeDiffs(time.Second)
+	defer api.UnsubscribeTreeDiffs(diffs)
+	assert.Eventually(t, func() bool { return countTreeLoads(server) == loads+1 }, time.Second, time.Millisecond)
+	server.RemoveSongs("5.mp3")
+	select {
+	case diff := <-diffs:
+		require.Len(t, diff.RemovedFiles, 1)
+		assert.Equal(t, "5.mp3", diff.RemovedFiles[0].Path)
+	case <-time.After(time.Second):
+		t.Fatal("no diff received")
+	}
+}
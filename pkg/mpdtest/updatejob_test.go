@@ -0,0 +1,41 @@
+package mpdtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anpotashev/mpdgo/pkg/mpdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForUpdate(t *testing.T) {
+	t.Run("finished job", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+		jobId, err := api.UpdateDB("")
+		require.NoError(t, err)
+		var progress []mpdapi.UpdateProgress
+		require.NoError(t, api.WaitForUpdate(t.Context(), jobId, func(p mpdapi.UpdateProgress) {
+			progress = append(progress, p)
+		}))
+		require.Len(t, progress, 1)
+		assert.True(t, progress[0].Done)
+	})
+	t.Run("status interrupted by ctx", func(t *testing.T) {
+		server := newTestServer(t)
+		api := connect(t, "", server.Dial)
+		release := make(chan struct{})
+		defer close(release)
+		server.Handle("status", func(args []string) ([]string, error) {
+			<-release
+			return nil, nil
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		started := time.Now()
+		assert.Error(t, api.WaitForUpdate(ctx, 1, nil))
+		assert.Less(t, time.Since(started), 500*time.Millisecond)
+	})
+}
@@ -0,0 +1,102 @@
+// Package tracing defines the hooks the client uses to trace its calls, see mpdapi.WithTracer.
+//
+// Every mpdapi call runs in a span named "mpdapi.<Method>", with the child spans
+// "mpd.pool.acquire" for the wait for a pool connection, and "mpd.write" and "mpd.read"
+// for every command sent to MPD. The interfaces follow OpenTelemetry, so an adapter is a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		s := otelSpan{span}
+//		s.SetAttributes(attrs...)
+//		return ctx, s
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttributes(attrs ...tracing.Attribute) {
+//		for _, a := range attrs {
+//			s.Span.SetAttributes(attribute.String(a.Key, fmt.Sprint(a.Value)))
+//		}
+//	}
+//
+//	func (s otelSpan) RecordError(err error) {
+//		s.Span.RecordError(err)
+//		s.Span.SetStatus(codes.Error, err.Error())
+//	}
+//
+//	func (s otelSpan) End() { s.Span.End() }
+package tracing
+
+import "context"
+
+// Attribute keys set by the client.
+const (
+	// AttrCommand is the name of the MPD command, e.g. "status" or "command_list".
+	AttrCommand = "mpd.command"
+	// AttrBytesWritten is the size of the command sent to MPD.
+	AttrBytesWritten = "mpd.bytes_written"
+	// AttrBytesRead is the size of the answer, without the final OK or ACK line.
+	AttrBytesRead = "mpd.bytes_read"
+	// AttrLines is the number of lines of the answer, without the final OK or ACK line.
+	AttrLines = "mpd.lines"
+	// AttrAckCode is the error code of an ACK answer.
+	AttrAckCode = "mpd.ack_code"
+)
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError marks the span as failed.
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. Start returns a context holding the new span, so the spans
+// started with it are its children.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type tracerKey struct{}
+
+// ContextWithTracer returns a context making Start use tracer.
+func ContextWithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// Start starts a span with the tracer of ctx. Without a tracer the span does nothing.
+// ctx may be nil.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if ctx == nil {
+		return ctx, nopSpan{}
+	}
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok {
+		return ctx, nopSpan{}
+	}
+	return tracer.Start(ctx, name, attrs...)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
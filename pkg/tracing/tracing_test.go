@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTracer struct {
+	started []string
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.started = append(t.started, name)
+	return ctx, nopSpan{}
+}
+
+func TestStart(t *testing.T) {
+	t.Run("without tracer", func(t *testing.T) {
+		ctx, span := Start(context.Background(), "span")
+		assert.Equal(t, context.Background(), ctx)
+		assert.Equal(t, nopSpan{}, span)
+	})
+	t.Run("nil context", func(t *testing.T) {
+		//lint:ignore SA1012 nil is accepted
+		ctx, span := Start(nil, "span")
+		assert.Nil(t, ctx)
+		assert.Equal(t, nopSpan{}, span)
+	})
+	t.Run("tracer from context", func(t *testing.T) {
+		tracer := &testTracer{}
+		ctx := ContextWithTracer(context.Background(), tracer)
+		Start(ctx, "first")
+		Start(ctx, "second", Int(AttrLines, 1))
+		assert.Equal(t, []string{"first", "second"}, tracer.started)
+	})
+}